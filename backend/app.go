@@ -6,20 +6,35 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
+	"net/http"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 
 	"smart-spotlight-ai/backend/history"
 	"smart-spotlight-ai/backend/keybind"
 	"smart-spotlight-ai/backend/llm"
 	"smart-spotlight-ai/backend/llm/mcphost"
 
+	"smart-spotlight-ai/backend/packages/llm/capabilities"
 	llmhistory "smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/providers"
+	"smart-spotlight-ai/backend/packages/oauth"
+	"smart-spotlight-ai/backend/packages/secrets"
 	"smart-spotlight-ai/backend/settings"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// mcpAuthFileName is where earlier versions kept OAuth tokens of remote MCP
+// servers in plain text; they move to the secret store once it is unlocked
+const mcpAuthFileName = "mcp-auth.json"
+
+// mcpLogsDirName holds the rotated stderr and log notifications of each MCP server
+const mcpLogsDirName = "mcp-logs"
+
 // App struct
 type App struct {
 	ctx                      context.Context
@@ -28,8 +43,16 @@ type App struct {
 	startupComplete          bool
 	historyService           *history.Service
 	llmService               *llm.Service
+	modelDiscovery           *llm.Discovery
+	capabilities             *capabilities.Registry
 	mcpService               *mcphost.MCPService
 	mcpServerSettingsService *settings.MCPServerSettingsService
+	secrets                  *secrets.Store
+	mcpAuth                  *oauth.Manager // OAuth credentials of remote servers, shared with the gateway
+	gateway                  *mcphost.Gateway
+	gatewayMu                sync.Mutex // serializes gateway reloads
+	loginEnv                 []string   // environment of the user's login shell, read once at startup; nil if unreadable
+	gatewayServer            *http.Server
 }
 
 // NewApp creates a new App application struct
@@ -44,6 +67,7 @@ func NewApp() *App {
 
 	return &App{
 		startupComplete: false,
+		capabilities:    capabilities.NewRegistry(),
 	}
 }
 
@@ -77,7 +101,7 @@ func (a *App) Startup(ctx context.Context) {
 		log.Printf("Error initializing search history: %v", err)
 	}
 
-	a.llmService = llm.NewService(settings.GetCurrentSettings())
+	a.modelDiscovery = llm.NewDiscovery(filepath.Join(configDir, modelCacheFileName))
 
 	// Initialize MCP Server Settings Service
 	// Use the already obtained configDir instead of calling GetConfigDir again
@@ -86,11 +110,38 @@ func (a *App) Startup(ctx context.Context) {
 		log.Printf("Error initializing MCP server settings service: %v", err)
 	}
 
-	// Initialize MCP service
-	if err := a.initializeMCPService(); err != nil {
+	// Remote servers signed in to once are reachable from search and the gateway
+	if store, err := oauth.NewStore(filepath.Join(configDir, mcpAuthFileName)); err != nil {
+		log.Printf("Error loading OAuth credentials, OAuth disabled: %v", err)
+	} else {
+		a.mcpAuth = oauth.NewManager(store, "smart-spotlight")
+	}
+
+	// Unlock secrets before MCP servers launch so their references resolve
+	a.openSecretStore()
+
+	// Launchers start us without the PATH entries shell profiles add, which
+	// stdio servers launched through npx, uvx and the like depend on. Starting
+	// a login shell is slow, so its environment is read once here.
+	if env, err := mcphost.LoginShellEnv(ctx); err != nil {
+		log.Printf("Error reading login shell environment, using our own: %v", err)
+	} else {
+		a.loginEnv = env
+	}
+
+	// Build both search modes from the default provider profile
+	if err := a.applyProviderSettings(); err != nil {
 		log.Printf("Error initializing MCP service: %v", err)
 	}
 
+	// Share the enabled servers with other MCP clients
+	if err := a.startMCPGateway(); err != nil {
+		log.Printf("Error starting MCP gateway: %v", err)
+	}
+
+	// Model lists are fetched in the background, the picker uses the cache meanwhile
+	go a.refreshAvailableModels()
+
 	// Setup global shortcut
 	a.setupGlobalShortcut()
 	a.startupComplete = true
@@ -98,6 +149,7 @@ func (a *App) Startup(ctx context.Context) {
 
 // Shutdown is called when the app is closing
 func (a *App) Shutdown(ctx context.Context) {
+	a.stopMCPGateway()
 	if a.db != nil {
 		a.db.Close()
 	}
@@ -135,20 +187,47 @@ func (a *App) Greet(name string) string {
 	return fmt.Sprintf("Hello %s, It's a new Incremental show time!", name)
 }
 
-// UpdateSettings updates the application settings
+// UpdateSettings updates the application settings and applies the default
+// provider profile to both search modes
 func (a *App) UpdateSettings(newSettings settings.Settings) error {
-	err := settings.SaveSettings(&newSettings)
+	_, err := settings.ModifySettings(func(current *settings.Settings) error {
+		// Callers that predate profiles leave them out; keep the stored ones
+		if newSettings.Profiles == nil {
+			newSettings.Profiles = append([]settings.ProviderProfile(nil), current.Profiles...)
+			newSettings.DefaultProfile = current.DefaultProfile
+		}
+		if newSettings.CapabilityOverrides == nil {
+			newSettings.CapabilityOverrides = current.CapabilityOverrides
+		}
+		newSettings.SystemPromptMigrated = current.SystemPromptMigrated
+		// The settings form edits the default profile through the single provider fields
+		newSettings.ApplyLegacyFields()
+
+		for i, profile := range newSettings.Profiles {
+			if profile.APIKey == secrets.MaskedValue {
+				existing, _ := current.Profile(profile.Name)
+				newSettings.Profiles[i].APIKey = existing.APIKey
+			}
+		}
+		if err := newSettings.ValidateProfiles(); err != nil {
+			return err
+		}
+		*current = newSettings
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to save settings: %w", err)
 	}
-	settings.AppSettings = &newSettings         // Update in-memory settings
-	a.llmService = llm.NewService(&newSettings) // Update LLM service with new settings
-	return nil
+	return a.applyProviderSettings()
 }
 
-// GetSettings retrieves the current application settings
+// GetSettings retrieves the current application settings with API keys masked
 func (a *App) GetSettings() *settings.Settings {
-	return settings.AppSettings
+	masked := *settings.GetCurrentSettings()
+	masked.Profiles = a.GetProviderProfiles()
+	masked.FillLegacyFields()
+	masked.AvailableModels = a.availableModels(&masked)
+	return &masked
 }
 
 // SearchWithLLM performs a search using the LLM service
@@ -156,6 +235,10 @@ func (a *App) SearchWithLLM(query string) (*llm.ChatResponse, error) {
 	if err := a.historyService.AddToHistory(query); err != nil {
 		log.Printf("Error adding to history: %v", err)
 	}
+	// A leading @profile answers just this query with another profile
+	if chain, rest := a.routeQuery(query); chain != nil {
+		return a.llmService.SearchWith(chain, rest)
+	}
 	return a.llmService.Search(query)
 }
 
@@ -170,7 +253,11 @@ func (a *App) SearchWithMCP(query string) error {
 		log.Printf("Error adding to history: %v", err)
 	}
 
-	// Perform search using MCP service
+	// Perform search using MCP service, on another profile for a leading @profile
+	if chain, rest := a.routeQuery(query); chain != nil {
+		route := mcpProvider(chain)
+		return a.mcpService.SearchWith(rest, &route)
+	}
 	return a.mcpService.Search(query)
 }
 
@@ -190,45 +277,70 @@ func (a *App) IsStartupComplete() bool {
 
 // initializeMCPService initializes the MCP service with configurations from environment variables
 func (a *App) initializeMCPService() error {
-	// Load configuration from environment variables
-	providerName := settings.GetEnvWithDefault("SPOT_AI_PROVIDER", "openai")
-	baseURL := settings.GetEnvWithDefault("SPOT_AI_API_ENDPOINT", "")
-	apiKey := settings.GetEnvWithDefault("SPOT_AI_API_KEY", "")
-	modelName := settings.GetEnvWithDefault("SPOT_AI_MODEL", "gpt-4o")
-	systemPrompt := settings.GetEnvWithDefault("SPOT_AI_SYSTEM_PROMPT", "")
+	// The provider and system prompt come from the settings, the rest from environment variables
+	current := settings.GetCurrentSettings()
+	chain := a.defaultChain(current)
+	if len(chain) == 0 {
+		log.Printf("Warning: no provider profile configured, MCP service will not be initialized")
+		return nil
+	}
+	config := chain[0]
 	configFile := settings.GetEnvWithDefault("SPOT_AI_CONFIG_FILE", "")
 
 	// Default to 10 if not specified
 	messageWindow := 10
 
-	// Skip initialization if API key is not provided
-	if apiKey == "" {
-		log.Printf("Warning: SPOT_AI_API_KEY not set, MCP service will not be initialized")
+	// Large catalogs are narrowed to the most relevant tools per request
+	toolTopK, err := strconv.Atoi(settings.GetEnvWithDefault("SPOT_AI_TOOL_TOP_K", "20"))
+	if err != nil {
+		log.Printf("Invalid SPOT_AI_TOOL_TOP_K, sending all tools: %v", err)
+		toolTopK = 0
+	}
+	var pinnedTools []string
+	if pinned := settings.GetEnvWithDefault("SPOT_AI_PINNED_TOOLS", ""); pinned != "" {
+		for _, pattern := range strings.Split(pinned, ",") {
+			pinnedTools = append(pinnedTools, strings.TrimSpace(pattern))
+		}
+	}
+
+	// Skip initialization until the profile has a key
+	if config.APIKey == "" && providers.NeedsAPIKey(config.Provider) {
+		log.Printf("Warning: provider profile %s has no API key, MCP service will not be initialized", config.Name)
 		return nil
 	}
 
 	// Create provider configuration
-	provider := mcphost.LLMProvider{
-		ProviderName: providerName,
-		BaseURL:      baseURL,
-		APIKey:       apiKey,
-		ModelName:    modelName,
-		Metadata:     make(map[string]string),
-	}
+	provider := mcpProvider(chain)
 
 	debugMode := settings.GetEnvWithDefault("SPOT_AI_DEBUG", "true")
 	debugModeBool := debugMode == "true"
+
+	// Server logs live next to the other config files
+	logDir := ""
+	if configDir, err := settings.GetConfigDir(); err == nil {
+		logDir = filepath.Join(configDir, mcpLogsDirName)
+	} else {
+		log.Printf("Error getting config directory, log files disabled: %v", err)
+	}
+
 	// Create MCP settings
 	mcpSettings := &mcphost.MCPSettings{
-		ConfigFile:    configFile,
-		SystemPrompt:  systemPrompt,
-		MessageWindow: messageWindow,
-		Provider:      provider,
-		DebugMode:     debugModeBool, // Use app's debug setting
+		ConfigFile:     configFile,
+		SystemPrompt:   current.SystemPrompt,
+		MessageWindow:  messageWindow,
+		Provider:       provider,
+		DebugMode:      debugModeBool, // Use app's debug setting
+		Auth:           a.mcpAuth,
+		ToolTopK:       toolTopK,
+		PinnedTools:    pinnedTools,
+		Secrets:        a.secretLookup(),
+		BaseEnv:        a.loginEnv,
+		LogDir:         logDir,
+		PromptTemplate: current.SystemPromptTemplate,
+		Capabilities:   a.capabilities,
 	}
 
 	// Create MCP service instance
-	var err error
 	a.mcpService, err = mcphost.NewMCPService(a.ctx, mcpSettings)
 	if err != nil {
 		return fmt.Errorf("failed to create MCP service: %w", err)
@@ -250,6 +362,14 @@ func (a *App) initializeMCPService() error {
 			out := map[string]interface{}{
 				"Type": ev.Type,
 			}
+			if ev.Provider != "" {
+				// the profile that answered, which differs from the default after a fallback
+				out["Provider"] = ev.Provider
+			}
+			if ev.Usage != nil {
+				// input and output tokens, and how much of the input the prompt cache served
+				out["Usage"] = ev.Usage
+			}
 
 			switch ev.Type {
 
@@ -268,11 +388,13 @@ func (a *App) initializeMCPService() error {
 
 			case mcphost.EventToolUse,
 				mcphost.EventToolResult,
+				mcphost.EventProgress,
+				mcphost.EventElicitation,
 				mcphost.EventAuthorization,
 				mcphost.EventConfirmationRequired:
 				out["Data"] = ev.Data // these are already maps / strings
 
-			case mcphost.EventError:
+			case mcphost.EventError, mcphost.EventThinking:
 				out["Data"] = fmt.Sprintf("%v", ev.Data)
 			}
 
@@ -285,7 +407,28 @@ func (a *App) initializeMCPService() error {
 	return nil
 }
 
+// RespondElicitation answers a server's request for user input.
+// action is "accept", "decline" or "cancel".
+func (a *App) RespondElicitation(token string, action string, content map[string]interface{}) error {
+	if a.mcpService == nil {
+		return fmt.Errorf("MCP service is not initialized")
+	}
+	return a.mcpService.RespondElicitation(token, action, content)
+}
+
+// PreviewSystemPrompt renders the system prompt the next request would be
+// sent with, including the instructions of connected servers
+func (a *App) PreviewSystemPrompt() (string, error) {
+	if a.mcpService == nil {
+		return "", fmt.Errorf("MCP service is not initialized")
+	}
+	return a.mcpService.PreviewSystemPrompt()
+}
+
 func (a *App) ConfirmTool(token string, ok bool) error {
+	if a.gateway != nil && a.gateway.Confirm(token, ok) {
+		return nil
+	}
 	if a.mcpService != nil {
 		a.mcpService.Confirm(token, ok)
 	}
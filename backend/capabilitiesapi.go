@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"fmt"
+
+	"smart-spotlight-ai/backend/packages/llm/capabilities"
+	"smart-spotlight-ai/backend/settings"
+)
+
+// GetModelCapabilities returns what is known about the model of a profile
+func (a *App) GetModelCapabilities(profileName string) (capabilities.Capabilities, error) {
+	profile, ok := settings.GetCurrentSettings().Profile(profileName)
+	if !ok {
+		return capabilities.Capabilities{}, fmt.Errorf("profile %s not found", profileName)
+	}
+	return a.capabilities.Lookup(profile.Provider, profile.Model), nil
+}
+
+// GetCapabilityOverrides returns the user's capability overrides
+func (a *App) GetCapabilityOverrides() []capabilities.Override {
+	return settings.GetCurrentSettings().CapabilityOverrides
+}
+
+// SetCapabilityOverride adds an override, replacing one for the same
+// provider and model pattern
+func (a *App) SetCapabilityOverride(override capabilities.Override) error {
+	if override.Model == "" {
+		return fmt.Errorf("model pattern is required")
+	}
+	return a.modifyCapabilityOverrides(func(overrides []capabilities.Override) []capabilities.Override {
+		for i, existing := range overrides {
+			if existing.Provider == override.Provider && existing.Model == override.Model {
+				overrides[i] = override
+				return overrides
+			}
+		}
+		return append(overrides, override)
+	})
+}
+
+// DeleteCapabilityOverride removes the override for a provider and model pattern
+func (a *App) DeleteCapabilityOverride(provider, model string) error {
+	return a.modifyCapabilityOverrides(func(overrides []capabilities.Override) []capabilities.Override {
+		kept := overrides[:0]
+		for _, existing := range overrides {
+			if existing.Provider != provider || existing.Model != model {
+				kept = append(kept, existing)
+			}
+		}
+		return kept
+	})
+}
+
+func (a *App) modifyCapabilityOverrides(modify func([]capabilities.Override) []capabilities.Override) error {
+	current, err := settings.ModifySettings(func(s *settings.Settings) error {
+		overrides := append([]capabilities.Override(nil), s.CapabilityOverrides...)
+		s.CapabilityOverrides = modify(overrides)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save capability overrides: %w", err)
+	}
+	a.capabilities.SetUserOverrides(current.CapabilityOverrides)
+	return nil
+}
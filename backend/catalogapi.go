@@ -0,0 +1,93 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"smart-spotlight-ai/backend/settings"
+)
+
+// CatalogEntryInfo is a catalog entry with whether its runtime is installed
+type CatalogEntryInfo struct {
+	settings.CatalogEntry
+	RuntimeAvailable bool `json:"runtimeAvailable"`
+}
+
+// GetMCPCatalog lists the known servers from the bundled and user registries
+func (a *App) GetMCPCatalog() ([]CatalogEntryInfo, error) {
+	if a.mcpServerSettingsService == nil {
+		return nil, fmt.Errorf("MCP server settings service not initialized")
+	}
+
+	pathEnv := a.loginPath()
+	entries := []CatalogEntryInfo{}
+	for _, entry := range a.mcpServerSettingsService.Catalog() {
+		entries = append(entries, CatalogEntryInfo{
+			CatalogEntry:     entry,
+			RuntimeAvailable: entry.RuntimeAvailable(pathEnv),
+		})
+	}
+	return entries, nil
+}
+
+// GetMCPCatalogDir returns the folder user registry files are read from
+func (a *App) GetMCPCatalogDir() string {
+	if a.mcpServerSettingsService == nil {
+		return ""
+	}
+	return a.mcpServerSettingsService.CatalogDir()
+}
+
+// InstallMCPCatalogServer adds a catalog entry as server name with the
+// inputs the user filled in. Secret inputs go to the secret store and are
+// saved as ${secret:...} references; while the store is locked they are
+// refused rather than written to the config in plain text.
+func (a *App) InstallMCPCatalogServer(name, id string, inputs map[string]string) error {
+	if a.mcpServerSettingsService == nil {
+		return fmt.Errorf("MCP server settings service not initialized")
+	}
+
+	storeSecret := func(key, value string) (string, error) {
+		if a.secrets == nil {
+			return "", fmt.Errorf("secret store is locked, unlock it first")
+		}
+		if err := a.secrets.Set(key, value); err != nil {
+			return "", err
+		}
+		return "${secret:" + key + "}", nil
+	}
+	deleteSecret := func(key string) {
+		if err := a.secrets.Delete(key); err != nil {
+			log.Printf("Error removing secret %s of a failed install: %v", key, err)
+		}
+	}
+	if err := a.mcpServerSettingsService.InstallFromCatalog(name, id, inputs, a.loginPath(), storeSecret, deleteSecret); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
+}
+
+// GetMCPCatalogDrift lists installed servers that no longer match their catalog entry
+func (a *App) GetMCPCatalogDrift() ([]settings.CatalogDrift, error) {
+	if a.mcpServerSettingsService == nil {
+		return nil, fmt.Errorf("MCP server settings service not initialized")
+	}
+	return a.mcpServerSettingsService.CatalogDrift(), nil
+}
+
+// loginPath returns the PATH of the user's login shell, falling back to ours
+func (a *App) loginPath() string {
+	path := ""
+	for _, kv := range a.loginEnv {
+		if value, ok := strings.CutPrefix(kv, "PATH="); ok {
+			path = value
+		}
+	}
+	if path == "" {
+		path = os.Getenv("PATH")
+	}
+	return path
+}
@@ -0,0 +1,188 @@
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"smart-spotlight-ai/backend/llm/mcphost"
+	"smart-spotlight-ai/backend/settings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// gatewayTokenFileName keeps the gateway token generated when
+// SPOT_AI_GATEWAY_TOKEN is not set
+const gatewayTokenFileName = "mcp-gateway-token"
+
+// startMCPGateway serves the enabled MCP servers to other clients at
+// SPOT_AI_GATEWAY_ADDR, if set. Confirmations show up in the spotlight window.
+func (a *App) startMCPGateway() error {
+	addr := settings.GetEnvWithDefault("SPOT_AI_GATEWAY_ADDR", "")
+	if addr == "" {
+		return nil
+	}
+	if a.mcpServerSettingsService == nil {
+		return fmt.Errorf("the MCP gateway needs the server settings")
+	}
+	token, err := gatewayToken()
+	if err != nil {
+		return err
+	}
+
+	gateway := mcphost.NewGateway(mcphost.GatewayOptions{
+		ConnectOptions: a.connectOptions(),
+		Emit:           a.emitGatewayEvent,
+	}, a.version)
+	handler, err := gateway.Handler(token)
+	if err != nil {
+		return err
+	}
+	a.gateway = gateway
+	if err := a.ReloadMCPGateway(); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	a.gatewayServer = &http.Server{Handler: handler}
+	go func() {
+		if err := a.gatewayServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("MCP gateway stopped: %v", err)
+		}
+	}()
+	log.Printf("MCP gateway listening on http://%s%s", listener.Addr(), mcphost.GatewayEndpoint)
+	return nil
+}
+
+// stopMCPGateway shuts the gateway down and disconnects its servers
+func (a *App) stopMCPGateway() {
+	if a.gatewayServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		a.gatewayServer.Shutdown(ctx)
+	}
+	if a.gateway != nil {
+		a.gateway.Close()
+	}
+}
+
+// emitGatewayEvent shows a confirmation request of a gateway call. Those
+// calls arrive while the window is hidden, so it is brought up.
+func (a *App) emitGatewayEvent(ev mcphost.PromptEvent) {
+	runtime.WindowShow(a.ctx)
+	runtime.EventsEmit(a.ctx, "PromptEvent", map[string]interface{}{
+		"Type": ev.Type,
+		"Data": ev.Data,
+	})
+}
+
+// mcpServersChanged relaunches the gateway's servers after the server
+// settings change; the prompt loop picks changes up on its next start
+func (a *App) mcpServersChanged() {
+	if a.gateway == nil {
+		return
+	}
+	if err := a.ReloadMCPGateway(); err != nil {
+		log.Printf("Error reloading MCP gateway: %v", err)
+	}
+}
+
+// ReloadMCPGateway reconnects the gateway to the currently enabled servers
+func (a *App) ReloadMCPGateway() error {
+	if a.gateway == nil {
+		return fmt.Errorf("MCP gateway is not enabled")
+	}
+	if a.mcpServerSettingsService == nil {
+		return fmt.Errorf("MCP server settings service not initialized")
+	}
+
+	// the last reload must also be the last to connect
+	a.gatewayMu.Lock()
+	defer a.gatewayMu.Unlock()
+
+	// the MCP host reads the same JSON we save, so that is how configs cross over
+	data, err := json.Marshal(a.mcpServerSettingsService.GetMCPConfig())
+	if err != nil {
+		return fmt.Errorf("failed to encode server config: %w", err)
+	}
+	var config mcphost.MCPConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to decode server config: %w", err)
+	}
+	return a.gateway.Connect(&config)
+}
+
+// GetMCPGatewayURL returns the address other MCP clients connect to, or ""
+// when the gateway is disabled
+func (a *App) GetMCPGatewayURL() string {
+	if a.gateway == nil {
+		return ""
+	}
+	return "http://" + settings.GetEnvWithDefault("SPOT_AI_GATEWAY_ADDR", "") + mcphost.GatewayEndpoint
+}
+
+// GetMCPGatewayToken returns the bearer token other MCP clients must send,
+// or "" when the gateway is disabled
+func (a *App) GetMCPGatewayToken() (string, error) {
+	if a.gateway == nil {
+		return "", nil
+	}
+	return gatewayToken()
+}
+
+// gatewayToken returns SPOT_AI_GATEWAY_TOKEN or, when it is not set, the
+// token kept in the config directory, generated on first use
+func gatewayToken() (string, error) {
+	if token := settings.GetEnvWithDefault("SPOT_AI_GATEWAY_TOKEN", ""); token != "" {
+		return token, nil
+	}
+	configDir, err := settings.GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	path := filepath.Join(configDir, gatewayTokenFileName)
+	data, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read gateway token: %w", err)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate gateway token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to save gateway token: %w", err)
+	}
+	return token, nil
+}
+
+// RunMCPGatewayStdio serves the gateway of the running app on stdin and
+// stdout, for MCP clients that can only launch stdio servers
+func RunMCPGatewayStdio(version string) error {
+	addr := settings.GetEnvWithDefault("SPOT_AI_GATEWAY_ADDR", "")
+	if addr == "" {
+		return fmt.Errorf("SPOT_AI_GATEWAY_ADDR is not set; it must match the address the app serves the gateway on")
+	}
+	token, err := gatewayToken()
+	if err != nil {
+		return err
+	}
+	return mcphost.ServeGatewayBridge(context.Background(), "http://"+addr+mcphost.GatewayEndpoint, token, version, os.Stdin, os.Stdout)
+}
@@ -0,0 +1,147 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"smart-spotlight-ai/backend/llm/mcphost"
+	"smart-spotlight-ai/backend/settings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// connectOptions are what MCP servers launched outside the MCP service,
+// by the gateway or the inspector, start with
+func (a *App) connectOptions() mcphost.ConnectOptions {
+	return mcphost.ConnectOptions{
+		Secrets: a.secretLookup(),
+		BaseEnv: a.loginEnv,
+		Auth:    a.mcpAuth,
+	}
+}
+
+// TestMCPSTDIOServer launches a stdio server without saving it and returns
+// what it reports on initialize. Its stderr shows up in the server's logs
+// while the MCP service runs.
+func (a *App) TestMCPSTDIOServer(name, command string, args []string, env map[string]string) (*mcphost.ServerInfo, error) {
+	config := settings.STDIOServerConfig{Command: command, Args: args, Env: env}
+	// re-testing a saved server keeps its stored secrets and sandbox
+	if a.mcpServerSettingsService != nil {
+		if existing, ok := a.mcpServerSettingsService.GetServer(name); ok {
+			if stdioConfig, ok := existing.Config.(settings.STDIOServerConfig); ok {
+				unmasked, err := unmaskEnv(stdioConfig.Env, env)
+				if err != nil {
+					return nil, err
+				}
+				config.Env = unmasked
+				config.ExecOptions = stdioConfig.ExecOptions
+			}
+		}
+	}
+	return a.testMCPServer(name, config)
+}
+
+// TestMCPSSEServer connects to a remote server without saving it and
+// returns what it reports on initialize
+func (a *App) TestMCPSSEServer(name, url string, headers []string) (*mcphost.ServerInfo, error) {
+	config := settings.SSEServerConfig{Url: url, Headers: headers}
+	if a.mcpServerSettingsService != nil {
+		if existing, ok := a.mcpServerSettingsService.GetServer(name); ok {
+			if sseConfig, ok := existing.Config.(settings.SSEServerConfig); ok {
+				unmasked, err := unmaskHeaders(sseConfig.Headers, headers)
+				if err != nil {
+					return nil, err
+				}
+				config.Headers = unmasked
+			}
+		}
+	}
+	return a.testMCPServer(name, config)
+}
+
+func (a *App) testMCPServer(name string, config settings.ServerConfig) (*mcphost.ServerInfo, error) {
+	server, err := hostServerConfig(settings.ServerConfigWrapper{Config: config, Enabled: true})
+	if err != nil {
+		return nil, err
+	}
+	if a.mcpService == nil {
+		return mcphost.NewInspector(a.connectOptions()).TestConnect(name, server)
+	}
+	return a.mcpService.TestConnect(name, server)
+}
+
+// hostServerConfig converts a saved server config for the MCP host. The
+// host reads the same JSON we save, so that is how configs cross over.
+func hostServerConfig(config settings.ServerConfigWrapper) (mcphost.ServerConfigWrapper, error) {
+	var server mcphost.ServerConfigWrapper
+	data, err := json.Marshal(config)
+	if err != nil {
+		return server, fmt.Errorf("failed to encode server config: %w", err)
+	}
+	if err := json.Unmarshal(data, &server); err != nil {
+		return server, fmt.Errorf("failed to decode server config: %w", err)
+	}
+	return server, nil
+}
+
+// savedServer returns the saved config of a server for inspecting it
+// without the MCP service, which needs an LLM provider to exist
+func (a *App) savedServer(name string) (mcphost.ServerConfigWrapper, error) {
+	if a.mcpServerSettingsService == nil {
+		return mcphost.ServerConfigWrapper{}, fmt.Errorf("MCP server settings service not initialized")
+	}
+	config, ok := a.mcpServerSettingsService.GetServer(name)
+	if !ok {
+		return mcphost.ServerConfigWrapper{}, fmt.Errorf("server %s not found", name)
+	}
+	return hostServerConfig(config)
+}
+
+// ListMCPServerTools lists every tool of a server with its input schema
+func (a *App) ListMCPServerTools(name string) ([]mcp.Tool, error) {
+	if a.mcpService != nil {
+		return a.mcpService.InspectTools(name)
+	}
+	server, err := a.savedServer(name)
+	if err != nil {
+		return nil, err
+	}
+	return mcphost.NewInspector(a.connectOptions()).InspectTools(name, server)
+}
+
+// ListMCPServerResources lists the resources and resource templates of a server
+func (a *App) ListMCPServerResources(name string) (*mcphost.ServerResources, error) {
+	if a.mcpService != nil {
+		return a.mcpService.InspectResources(name)
+	}
+	server, err := a.savedServer(name)
+	if err != nil {
+		return nil, err
+	}
+	return mcphost.NewInspector(a.connectOptions()).InspectResources(name, server)
+}
+
+// ListMCPServerPrompts lists the prompts of a server with their arguments
+func (a *App) ListMCPServerPrompts(name string) ([]mcp.Prompt, error) {
+	if a.mcpService != nil {
+		return a.mcpService.InspectPrompts(name)
+	}
+	server, err := a.savedServer(name)
+	if err != nil {
+		return nil, err
+	}
+	return mcphost.NewInspector(a.connectOptions()).InspectPrompts(name, server)
+}
+
+// CallMCPTool runs a tool by hand with a JSON object of arguments and
+// returns the server's raw result
+func (a *App) CallMCPTool(name, tool, arguments string) (*mcp.CallToolResult, error) {
+	if a.mcpService != nil {
+		return a.mcpService.CallToolManually(name, tool, arguments)
+	}
+	server, err := a.savedServer(name)
+	if err != nil {
+		return nil, err
+	}
+	return mcphost.NewInspector(a.connectOptions()).CallToolManually(name, server, tool, arguments)
+}
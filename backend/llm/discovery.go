@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultModelTTL is how long a discovered model list is served from the cache
+const DefaultModelTTL = 24 * time.Hour
+
+const discoveryTimeout = 15 * time.Second
+
+// ModelList is what discovery found at one endpoint
+type ModelList struct {
+	Provider  string             `json:"provider"`
+	BaseURL   string             `json:"baseUrl,omitempty"`
+	Models    []models.ModelInfo `json:"models"`
+	FetchedAt time.Time          `json:"fetchedAt"`
+}
+
+// IDs returns the model IDs in the list
+func (l ModelList) IDs() []string {
+	ids := make([]string, len(l.Models))
+	for i, model := range l.Models {
+		ids[i] = model.ID
+	}
+	return ids
+}
+
+// Discovery asks providers which models they offer and caches the answers,
+// on disk when a cache file is given so lists survive restarts
+type Discovery struct {
+	mu    sync.Mutex
+	path  string
+	ttl   time.Duration
+	now   func() time.Time
+	lists map[string]ModelList // cacheKey -> list
+}
+
+// NewDiscovery creates a discovery service caching to path; an empty path
+// keeps the cache in memory
+func NewDiscovery(path string) *Discovery {
+	d := &Discovery{
+		path:  path,
+		ttl:   DefaultModelTTL,
+		now:   time.Now,
+		lists: make(map[string]ModelList),
+	}
+	if path == "" {
+		return d
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to read model cache", "path", path, "error", err)
+		}
+		return d
+	}
+	if err := json.Unmarshal(data, &d.lists); err != nil {
+		slog.Warn("ignoring unreadable model cache", "path", path, "error", err)
+		d.lists = make(map[string]ModelList)
+	}
+	return d
+}
+
+// cacheKey identifies an endpoint; profiles sharing one share its list
+func cacheKey(config providers.Config) string {
+	return config.Provider + "|" + config.BaseURL
+}
+
+// Cached returns the last list fetched for the endpoint of config, however old
+func (d *Discovery) Cached(config providers.Config) (ModelList, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	list, ok := d.lists[cacheKey(config)]
+	return list, ok
+}
+
+// Models returns the models offered at the endpoint of config. A cached
+// list younger than the TTL is returned without asking the provider unless
+// refresh is set. When asking fails the stale list, if any, is returned
+// along with the error.
+func (d *Discovery) Models(ctx context.Context, config providers.Config, refresh bool) (ModelList, error) {
+	cached, ok := d.Cached(config)
+	if ok && !refresh && d.now().Sub(cached.FetchedAt) < d.ttl {
+		return cached, nil
+	}
+
+	list, err := d.fetch(ctx, config)
+	if err != nil {
+		return cached, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lists[cacheKey(config)] = list
+	if err := d.save(); err != nil {
+		slog.Warn("failed to write model cache", "path", d.path, "error", err)
+	}
+	return list, nil
+}
+
+func (d *Discovery) fetch(ctx context.Context, config providers.Config) (ModelList, error) {
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+
+	provider, err := providers.New(ctx, config)
+	if err != nil {
+		return ModelList{}, err
+	}
+	lister, ok := provider.(models.ModelLister)
+	if !ok {
+		return ModelList{}, fmt.Errorf("%s cannot list its models", config.Provider)
+	}
+	infos, err := lister.ListModels(ctx)
+	if err != nil {
+		return ModelList{}, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+
+	return ModelList{
+		Provider:  config.Provider,
+		BaseURL:   config.BaseURL,
+		Models:    infos,
+		FetchedAt: d.now(),
+	}, nil
+}
+
+// save writes the cache file; callers hold d.mu
+func (d *Discovery) save() error {
+	if d.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(d.lists, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(d.path, data, 0600)
+}
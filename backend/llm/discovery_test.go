@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers"
+	"testing"
+	"time"
+)
+
+func TestDiscoveryCachesModelLists(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/models" {
+			t.Errorf("Expected a request for /models, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data": [
+			{"id": "vendor/vision-model", "context_length": 128000,
+			 "architecture": {"input_modalities": ["text", "image"]},
+			 "supported_parameters": ["tools", "temperature"]},
+			{"id": "local-model", "max_model_len": 8192}
+		]}`)
+	}))
+	defer server.Close()
+
+	config := providers.Config{Provider: "openai", APIKey: "sk-test", BaseURL: server.URL}
+	path := filepath.Join(t.TempDir(), "models.json")
+	discovery := NewDiscovery(path)
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	discovery.now = func() time.Time { return now }
+
+	list, err := discovery.Models(context.Background(), config, false)
+	if err != nil {
+		t.Fatalf("Models failed: %v", err)
+	}
+	if len(list.Models) != 2 || !list.FetchedAt.Equal(now) {
+		t.Fatalf("Expected 2 models fetched now, got %+v", list)
+	}
+	local, vision := list.Models[0], list.Models[1]
+	if local.ID != "local-model" || local.ContextWindow != 8192 || local.Tools != nil {
+		t.Errorf("Expected the vLLM context length and unknown tool support, got %+v", local)
+	}
+	if vision.ContextWindow != 128000 || vision.Vision == nil || !*vision.Vision || vision.Tools == nil || !*vision.Tools {
+		t.Errorf("Expected the reported limits and capabilities, got %+v", vision)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := discovery.Models(context.Background(), config, false); err != nil || requests != 1 {
+		t.Errorf("Expected a fresh list to come from the cache, got %d requests, %v", requests, err)
+	}
+	if _, err := discovery.Models(context.Background(), config, true); err != nil || requests != 2 {
+		t.Errorf("Expected refresh to ask again, got %d requests, %v", requests, err)
+	}
+
+	reloaded := NewDiscovery(path)
+	cached, ok := reloaded.Cached(config)
+	if !ok || !cached.FetchedAt.Equal(now) || len(cached.IDs()) != 2 {
+		t.Errorf("Expected the cache to survive a restart, got %+v", cached)
+	}
+
+	server.Close()
+	reloaded.now = func() time.Time { return now.Add(2 * DefaultModelTTL) }
+	stale, err := reloaded.Models(context.Background(), config, false)
+	if _, ok := models.AsProviderError(err); !ok {
+		t.Errorf("Expected a provider error once the endpoint is gone, got %v", err)
+	}
+	if len(stale.Models) != 2 {
+		t.Errorf("Expected the stale list with the error, got %+v", stale)
+	}
+}
@@ -1,160 +1,112 @@
 package llm
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"smart-spotlight-ai/backend/settings"
+	"log/slog"
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers"
 	"time"
 )
 
+// DefaultSystemPrompt is used by the plain search when no prompt is configured
+const DefaultSystemPrompt = "You are a helpful assistant. Respond in markdown format. Keep responses concise but informative."
+
+const testTimeout = 10 * time.Second
+
+// searchTimeout is how long each provider of a fallback chain gets to answer
+var searchTimeout = 30 * time.Second
+
 // ChatResponse represents the response from the LLM
 type ChatResponse struct {
-	Content string `json:"content"`
-	Error   string `json:"error,omitempty"`
+	Content  string `json:"content"`
+	Provider string `json:"provider,omitempty"` // profile that answered
+	Error    string `json:"error,omitempty"`
 }
 
 // Service handles LLM operations
 type Service struct {
-	settings *settings.Settings
+	chain []providers.Config
 }
 
-// NewService creates a new LLM service
-func NewService(settings *settings.Settings) *Service {
+// NewService creates a new LLM service. The first configuration is tried
+// first, the others in order when it fails.
+func NewService(chain ...providers.Config) *Service {
 	return &Service{
-		settings: settings,
+		chain: chain,
 	}
 }
 
 // Search performs a search using the configured LLM
 func (s *Service) Search(query string) (*ChatResponse, error) {
-	messages := []map[string]string{
-		{
-			"role":    "system",
-			"content": "You are a helpful assistant. Respond in markdown format. Keep responses concise but informative.",
-		},
-		{
-			"role":    "user",
-			"content": query,
-		},
-	}
-
-	reqBody := map[string]interface{}{
-		"model":       s.settings.Model,
-		"messages":    messages,
-		"temperature": 0.7,
-		"max_tokens":  2000,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("error preparing request: %w", err)
-	}
-
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	return s.SearchWith(s.chain, query)
+}
 
-	req, err := http.NewRequest("POST", s.settings.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+// SearchWith performs a search on another fallback chain. A provider that
+// fails with an error another provider might not hit, including running out
+// of its own searchTimeout, hands over to the next.
+func (s *Service) SearchWith(chain []providers.Config, query string) (*ChatResponse, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no provider configured")
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.settings.APIKey)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error connecting to API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		var errorResponse map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err == nil {
-			if errMsg, ok := errorResponse["error"].(map[string]interface{}); ok {
-				return &ChatResponse{Error: fmt.Sprintf("%v", errMsg["message"])}, nil
-			}
+	var err error
+	for i, config := range chain {
+		if config.SystemPrompt == "" {
+			config.SystemPrompt = DefaultSystemPrompt
 		}
-		return &ChatResponse{Error: fmt.Sprintf("API returned status code %d", resp.StatusCode)}, nil
-	}
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
-	}
 
-	choices, ok := result["choices"].([]interface{})
-	if !ok || len(choices) == 0 {
-		return nil, fmt.Errorf("invalid response format")
-	}
-
-	firstChoice, ok := choices[0].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid choice format")
-	}
-
-	message, ok := firstChoice["message"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid message format")
-	}
-
-	content, ok := message["content"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid content format")
+		var content string
+		ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+		content, err = s.send(ctx, config, query)
+		cancel()
+		if err == nil {
+			return &ChatResponse{Content: content, Provider: config.Name}, nil
+		}
+		if !models.ShouldFallback(err) {
+			return nil, err
+		}
+		if i < len(chain)-1 {
+			slog.Warn("provider failed, trying the next one", "profile", config.Name, "error", err)
+		}
 	}
-
-	return &ChatResponse{Content: content}, nil
+	return nil, err
 }
 
 // TestAPIConnection tests if the API settings are valid
 func (s *Service) TestAPIConnection() error {
-	// Create a simple request to test the API
-	reqBody := map[string]interface{}{
-		"model": s.settings.Model,
-		"messages": []map[string]string{
-			{
-				"role":    "user",
-				"content": "Hello, this is a test message.",
-			},
-		},
-		"max_tokens":  5,   // Fixed small value for testing
-		"temperature": 0.7, // Fixed default value
+	if len(s.chain) == 0 {
+		return fmt.Errorf("no provider configured")
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("error preparing request: %w", err)
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	// Test the primary provider only and keep it cheap, the reply itself does not matter
+	config := s.chain[0]
+	config.Params.MaxTokens = 5
+	_, err := s.send(ctx, config, "Hello, this is a test message.")
+	return err
+}
 
-	req, err := http.NewRequest("POST", s.settings.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+// send asks the provider described by config a single question
+func (s *Service) send(ctx context.Context, config providers.Config, query string) (string, error) {
+	provider, err := providers.New(ctx, config)
 	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+		return "", err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.settings.APIKey)
-
-	resp, err := client.Do(req)
+	message := &history.HistoryMessage{
+		Role:    "user",
+		Content: []history.ContentBlock{{Type: "text", Text: query}},
+	}
+	reply, err := provider.CreateMessage(ctx, "", []models.Message{message}, nil)
 	if err != nil {
-		return fmt.Errorf("error connecting to API: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		var errorResponse map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err == nil {
-			if errMsg, ok := errorResponse["error"].(map[string]interface{}); ok {
-				return fmt.Errorf("API error: %v", errMsg["message"])
-			}
-		}
-		return fmt.Errorf("API returned status code %d", resp.StatusCode)
+	if reply == nil {
+		return "", fmt.Errorf("%s returned no message", provider.Name())
 	}
-
-	return nil
+	return reply.GetContent(), nil
 }
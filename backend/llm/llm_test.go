@@ -2,9 +2,14 @@ package llm
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers"
 	"smart-spotlight-ai/backend/settings"
 	"testing"
+	"time"
 )
 
 func TestLLMService(t *testing.T) {
@@ -17,23 +22,21 @@ func TestLLMService(t *testing.T) {
 		t.Skip("Skipping test: Required environment variables not set")
 	}
 
-	// Create settings for test
-	testSettings := &settings.Settings{
-		APIKey:  apiKey,
-		Model:   model,
-		BaseURL: baseURL,
+	// Create provider configuration for test
+	config := providers.Config{
+		Provider: settings.GetEnvWithDefault("SPOT_AI_PROVIDER", "openai"),
+		APIKey:   apiKey,
+		Model:    model,
+		BaseURL:  baseURL,
 	}
 
 	// Create service instance
-	service := NewService(testSettings)
+	service := NewService(config)
 
 	t.Run("Test LLM Search", func(t *testing.T) {
 		response, err := service.Search("What is clojure?")
 		if err != nil {
-			t.Errorf("Search failed: %v", err)
-		}
-		if response == nil {
-			t.Error("Expected non-nil response")
+			t.Fatalf("Search failed: %v", err)
 		}
 		fmt.Printf("Response: %+v\n", response)
 		if response.Content == "" {
@@ -44,3 +47,70 @@ func TestLLMService(t *testing.T) {
 		}
 	})
 }
+
+func TestLLMServiceMissingKey(t *testing.T) {
+	service := NewService(providers.Config{Provider: "anthropic", Model: "claude-3-5-haiku-latest"})
+
+	_, err := service.Search("What is clojure?")
+	providerErr, ok := models.AsProviderError(err)
+	if !ok {
+		t.Fatalf("Expected a provider error, got %v", err)
+	}
+	if providerErr.Kind != models.ErrorAuth || providerErr.Retryable() {
+		t.Errorf("Expected a non-retryable auth error, got %+v", providerErr)
+	}
+}
+
+func TestSearchFallsBackToNextProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "Clojure is a Lisp."}}]}`)
+	}))
+	defer server.Close()
+
+	service := NewService(
+		providers.Config{Name: "claude", Provider: "anthropic", Model: "claude-sonnet-4-5"},
+		providers.Config{Name: "backup", Provider: "openai", APIKey: "sk-test", Model: "gpt-4o", BaseURL: server.URL},
+	)
+
+	response, err := service.Search("What is clojure?")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if response.Provider != "backup" || response.Content != "Clojure is a Lisp." {
+		t.Errorf("Expected the backup answer, got %+v", response)
+	}
+}
+
+func TestSearchGivesEachProviderItsOwnTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { searchTimeout = timeout }(searchTimeout)
+	searchTimeout = 200 * time.Millisecond
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * searchTimeout):
+		}
+	}))
+	defer slow.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the backup uses most of its own timeout, which the primary already used up
+		time.Sleep(searchTimeout / 2)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "Clojure is a Lisp."}}]}`)
+	}))
+	defer backup.Close()
+
+	service := NewService(
+		providers.Config{Name: "slow", Provider: "openai", APIKey: "sk-test", Model: "gpt-4o", BaseURL: slow.URL},
+		providers.Config{Name: "backup", Provider: "openai", APIKey: "sk-test", Model: "gpt-4o", BaseURL: backup.URL},
+	)
+
+	response, err := service.Search("What is clojure?")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if response.Provider != "backup" || response.Content != "Clojure is a Lisp." {
+		t.Errorf("Expected the backup answer, got %+v", response)
+	}
+}
@@ -0,0 +1,151 @@
+package mcphost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"smart-spotlight-ai/backend/packages/oauth"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// authorizationTimeout is how long we wait for the user to finish signing in
+const authorizationTimeout = 5 * time.Minute
+
+// clientHooks returns the hooks the service uses when connecting servers
+func (s *MCPService) clientHooks() *clientHooks {
+	return &clientHooks{
+		headers:      s.authHeaders,
+		unauthorized: s.startAuthorization,
+		elicitation:  s.elicitationHandler,
+		secrets:      s.secretLookup(),
+		exited:       s.recordServerExit,
+		baseEnv:      s.settings.BaseEnv,
+		status:       s.setServerStatus,
+		stderr:       s.appendStderr,
+		initialized:  s.recordServerInfo,
+	}
+}
+
+// authHeaders adds a bearer token for servers we hold OAuth credentials for
+func (s *MCPService) authHeaders(name, url string) map[string]string {
+	return bearerHeaders(s.auth, s.logger, name, url)
+}
+
+// bearerHeaders returns the Authorization header for a server auth holds a
+// token for, refreshing it when needed
+func bearerHeaders(auth *oauth.Manager, logger *slog.Logger, name, url string) map[string]string {
+	if auth == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, err := auth.Token(ctx, name, url)
+	if err != nil {
+		if !errors.Is(err, oauth.ErrNoToken) {
+			logger.Warn("failed to load OAuth token", "server", name, "error", err)
+		}
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + token.AccessToken}
+}
+
+// startAuthorization runs the OAuth flow for a server in the background and
+// connects it once the user has granted access. The frontend receives an
+// EventAuthorization carrying the URL to open.
+func (s *MCPService) startAuthorization(name string, cfg SSEServerConfig) {
+	if s.auth == nil {
+		s.logger.Warn("server requires authorization but OAuth is disabled", "server", name)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), authorizationTimeout)
+		defer cancel()
+
+		_, err := s.auth.Authorize(ctx, name, cfg.Url, func(authURL string) {
+			s.emit(PromptEvent{
+				Type: EventAuthorization,
+				Data: map[string]any{
+					"server": name,
+					"url":    authURL,
+				},
+			})
+		})
+		if err != nil {
+			s.logger.Error("authorization failed", "server", name, "error", err)
+			s.emit(PromptEvent{Type: EventError, Data: fmt.Sprintf("authorization for %s failed: %v", name, err)})
+			return
+		}
+
+		client, err := createMCPClient(name, ServerConfigWrapper{Config: cfg}, s.clientHooks())
+		if err != nil {
+			s.logger.Error("failed to connect after authorization", "server", name, "error", err)
+			s.setServerStatus(ServerStatus{Name: name, State: ServerFailed, Error: err.Error()})
+			s.emit(PromptEvent{Type: EventError, Data: err.Error()})
+			return
+		}
+
+		s.setServerStatus(ServerStatus{Name: name, State: ServerRunning})
+		s.addClient(name, client)
+		s.registerNotificationHandlers(name, client)
+		if err := s.refreshServerTools(name); err != nil {
+			s.logger.Error("failed to fetch tools", "server", name, "error", err)
+		}
+	}()
+}
+
+// getClient returns the connected client of a server
+func (s *MCPService) getClient(name string) (mcpclient.MCPClient, bool) {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	client, ok := s.mcpClients[name]
+	return client, ok
+}
+
+// addClient registers a client connected after startup, replacing any old one
+func (s *MCPService) addClient(name string, client mcpclient.MCPClient) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	if s.mcpClients == nil {
+		s.mcpClients = make(map[string]mcpclient.MCPClient)
+	}
+	if old, ok := s.mcpClients[name]; ok {
+		old.Close()
+	}
+	s.mcpClients[name] = client
+}
+
+// authErrorTransport reports a 401 answer as mcp-go's
+// OAuthAuthorizationRequiredError, which its transports only return when they
+// run the OAuth flow themselves. A 401 once the connection is established
+// means the token expired or was revoked; expired is then called, once.
+type authErrorTransport struct {
+	base        http.RoundTripper
+	established atomic.Bool
+	expired     func()
+	expiredOnce sync.Once
+}
+
+func (t *authErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+	if t.established.Load() && t.expired != nil {
+		t.expiredOnce.Do(t.expired)
+	}
+	return nil, &transport.OAuthAuthorizationRequiredError{}
+}
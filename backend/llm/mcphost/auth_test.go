@@ -0,0 +1,151 @@
+package mcphost
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"smart-spotlight-ai/backend/packages/oauth"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newProtectedSSEServer serves an MCP server that requires a bearer token,
+// together with the default /authorize, /token and /register endpoints.
+// Calling revoke makes the server reject the token it handed out.
+func newProtectedSSEServer(t *testing.T) (ts *httptest.Server, revoke func()) {
+	mcpServer := server.NewMCPServer("protected", "1.0.0")
+	mcpServer.AddTool(mcp.NewTool("whoami"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("authorized user"), nil
+	})
+
+	var revoked atomic.Bool
+	const accessToken = "stand-in-token"
+	mux := http.NewServeMux()
+	ts = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		// the SSE stream never ends on its own
+		ts.CloseClientConnections()
+		ts.Close()
+	})
+
+	sse := server.NewSSEServer(mcpServer, server.WithBaseURL(ts.URL))
+	protected := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+accessToken || revoked.Load() {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		sse.ServeHTTP(w, r)
+	})
+	mux.Handle("/sse", protected)
+	mux.Handle("/message", protected)
+
+	var redirectURI string
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RedirectURIs []string `json:"redirect_uris"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		redirectURI = req.RedirectURIs[0]
+		json.NewEncoder(w).Encode(map[string]any{"client_id": "spotlight"})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, redirectURI+"?code=abc&state="+r.URL.Query().Get("state"), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"access_token": accessToken, "token_type": "Bearer"})
+	})
+
+	return ts, func() { revoked.Store(true) }
+}
+
+func TestUnauthorizedServerTriggersAuthorization(t *testing.T) {
+	ts, revoke := newProtectedSSEServer(t)
+	dir := t.TempDir()
+
+	configFile := filepath.Join(dir, "mcp.json")
+	config := `{"mcpServers": {"remote": {"url": "` + ts.URL + `/sse"}}}`
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+
+	store, err := oauth.NewStore(filepath.Join(dir, "auth.json"))
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+
+	svc := newTestService()
+	svc.settings = &MCPSettings{ConfigFile: configFile}
+	svc.auth = oauth.NewManager(store, "test")
+
+	if err := svc.InitializeClients(); err != nil {
+		t.Fatalf("InitializeClients: %v", err)
+	}
+	if len(svc.currentTools()) != 0 {
+		t.Fatal("Expected no tools before authorization")
+	}
+
+	var ev PromptEvent
+	select {
+	case ev = <-svc.EventChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected an authorization event")
+	}
+	if ev.Type != EventAuthorization {
+		t.Fatalf("Expected %s, got %s: %v", EventAuthorization, ev.Type, ev.Data)
+	}
+	data := ev.Data.(map[string]any)
+	authURL := data["url"].(string)
+	if data["server"] != "remote" || !strings.Contains(authURL, "code_challenge=") {
+		t.Fatalf("Unexpected authorization payload: %v", data)
+	}
+
+	// the user approves in the browser
+	resp, err := http.Get(authURL)
+	if err != nil {
+		t.Fatalf("visiting authorization URL: %v", err)
+	}
+	resp.Body.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(svc.currentTools()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("server was not connected after authorization")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if name := svc.currentTools()[0].Name; name != "remote__whoami" {
+		t.Errorf("Expected remote__whoami, got %s", name)
+	}
+
+	client, _ := svc.getClient("remote")
+	defer client.Close()
+
+	// a token that stops working mid-session starts the sign-in again
+	revoke()
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "whoami"
+	if _, err := client.CallTool(context.Background(), req); !isUnauthorized(err) {
+		t.Fatalf("Expected an authorization error, got %v", err)
+	}
+	select {
+	case ev = <-svc.EventChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a new authorization event")
+	}
+	if ev.Type != EventAuthorization {
+		t.Errorf("Expected %s, got %s: %v", EventAuthorization, ev.Type, ev.Data)
+	}
+	if status := svc.ServerStatuses(); len(status) != 1 || status[0].State != ServerNeedsAuth {
+		t.Errorf("Expected the server to need authorization, got %+v", status)
+	}
+}
@@ -0,0 +1,73 @@
+package mcphost
+
+import (
+	"encoding/json"
+
+	"smart-spotlight-ai/backend/packages/llm/capabilities"
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// estimateTokens approximates four characters per token, close enough for
+// English text and JSON to keep requests inside a context window
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+func messageTokens(msg history.HistoryMessage) int {
+	tokens := 4 // role and message framing
+	for _, block := range msg.Content {
+		tokens += estimateTokens(block.Text) + estimateTokens(string(block.Input))
+	}
+	return tokens
+}
+
+func toolTokens(tools []models.Tool) int {
+	tokens := 0
+	for _, tool := range tools {
+		schema, _ := json.Marshal(tool.InputSchema)
+		tokens += estimateTokens(tool.Name) + estimateTokens(tool.Description) + estimateTokens(string(schema))
+	}
+	return tokens
+}
+
+// contextBudget is how many tokens of conversation fit next to the tools and
+// the answer, or 0 when the model's context window is unknown
+func contextBudget(caps capabilities.Capabilities, maxTokens int, tools []models.Tool) int {
+	if caps.ContextWindow <= 0 {
+		return 0
+	}
+	// leave room for the answer, but never more than a quarter of the window
+	reserve := maxTokens
+	if reserve <= 0 {
+		reserve = caps.MaxOutputTokens
+	}
+	if limit := caps.ContextWindow / 4; reserve <= 0 || reserve > limit {
+		reserve = limit
+	}
+	budget := caps.ContextWindow - reserve - toolTokens(tools)
+	if budget < 1 {
+		budget = 1
+	}
+	return budget
+}
+
+// fitContext drops the oldest messages until the conversation fits budget,
+// keeping tool calls paired with their results. The latest message is
+// always kept.
+func fitContext(msgs []history.HistoryMessage, budget int) []history.HistoryMessage {
+	if budget <= 0 {
+		return msgs
+	}
+	for window := len(msgs); window > 1; window-- {
+		fitted := pruneMessages(msgs, window)
+		total := 0
+		for _, msg := range fitted {
+			total += messageTokens(msg)
+		}
+		if total <= budget {
+			return fitted
+		}
+	}
+	return pruneMessages(msgs, 1)
+}
@@ -0,0 +1,111 @@
+package mcphost
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"smart-spotlight-ai/backend/packages/llm/capabilities"
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// toolRejectingProvider fails requests that carry tools, like an Ollama
+// model without tool support
+type toolRejectingProvider struct {
+	scriptedProvider
+}
+
+func (p *toolRejectingProvider) CreateMessage(ctx context.Context, prompt string, messages []models.Message, tools []models.Tool) (models.Message, error) {
+	if len(tools) > 0 {
+		p.tools = append(p.tools, tools)
+		return nil, models.NewStatusError("ollama", 400, `registry.ollama.ai/library/gemma:2b does not support tools`)
+	}
+	return p.scriptedProvider.CreateMessage(ctx, prompt, messages, tools)
+}
+
+func TestToolsSkippedForModelsWithoutSupport(t *testing.T) {
+	registry := capabilities.NewRegistry()
+	registry.SetDiscovered("ollama", []models.ModelInfo{{ID: "gemma:2b", Tools: capabilities.Bool(false)}})
+
+	svc := newTestService()
+	svc.settings = &MCPSettings{
+		Provider:     LLMProvider{ProviderName: "ollama", ModelName: "gemma:2b"},
+		Capabilities: registry,
+	}
+	provider := &scriptedProvider{replies: []*history.HistoryMessage{answer("Plain answer.")}}
+	svc.provider = provider
+	svc.tools = []models.Tool{{Name: "notes__search", Description: "Search notes"}}
+
+	var messages []history.HistoryMessage
+	if err := svc.runLLMWithToolCycle(context.Background(), "hello", &messages); err != nil {
+		t.Fatalf("runLLMWithToolCycle: %v", err)
+	}
+	if ev := <-svc.EventChan; ev.Type != EventFinalResult {
+		t.Errorf("Expected %s, got %s: %v", EventFinalResult, ev.Type, ev.Data)
+	}
+	if len(provider.tools) != 1 || len(provider.tools[0]) != 0 {
+		t.Errorf("Expected one request without tools, got %v", provider.tools)
+	}
+}
+
+func TestToolRejectionRetriesAsPlainChat(t *testing.T) {
+	registry := capabilities.NewRegistry()
+
+	svc := newTestService()
+	svc.settings = &MCPSettings{
+		Provider:     LLMProvider{ProviderName: "ollama", ModelName: "gemma:2b"},
+		Capabilities: registry,
+	}
+	provider := &toolRejectingProvider{scriptedProvider{replies: []*history.HistoryMessage{answer("Plain answer.")}}}
+	svc.provider = provider
+	svc.tools = []models.Tool{{Name: "notes__search", Description: "Search notes"}}
+
+	var messages []history.HistoryMessage
+	if err := svc.runLLMWithToolCycle(context.Background(), "hello", &messages); err != nil {
+		t.Fatalf("runLLMWithToolCycle: %v", err)
+	}
+	if ev := <-svc.EventChan; ev.Type != EventFinalResult {
+		t.Errorf("Expected %s, got %s: %v", EventFinalResult, ev.Type, ev.Data)
+	}
+	if len(provider.tools) != 2 || len(provider.tools[1]) != 0 {
+		t.Errorf("Expected a retry without tools, got %v", provider.tools)
+	}
+	if registry.Lookup("ollama", "gemma:2b").SupportsTools() {
+		t.Errorf("Expected the rejection to be remembered")
+	}
+}
+
+func TestFitContextDropsOldestMessages(t *testing.T) {
+	long := strings.Repeat("word ", 100) // ~125 tokens
+	var msgs []history.HistoryMessage
+	for i := 0; i < 6; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		msgs = append(msgs, history.HistoryMessage{Role: role, Content: []history.ContentBlock{{Type: "text", Text: long}}})
+	}
+
+	if got := fitContext(msgs, 0); len(got) != len(msgs) {
+		t.Errorf("Expected an unknown budget to keep everything, got %d messages", len(got))
+	}
+	got := fitContext(msgs, 300)
+	if len(got) == 0 || len(got) >= len(msgs) {
+		t.Fatalf("Expected the conversation to be trimmed, got %d messages", len(got))
+	}
+	if last := got[len(got)-1]; last.Role != "assistant" || last.Content[0].Text != long {
+		t.Errorf("Expected the latest message to be kept, got %+v", last)
+	}
+	if got := fitContext(msgs, 1); len(got) == 0 {
+		t.Errorf("Expected the latest message to be kept even over budget")
+	}
+
+	caps := capabilities.Capabilities{ContextWindow: 8000, MaxOutputTokens: 4000}
+	if budget := contextBudget(caps, 0, nil); budget != 6000 {
+		t.Errorf("Expected the answer reserve capped at a quarter of the window, got %d", budget)
+	}
+	if budget := contextBudget(caps, 1000, nil); budget != 7000 {
+		t.Errorf("Expected the profile's max tokens to be reserved, got %d", budget)
+	}
+}
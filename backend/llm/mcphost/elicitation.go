@@ -0,0 +1,245 @@
+package mcphost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// elicitationTimeout is how long a server waits for the user before we cancel
+const elicitationTimeout = 5 * time.Minute
+
+// pendingElicitation is an elicitation request waiting for the user
+type pendingElicitation struct {
+	server string
+	schema map[string]any
+	reply  chan *mcp.ElicitationResult
+}
+
+// serverElicitationHandler answers elicitation requests from one server
+type serverElicitationHandler struct {
+	svc    *MCPService
+	server string
+}
+
+// Elicit forwards the request to the frontend and waits for RespondElicitation
+func (h *serverElicitationHandler) Elicit(ctx context.Context, req mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	return h.svc.elicit(ctx, h.server, req.Params)
+}
+
+// elicitationHandler returns the elicitation handler for a server
+func (s *MCPService) elicitationHandler(server string) mcpclient.ElicitationHandler {
+	return &serverElicitationHandler{svc: s, server: server}
+}
+
+func (s *MCPService) elicit(ctx context.Context, server string, params mcp.ElicitationParams) (*mcp.ElicitationResult, error) {
+	schema, err := toSchemaMap(params.RequestedSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid requested schema: %w", err)
+	}
+
+	token := uuid.NewString()
+	pending := &pendingElicitation{
+		server: server,
+		schema: schema,
+		reply:  make(chan *mcp.ElicitationResult, 1),
+	}
+
+	s.elicitMu.Lock()
+	if s.elicitations == nil {
+		s.elicitations = make(map[string]*pendingElicitation)
+	}
+	s.elicitations[token] = pending
+	s.elicitMu.Unlock()
+
+	defer func() {
+		s.elicitMu.Lock()
+		delete(s.elicitations, token)
+		s.elicitMu.Unlock()
+	}()
+
+	s.emit(PromptEvent{
+		Type: EventElicitation,
+		Data: map[string]any{
+			"token":   token,
+			"server":  server,
+			"message": params.Message,
+			"schema":  schema,
+		},
+	})
+
+	select {
+	case result := <-pending.reply:
+		return result, nil
+	case <-ctx.Done():
+		return cancelledElicitation(), nil
+	case <-time.After(elicitationTimeout):
+		s.logger.Warn("elicitation timed out", "server", server)
+		return cancelledElicitation(), nil
+	}
+}
+
+// RespondElicitation answers a pending elicitation. action is "accept",
+// "decline" or "cancel"; content is validated against the requested schema
+// on accept, and an invalid reply leaves the request pending.
+func (s *MCPService) RespondElicitation(token string, action string, content map[string]any) error {
+	s.elicitMu.Lock()
+	pending, ok := s.elicitations[token]
+	s.elicitMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no pending elicitation for token %s", token)
+	}
+
+	result := &mcp.ElicitationResult{}
+	switch mcp.ElicitationResponseAction(action) {
+	case mcp.ElicitationResponseActionAccept:
+		if err := validateElicitation(pending.schema, content); err != nil {
+			return err
+		}
+		result.Action = mcp.ElicitationResponseActionAccept
+		result.Content = content
+	case mcp.ElicitationResponseActionDecline:
+		result.Action = mcp.ElicitationResponseActionDecline
+	case mcp.ElicitationResponseActionCancel:
+		result.Action = mcp.ElicitationResponseActionCancel
+	default:
+		return fmt.Errorf("unknown elicitation action: %s", action)
+	}
+
+	select {
+	case pending.reply <- result:
+	default:
+		return fmt.Errorf("elicitation %s was already answered", token)
+	}
+	return nil
+}
+
+func cancelledElicitation() *mcp.ElicitationResult {
+	result := &mcp.ElicitationResult{}
+	result.Action = mcp.ElicitationResponseActionCancel
+	return result
+}
+
+// toSchemaMap normalizes a requested schema into a plain JSON object
+func toSchemaMap(schema any) (map[string]any, error) {
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("schema is empty")
+	}
+	return m, nil
+}
+
+// validateElicitation checks content against the restricted JSON schema MCP
+// allows for elicitation: a flat object of string, number, integer, boolean
+// and enum properties
+func validateElicitation(schema map[string]any, content map[string]any) error {
+	props, _ := schema["properties"].(map[string]any)
+
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := content[name]; !present {
+				return fmt.Errorf("%s is required", name)
+			}
+		}
+	}
+
+	for name, value := range content {
+		prop, ok := props[name].(map[string]any)
+		if !ok {
+			return fmt.Errorf("unexpected field %s", name)
+		}
+		if err := validateProperty(name, prop, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateProperty(name string, prop map[string]any, value any) error {
+	if enum, ok := prop["enum"].([]any); ok {
+		for _, allowed := range enum {
+			if allowed == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must be one of %v", name, enum)
+	}
+
+	switch prop["type"] {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", name)
+		}
+		length := float64(utf8.RuneCountInString(str))
+		if min, ok := prop["minLength"].(float64); ok && length < min {
+			return fmt.Errorf("%s must be at least %v characters", name, min)
+		}
+		if max, ok := prop["maxLength"].(float64); ok && length > max {
+			return fmt.Errorf("%s must be at most %v characters", name, max)
+		}
+		if format, ok := prop["format"].(string); ok {
+			return validateFormat(name, format, str)
+		}
+	case "number", "integer":
+		num, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s must be a number", name)
+		}
+		if prop["type"] == "integer" && num != math.Trunc(num) {
+			return fmt.Errorf("%s must be an integer", name)
+		}
+		if min, ok := prop["minimum"].(float64); ok && num < min {
+			return fmt.Errorf("%s must be at least %v", name, min)
+		}
+		if max, ok := prop["maximum"].(float64); ok && num > max {
+			return fmt.Errorf("%s must be at most %v", name, max)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be true or false", name)
+		}
+	default:
+		return fmt.Errorf("%s has unsupported type %v", name, prop["type"])
+	}
+	return nil
+}
+
+func validateFormat(name, format, value string) error {
+	switch format {
+	case "email":
+		if _, err := mail.ParseAddress(value); err != nil {
+			return fmt.Errorf("%s must be an email address", name)
+		}
+	case "uri":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" {
+			return fmt.Errorf("%s must be a URI", name)
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return fmt.Errorf("%s must be a date (YYYY-MM-DD)", name)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("%s must be a date-time", name)
+		}
+	}
+	return nil
+}
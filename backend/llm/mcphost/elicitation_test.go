@@ -0,0 +1,125 @@
+package mcphost
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var contactSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"name":  map[string]any{"type": "string", "minLength": 2},
+		"email": map[string]any{"type": "string", "format": "email"},
+		"age":   map[string]any{"type": "integer", "minimum": 0},
+		"plan":  map[string]any{"type": "string", "enum": []any{"free", "pro"}},
+		"optIn": map[string]any{"type": "boolean"},
+	},
+	"required": []any{"name", "email"},
+}
+
+func TestValidateElicitation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content map[string]any
+		wantErr bool
+	}{
+		{"valid", map[string]any{"name": "Ada", "email": "ada@example.com", "age": float64(36), "plan": "pro", "optIn": true}, false},
+		{"missing required", map[string]any{"name": "Ada"}, true},
+		{"too short", map[string]any{"name": "A", "email": "ada@example.com"}, true},
+		{"bad email", map[string]any{"name": "Ada", "email": "not an email"}, true},
+		{"fractional integer", map[string]any{"name": "Ada", "email": "ada@example.com", "age": 3.5}, true},
+		{"below minimum", map[string]any{"name": "Ada", "email": "ada@example.com", "age": float64(-1)}, true},
+		{"not in enum", map[string]any{"name": "Ada", "email": "ada@example.com", "plan": "gold"}, true},
+		{"wrong type", map[string]any{"name": "Ada", "email": "ada@example.com", "optIn": "yes"}, true},
+		{"unknown field", map[string]any{"name": "Ada", "email": "ada@example.com", "extra": 1}, true},
+	}
+
+	// schemas arrive as JSON, so numbers are float64
+	schema, err := toSchemaMap(contactSchema)
+	if err != nil {
+		t.Fatalf("toSchemaMap: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateElicitation(schema, tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// startElicitation runs an elicitation in the background and returns its token
+func startElicitation(t *testing.T, svc *MCPService) (string, chan *mcp.ElicitationResult) {
+	results := make(chan *mcp.ElicitationResult, 1)
+	go func() {
+		req := mcp.ElicitationRequest{}
+		req.Params.Message = "Who should we contact?"
+		req.Params.RequestedSchema = contactSchema
+		result, err := svc.elicitationHandler("crm").Elicit(context.Background(), req)
+		if err != nil {
+			t.Errorf("Elicit: %v", err)
+		}
+		results <- result
+	}()
+
+	select {
+	case ev := <-svc.EventChan:
+		if ev.Type != EventElicitation {
+			t.Fatalf("Expected %s, got %s", EventElicitation, ev.Type)
+		}
+		data := ev.Data.(map[string]any)
+		if data["server"] != "crm" || data["message"] != "Who should we contact?" {
+			t.Fatalf("Unexpected elicitation payload: %v", data)
+		}
+		return data["token"].(string), results
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected an elicitation event")
+	}
+	return "", nil
+}
+
+func TestRespondElicitationAccept(t *testing.T) {
+	svc := newTestService()
+	token, results := startElicitation(t, svc)
+
+	if err := svc.RespondElicitation(token, "accept", map[string]any{"name": "Ada"}); err == nil {
+		t.Fatal("Expected invalid content to be rejected")
+	}
+
+	content := map[string]any{"name": "Ada", "email": "ada@example.com"}
+	if err := svc.RespondElicitation(token, "accept", content); err != nil {
+		t.Fatalf("RespondElicitation: %v", err)
+	}
+
+	result := <-results
+	if result.Action != mcp.ElicitationResponseActionAccept {
+		t.Errorf("Expected accept, got %s", result.Action)
+	}
+	if got := result.Content.(map[string]any)["email"]; got != "ada@example.com" {
+		t.Errorf("Expected email to be passed through, got %v", got)
+	}
+
+	if err := svc.RespondElicitation(token, "accept", content); err == nil {
+		t.Error("Expected an error when answering twice")
+	}
+}
+
+func TestRespondElicitationDecline(t *testing.T) {
+	svc := newTestService()
+	token, results := startElicitation(t, svc)
+
+	if err := svc.RespondElicitation(token, "maybe", nil); err == nil {
+		t.Fatal("Expected an unknown action to be rejected")
+	}
+	if err := svc.RespondElicitation(token, "decline", nil); err != nil {
+		t.Fatalf("RespondElicitation: %v", err)
+	}
+	if result := <-results; result.Action != mcp.ElicitationResponseActionDecline {
+		t.Errorf("Expected decline, got %s", result.Action)
+	}
+}
@@ -0,0 +1,216 @@
+package mcphost
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"smart-spotlight-ai/backend/packages/llm/capabilities"
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers"
+)
+
+// chainLink is one provider of a fallback chain
+type chainLink struct {
+	name         string // profile name reported with answers
+	providerName string // provider type and model look up capabilities
+	model        string
+	maxTokens    int  // answer budget the profile asks for, 0 for the default
+	numCtx       int  // context window an ollama profile loads the model with
+	caching      bool // the provider caches the prompt prefix
+	provider     models.Provider
+}
+
+func newChainLink(config LLMProvider, provider models.Provider) chainLink {
+	return chainLink{
+		name:         config.Name,
+		providerName: config.ProviderName,
+		model:        config.ModelName,
+		maxTokens:    config.Params.MaxTokens,
+		numCtx:       config.Ollama.NumCtx,
+		caching:      config.ProviderName == providers.Anthropic && config.Anthropic.PromptCaching,
+		provider:     provider,
+	}
+}
+
+// promptRequest is the data of an EventPrompt
+type promptRequest struct {
+	Query string
+	Route *LLMProvider // answers just this prompt; nil uses the configured provider
+}
+
+// buildChain creates the provider for config followed by its fallbacks. A
+// fallback that cannot be created is skipped so the primary still works.
+func buildChain(ctx context.Context, config LLMProvider, systemPrompt string, logger *slog.Logger) ([]chainLink, error) {
+	if err := validateProvider(config); err != nil {
+		return nil, err
+	}
+	provider, err := createProvider(ctx, config, systemPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("error creating provider: %w", err)
+	}
+	chain := []chainLink{newChainLink(config, provider)}
+
+	for _, fallback := range config.Fallbacks {
+		if err := validateProvider(fallback); err != nil {
+			logger.Warn("skipping fallback provider", "profile", fallback.Name, "error", err)
+			continue
+		}
+		provider, err := createProvider(ctx, fallback, systemPrompt)
+		if err != nil {
+			logger.Warn("skipping fallback provider", "profile", fallback.Name, "error", err)
+			continue
+		}
+		chain = append(chain, newChainLink(fallback, provider))
+	}
+	return chain, nil
+}
+
+// defaultChain returns the configured provider followed by its fallbacks
+func (s *MCPService) defaultChain() []chainLink {
+	s.providerMu.RLock()
+	defer s.providerMu.RUnlock()
+	chain := []chainLink{newChainLink(s.settings.Provider, s.provider)}
+	return append(chain, s.fallbacks...)
+}
+
+// turnChain returns the providers answering the current prompt
+func (s *MCPService) turnChain() []chainLink {
+	if len(s.turn) > 0 {
+		return s.turn
+	}
+	return s.defaultChain()
+}
+
+// capabilities returns what is known about the model of link. A model
+// loaded with a smaller num_ctx than it supports only sees that much.
+func (s *MCPService) capabilities(link chainLink) capabilities.Capabilities {
+	var caps capabilities.Capabilities
+	if s.settings.Capabilities != nil {
+		caps = s.settings.Capabilities.Lookup(link.providerName, link.model)
+	}
+	if link.numCtx > 0 {
+		caps.ContextWindow = link.numCtx
+	}
+	return caps
+}
+
+// createMessage asks the providers of the current prompt in order until one
+// answers. Only failures another provider might not hit move on to the next;
+// the provider that answered keeps the rest of the prompt so a tool cycle
+// does not retry one that already failed.
+func (s *MCPService) createMessage(
+	ctx context.Context,
+	prompt string,
+	messages []history.HistoryMessage,
+	tools []models.Tool,
+) (models.Message, string, error) {
+	chain := s.turnChain()
+
+	var err error
+	for i, link := range chain {
+		var msg models.Message
+		msg, err = s.askLink(ctx, link, prompt, messages, tools)
+		if err == nil {
+			s.recordUsage(link, msg)
+			s.turn = chain[i:]
+			return msg, link.name, nil
+		}
+		if !models.ShouldFallback(err) {
+			return nil, "", err
+		}
+		if i < len(chain)-1 {
+			s.logger.Warn("provider failed, trying the next one", "profile", link.name, "error", err)
+		}
+	}
+	return nil, "", err
+}
+
+// askLink sends the conversation to one provider, sized to its context
+// window. Models without tool support get plain chat; one that rejects the
+// tools it was sent is asked again without them, and remembered.
+func (s *MCPService) askLink(
+	ctx context.Context,
+	link chainLink,
+	prompt string,
+	messages []history.HistoryMessage,
+	tools []models.Tool,
+) (models.Message, error) {
+	caps := s.capabilities(link)
+	if !caps.SupportsTools() {
+		tools = nil
+	}
+
+	msg, err := link.provider.CreateMessage(ctx, prompt, s.fitMessages(messages, caps, link, tools), tools)
+	if err == nil || len(tools) == 0 || !rejectsTools(err) {
+		return msg, err
+	}
+
+	s.logger.Warn("model rejected tools, continuing without them", "profile", link.name, "model", link.model, "error", err)
+	if s.settings.Capabilities != nil {
+		s.settings.Capabilities.Learn(link.providerName, link.model, capabilities.Capabilities{Tools: capabilities.Bool(false)})
+	}
+	return link.provider.CreateMessage(ctx, prompt, s.fitMessages(messages, caps, link, nil), nil)
+}
+
+// fitMessages adapts the history to models.Message, dropping the oldest
+// messages that do not fit the model's context window
+func (s *MCPService) fitMessages(
+	messages []history.HistoryMessage,
+	caps capabilities.Capabilities,
+	link chainLink,
+	tools []models.Tool,
+) []models.Message {
+	fitted := fitContext(messages, contextBudget(caps, link.maxTokens, tools))
+	if len(fitted) < len(messages) {
+		s.logger.Debug("conversation trimmed to the context window",
+			"model", link.model, "context_window", caps.ContextWindow,
+			"kept", len(fitted), "of", len(messages))
+	}
+	llmMsgs := make([]models.Message, len(fitted))
+	for i := range fitted {
+		llmMsgs[i] = &fitted[i]
+	}
+	return llmMsgs
+}
+
+// recordUsage adds the tokens a request took, and how much of the prompt
+// came from the provider's cache, to the prompt's usage and logs them
+func (s *MCPService) recordUsage(link chainLink, msg models.Message) {
+	input, output := msg.GetUsage()
+	s.usage.InputTokens += input
+	s.usage.OutputTokens += output
+	attrs := []any{"profile", link.name, "model", link.model, "input_tokens", input, "output_tokens", output}
+	if cached, ok := msg.(models.CacheUsage); ok {
+		read, write := cached.GetCacheUsage()
+		s.usage.CacheReadTokens += read
+		s.usage.CacheWriteTokens += write
+		attrs = append(attrs, "cache_read_tokens", read, "cache_write_tokens", write)
+	}
+	s.logger.Debug("token usage", attrs...)
+}
+
+// toolRejections are how providers word refusing tools for a model
+var toolRejections = []string{
+	"does not support tools",
+	"tools are not supported",
+	"tool use is not supported",
+	"does not support function calling",
+}
+
+// rejectsTools reports whether err is a provider refusing the tools it was sent
+func rejectsTools(err error) bool {
+	providerErr, ok := models.AsProviderError(err)
+	if !ok || providerErr.Kind != models.ErrorInvalidRequest {
+		return false
+	}
+	message := strings.ToLower(providerErr.Error())
+	for _, phrase := range toolRejections {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
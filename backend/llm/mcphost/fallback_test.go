@@ -0,0 +1,128 @@
+package mcphost
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers/anthropic"
+)
+
+// failingProvider fails every request with err
+type failingProvider struct {
+	scriptedProvider
+	err   error
+	calls int
+}
+
+func (p *failingProvider) CreateMessage(ctx context.Context, prompt string, messages []models.Message, tools []models.Tool) (models.Message, error) {
+	p.calls++
+	return nil, p.err
+}
+
+func answer(text string) *history.HistoryMessage {
+	return &history.HistoryMessage{
+		Role:    "assistant",
+		Content: []history.ContentBlock{{Type: "text", Text: text}},
+	}
+}
+
+func TestFallbackAnswersWhenPrimaryIsUnavailable(t *testing.T) {
+	svc := newTestService()
+	svc.settings = &MCPSettings{Provider: LLMProvider{Name: "claude"}}
+	primary := &failingProvider{err: models.NewStatusError("anthropic", 529, "overloaded")}
+	svc.provider = primary
+	svc.fallbacks = []chainLink{{name: "local", provider: &scriptedProvider{replies: []*history.HistoryMessage{
+		{
+			Role: "assistant",
+			Content: []history.ContentBlock{{
+				Type: "tool_use", ID: "call-1", Name: searchToolsName,
+				Input: []byte(`{"query": "anything"}`),
+			}},
+		},
+		answer("Answered locally."),
+	}}}}
+
+	var messages []history.HistoryMessage
+	if err := svc.runLLMWithToolCycle(context.Background(), "hello", &messages); err != nil {
+		t.Fatalf("runLLMWithToolCycle: %v", err)
+	}
+
+	ev := <-svc.EventChan
+	if ev.Type != EventFinalResult || ev.Provider != "local" {
+		t.Errorf("Expected a final result from local, got %s from %q: %v", ev.Type, ev.Provider, ev.Data)
+	}
+	if primary.calls != 1 {
+		t.Errorf("Expected the failed provider to be skipped for the rest of the prompt, got %d calls", primary.calls)
+	}
+}
+
+func TestFallbackSkippedForInvalidRequests(t *testing.T) {
+	svc := newTestService()
+	svc.settings = &MCPSettings{}
+	svc.provider = &failingProvider{err: models.NewStatusError("openai", 400, "unknown model")}
+	backup := &scriptedProvider{replies: []*history.HistoryMessage{answer("unused")}}
+	svc.fallbacks = []chainLink{{name: "backup", provider: backup}}
+
+	var messages []history.HistoryMessage
+	if err := svc.runLLMWithToolCycle(context.Background(), "hello", &messages); err != nil {
+		t.Fatalf("runLLMWithToolCycle: %v", err)
+	}
+
+	ev := <-svc.EventChan
+	if ev.Type != EventError {
+		t.Errorf("Expected %s, got %s: %v", EventError, ev.Type, ev.Data)
+	}
+	if len(backup.tools) != 0 {
+		t.Errorf("Expected a request the provider rejected not to be retried elsewhere")
+	}
+}
+
+func TestFinalResultReportsPromptUsage(t *testing.T) {
+	replies := []string{
+		fmt.Sprintf(`{"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_1", "name": %q, "input": {"query": "notes"}}],
+			"usage": {"input_tokens": 12, "output_tokens": 3, "cache_creation_input_tokens": 40, "cache_read_input_tokens": 2100}}`, searchToolsName),
+		`{"role": "assistant", "content": [{"type": "text", "text": "Done."}],
+			"usage": {"input_tokens": 20, "output_tokens": 5, "cache_read_input_tokens": 2140}}`,
+	}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, replies[calls%len(replies)])
+		calls++
+	}))
+	defer server.Close()
+
+	config := LLMProvider{Name: "claude", ProviderName: "anthropic", APIKey: "sk-ant", ModelName: "claude-sonnet-4-5",
+		BaseURL: server.URL, Anthropic: anthropic.Options{PromptCaching: true}}
+	svc := newTestService()
+	svc.settings = &MCPSettings{Provider: config}
+	chain, err := buildChain(context.Background(), config, "", svc.logger)
+	if err != nil {
+		t.Fatalf("buildChain: %v", err)
+	}
+	svc.provider = chain[0].provider
+	svc.InputChan = make(chan PromptEvent, 1)
+
+	want := Usage{InputTokens: 32, OutputTokens: 8, CacheReadTokens: 4240, CacheWriteTokens: 40}
+	var messages []history.HistoryMessage
+	for turn := 0; turn < 2; turn++ {
+		svc.InputChan <- PromptEvent{Type: EventPrompt, Data: promptRequest{Query: "find my notes"}}
+		if err := svc.RunPromptWithChannels(context.Background(), &messages); err != nil {
+			t.Fatalf("RunPromptWithChannels: %v", err)
+		}
+
+		ev := <-svc.EventChan
+		if ev.Type != EventFinalResult {
+			t.Fatalf("Expected %s, got %s: %v", EventFinalResult, ev.Type, ev.Data)
+		}
+		// the second prompt starts counting anew
+		if ev.Usage == nil || *ev.Usage != want {
+			t.Errorf("Expected usage %+v in turn %d, got %+v", want, turn+1, ev.Usage)
+		}
+	}
+}
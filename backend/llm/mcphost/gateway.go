@@ -0,0 +1,486 @@
+package mcphost
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"smart-spotlight-ai/backend/packages/secrets"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// GatewayEndpoint is the path the gateway serves Streamable HTTP on
+	GatewayEndpoint = "/mcp"
+
+	gatewayName           = "smart-spotlight"
+	gatewaySyncTimeout    = 30 * time.Second
+	gatewayConfirmTimeout = 120 * time.Second
+
+	methodResourcesListChanged = "notifications/resources/list_changed"
+	methodPromptsListChanged   = "notifications/prompts/list_changed"
+)
+
+// GatewayOptions are what the gateway's servers launch with and where its
+// confirmation requests go. They come from the app rather than the prompt
+// loop, so the gateway runs without a provider configured.
+type GatewayOptions struct {
+	ConnectOptions
+	Emit func(PromptEvent) // shows confirmation requests; nil refuses calls that need one
+}
+
+// Gateway exposes a set of MCP servers as a single MCP server, so other
+// clients can reuse the servers, secrets and confirmation rules managed here.
+// Tools and prompts are renamed server__name; resource URIs and templates
+// are prefixed with smart-spotlight://server/, so servers listing the same URI
+// stay apart.
+type Gateway struct {
+	server *server.MCPServer
+
+	mu      sync.Mutex
+	options GatewayOptions
+	clients map[string]mcpclient.MCPClient
+	filters map[string]ToolFilter
+	tools   toolNameRegistry
+	prompts toolNameRegistry
+
+	confirmMu      sync.Mutex
+	pendingConfirm map[string]chan bool // token -> call waiting for the user
+
+	// passthrough keeps upstream names unchanged, for a bridge to a single gateway
+	passthrough bool
+}
+
+// NewGateway creates a gateway whose servers launch with options and whose
+// tool calls go through the confirmation policy of the prompt loop
+func NewGateway(options GatewayOptions, version string) *Gateway {
+	return &Gateway{
+		options: options,
+		server: server.NewMCPServer(gatewayName, version,
+			server.WithToolCapabilities(true),
+			server.WithResourceCapabilities(false, true),
+			server.WithPromptCapabilities(true),
+			server.WithRecovery()),
+	}
+}
+
+// Connect replaces the gateway's servers with those in config. A server that
+// fails to start is logged and left out so the others stay reachable.
+func (g *Gateway) Connect(config *MCPConfig) error {
+	g.mu.Lock()
+	options := g.options
+	g.mu.Unlock()
+	hooks := options.hooks()
+
+	clients := make(map[string]mcpclient.MCPClient)
+	filters := make(map[string]ToolFilter)
+	for name, server := range config.MCPServers {
+		server, _, err := prepareServer(server, hooks)
+		if err != nil {
+			slog.Error("gateway skipping server that cannot be launched", "name", name, "error", err)
+			continue
+		}
+		client, err := createMCPClient(name, server, hooks)
+		if err != nil {
+			slog.Error("gateway failed to connect to server", "name", name, "error", err)
+			continue
+		}
+		clients[name] = client
+		filters[name] = server.Config.GetToolFilter()
+	}
+
+	g.setClients(clients, filters)
+	return g.sync()
+}
+
+// SetSecrets replaces the lookup used by the next Connect, e.g. once the
+// secret store is unlocked
+func (g *Gateway) SetSecrets(lookup secrets.Lookup) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.options.Secrets = lookup
+}
+
+// setClients swaps in new upstream clients and closes the old ones
+func (g *Gateway) setClients(clients map[string]mcpclient.MCPClient, filters map[string]ToolFilter) {
+	g.mu.Lock()
+	old := g.clients
+	g.clients, g.filters = clients, filters
+	g.mu.Unlock()
+
+	for _, client := range old {
+		client.Close()
+	}
+	for name, client := range clients {
+		g.watch(name, client)
+	}
+}
+
+// watch re-syncs the gateway when a server's catalog changes
+func (g *Gateway) watch(name string, client mcpclient.MCPClient) {
+	client.OnNotification(func(n mcp.JSONRPCNotification) {
+		switch n.Method {
+		case methodToolsListChanged, methodResourcesListChanged, methodPromptsListChanged:
+			// re-listing must not run on the goroutine delivering the notification
+			go func() {
+				if err := g.sync(); err != nil {
+					slog.Error("gateway failed to refresh", "server", name, "error", err)
+				}
+			}()
+		}
+	})
+}
+
+// Close disconnects from every server
+func (g *Gateway) Close() {
+	g.setClients(nil, nil)
+}
+
+// Handler serves the gateway over Streamable HTTP at GatewayEndpoint. Every
+// request must carry token as a bearer token and name a loopback host in Host
+// and Origin, so web pages cannot reach it through DNS rebinding.
+func (g *Gateway) Handler(token string) (http.Handler, error) {
+	if token == "" {
+		return nil, errors.New("the gateway needs a token")
+	}
+	handler := server.NewStreamableHTTPServer(g.server, server.WithEndpointPath(GatewayEndpoint))
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopbackHost(r.Host) {
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || !isLoopbackHost(u.Host) {
+				http.Error(w, "forbidden origin", http.StatusForbidden)
+				return
+			}
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}), nil
+}
+
+// isLoopbackHost reports whether a host[:port] names this machine
+func isLoopbackHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ServeStdio serves the gateway on in and out until ctx is done or in closes
+func (g *Gateway) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	return server.NewStdioServer(g.server).Listen(ctx, in, out)
+}
+
+// sync rebuilds the gateway's catalog from its servers
+func (g *Gateway) sync() error {
+	g.mu.Lock()
+	clients := make(map[string]mcpclient.MCPClient, len(g.clients))
+	for name, client := range g.clients {
+		clients[name] = client
+	}
+	g.mu.Unlock()
+
+	names := make([]string, 0, len(clients))
+	for name := range clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatewaySyncTimeout)
+	defer cancel()
+
+	var tools []server.ServerTool
+	var prompts []server.ServerPrompt
+	var resources []server.ServerResource
+	var templates []server.ServerResourceTemplate
+	seenURIs := map[string]bool{}
+	seenTemplates := map[string]bool{}
+
+	for _, name := range names {
+		client := clients[name]
+
+		// servers without tools, prompts or resources answer with an error
+		if serverTools, err := listAllTools(ctx, client); err == nil {
+			filter := g.toolFilter(name)
+			for _, tool := range serverTools {
+				if filter.Allows(tool.Name) {
+					tools = append(tools, g.proxyTool(name, client, tool))
+				}
+			}
+		} else {
+			slog.Warn("gateway cannot list tools of server", "name", name, "error", err)
+		}
+		if serverPrompts, err := listAllPrompts(ctx, client); err == nil {
+			for _, prompt := range serverPrompts {
+				prompts = append(prompts, g.proxyPrompt(name, client, prompt))
+			}
+		}
+		if serverResources, err := listAllResources(ctx, client); err == nil {
+			for _, resource := range serverResources {
+				proxy := g.proxyResource(name, client, resource)
+				if seenURIs[proxy.Resource.URI] {
+					slog.Warn("gateway hiding duplicate resource", "server", name, "uri", resource.URI)
+					continue
+				}
+				seenURIs[proxy.Resource.URI] = true
+				resources = append(resources, proxy)
+			}
+		}
+		if serverTemplates, err := listAllResourceTemplates(ctx, client); err == nil {
+			for _, template := range serverTemplates {
+				if template.URITemplate == nil {
+					continue
+				}
+				proxy, err := g.proxyResourceTemplate(name, client, template)
+				if err != nil {
+					slog.Warn("gateway hiding resource template", "server", name, "template", template.URITemplate.Raw(), "error", err)
+					continue
+				}
+				raw := proxy.Template.URITemplate.Raw()
+				if seenTemplates[raw] {
+					slog.Warn("gateway hiding duplicate resource template", "server", name, "template", raw)
+					continue
+				}
+				seenTemplates[raw] = true
+				templates = append(templates, proxy)
+			}
+		}
+	}
+
+	g.server.SetTools(tools...)
+	g.server.SetPrompts(prompts...)
+	g.server.SetResources(resources...)
+	g.server.SetResourceTemplates(templates...)
+	return nil
+}
+
+func (g *Gateway) toolFilter(server string) ToolFilter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.filters[server]
+}
+
+// proxyTool forwards calls of a server's tool after the usual confirmation
+func (g *Gateway) proxyTool(name string, client mcpclient.MCPClient, tool mcp.Tool) server.ServerTool {
+	original := tool.Name
+	if !g.passthrough {
+		tool.Name = g.tools.name(name, original)
+	}
+	return server.ServerTool{
+		Tool: tool,
+		Handler: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			// a bridge forwards to a gateway that asks itself
+			if !g.passthrough {
+				if err := g.confirmCall(ctx, name, original, req.GetArguments()); err != nil {
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+			}
+			req.Params.Name = original
+			return client.CallTool(ctx, req)
+		},
+	}
+}
+
+// proxyPrompt forwards a server's prompt under its gateway name
+func (g *Gateway) proxyPrompt(name string, client mcpclient.MCPClient, prompt mcp.Prompt) server.ServerPrompt {
+	original := prompt.Name
+	if !g.passthrough {
+		prompt.Name = g.prompts.name(name, original)
+	}
+	return server.ServerPrompt{
+		Prompt: prompt,
+		Handler: func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			req.Params.Name = original
+			return client.GetPrompt(ctx, req)
+		},
+	}
+}
+
+// resourcePrefix is what the gateway puts before the URIs of a server's resources
+func (g *Gateway) resourcePrefix(name string) string {
+	if g.passthrough {
+		return ""
+	}
+	return gatewayName + "://" + url.PathEscape(name) + "/"
+}
+
+// proxyResource forwards reads of a server's resource under its gateway URI
+func (g *Gateway) proxyResource(name string, client mcpclient.MCPClient, resource mcp.Resource) server.ServerResource {
+	prefix := g.resourcePrefix(name)
+	resource.URI = prefix + resource.URI
+	return server.ServerResource{
+		Resource: resource,
+		Handler: func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return readResource(ctx, client, prefix, req)
+		},
+	}
+}
+
+// proxyResourceTemplate forwards reads of URIs matching a server's template
+// under its gateway URI
+func (g *Gateway) proxyResourceTemplate(name string, client mcpclient.MCPClient, template mcp.ResourceTemplate) (server.ServerResourceTemplate, error) {
+	prefix := g.resourcePrefix(name)
+	if prefix != "" {
+		raw, err := json.Marshal(prefix + template.URITemplate.Raw())
+		if err != nil {
+			return server.ServerResourceTemplate{}, err
+		}
+		prefixed := &mcp.URITemplate{}
+		if err := json.Unmarshal(raw, prefixed); err != nil {
+			return server.ServerResourceTemplate{}, err
+		}
+		template.URITemplate = prefixed
+	}
+	return server.ServerResourceTemplate{
+		Template: template,
+		Handler: func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return readResource(ctx, client, prefix, req)
+		},
+	}, nil
+}
+
+// readResource reads the upstream resource behind a gateway URI and names
+// the contents by gateway URI again
+func readResource(ctx context.Context, client mcpclient.MCPClient, prefix string, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	req.Params.URI = strings.TrimPrefix(req.Params.URI, prefix)
+	result, err := client.ReadResource(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for i, content := range result.Contents {
+		switch c := content.(type) {
+		case mcp.TextResourceContents:
+			c.URI = prefix + c.URI
+			result.Contents[i] = c
+		case mcp.BlobResourceContents:
+			c.URI = prefix + c.URI
+			result.Contents[i] = c
+		}
+	}
+	return result.Contents, nil
+}
+
+// confirmCall applies the confirmation policy of the prompt loop to a call
+// made through the gateway and waits for the user's answer in the UI
+func (g *Gateway) confirmCall(ctx context.Context, server, tool string, args map[string]any) error {
+	need, token := confirmationRequired(server, tool, args)
+	if !need {
+		return nil
+	}
+	if g.options.Emit == nil {
+		return errors.New("the call needs a confirmation but no window can ask for it")
+	}
+
+	reply := make(chan bool, 1)
+	g.confirmMu.Lock()
+	if g.pendingConfirm == nil {
+		g.pendingConfirm = make(map[string]chan bool)
+	}
+	g.pendingConfirm[token] = reply
+	g.confirmMu.Unlock()
+	defer func() {
+		g.confirmMu.Lock()
+		delete(g.pendingConfirm, token)
+		g.confirmMu.Unlock()
+	}()
+
+	argJSON, _ := json.MarshalIndent(args, "", "  ")
+	event := PromptEvent{
+		Type: EventConfirmationRequired,
+		Data: map[string]any{
+			"token":  token,
+			"server": server,
+			"tool":   tool,
+			"args":   string(argJSON),
+			"source": "gateway",
+		},
+	}
+	g.options.Emit(event)
+
+	select {
+	case ok := <-reply:
+		if !ok {
+			return errors.New("operation aborted by user")
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(gatewayConfirmTimeout):
+		return errors.New("confirmation timeout")
+	}
+}
+
+// Confirm delivers the user's answer to a waiting call and reports whether
+// token belonged to one
+func (g *Gateway) Confirm(token string, ok bool) bool {
+	g.confirmMu.Lock()
+	defer g.confirmMu.Unlock()
+
+	reply, found := g.pendingConfirm[token]
+	if found {
+		reply <- ok
+		delete(g.pendingConfirm, token)
+	}
+	return found
+}
+
+// ServeGatewayBridge serves the gateway of a running instance, reached at url,
+// on in and out. Clients that can only launch stdio servers use it so that
+// confirmations still show up in the running app.
+func ServeGatewayBridge(ctx context.Context, url, token, version string, in io.Reader, out io.Writer) error {
+	options := []transport.StreamableHTTPCOption{transport.WithContinuousListening()}
+	if token != "" {
+		options = append(options, transport.WithHTTPHeaders(map[string]string{"Authorization": "Bearer " + token}))
+	}
+	client, err := mcpclient.NewStreamableHttpClient(url, options...)
+	if err != nil {
+		return fmt.Errorf("failed to create gateway client: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.Start(ctx); err != nil {
+		return fmt.Errorf("failed to reach the gateway at %s: %w", url, err)
+	}
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initReq.Params.ClientInfo = mcp.Implementation{Name: gatewayName + "-bridge", Version: version}
+	if _, err := client.Initialize(ctx, initReq); err != nil {
+		return fmt.Errorf("failed to initialize with the gateway at %s: %w", url, err)
+	}
+
+	g := NewGateway(GatewayOptions{}, version)
+	g.passthrough = true
+	g.setClients(map[string]mcpclient.MCPClient{gatewayName: client}, nil)
+	if err := g.sync(); err != nil {
+		return err
+	}
+	return g.ServeStdio(ctx, in, out)
+}
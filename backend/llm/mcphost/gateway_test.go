@@ -0,0 +1,305 @@
+package mcphost
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newGateway serves a gateway over notes (see newInspectableSSEServer) and
+// returns a client connected to it
+func newGateway(t *testing.T, token string) *mcpclient.Client {
+	upstream := newInspectableSSEServer(t)
+	return serveGateway(t, token, map[string]ServerConfigWrapper{
+		"notes": {Config: SSEServerConfig{Url: upstream.URL + "/sse"}},
+	})
+}
+
+// serveGateway serves a gateway over servers and returns a client connected to it
+func serveGateway(t *testing.T, token string, servers map[string]ServerConfigWrapper) *mcpclient.Client {
+	gateway := NewGateway(GatewayOptions{}, "test")
+	err := gateway.Connect(&MCPConfig{MCPServers: servers})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(gateway.Close)
+
+	handler, err := gateway.Handler(token)
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	client, err := mcpclient.NewStreamableHttpClient(ts.URL+GatewayEndpoint,
+		transport.WithHTTPHeaders(map[string]string{"Authorization": "Bearer " + token}))
+	if err != nil {
+		t.Fatalf("NewStreamableHttpClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func initializeClient(ctx context.Context, client *mcpclient.Client) error {
+	if err := client.Start(ctx); err != nil {
+		return err
+	}
+	req := mcp.InitializeRequest{}
+	req.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	_, err := client.Initialize(ctx, req)
+	return err
+}
+
+func TestGatewayProxiesNamespacedCatalog(t *testing.T) {
+	client := newGateway(t, "secret")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := initializeClient(ctx, client); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	tools, err := client.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools.Tools) != 1 || tools.Tools[0].Name != "notes__search_notes" {
+		t.Fatalf("Expected notes__search_notes, got %+v", tools.Tools)
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "notes__search_notes"
+	req.Params.Arguments = map[string]any{"query": "groceries"}
+	result, err := client.CallTool(ctx, req)
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); !ok || text.Text != "found: groceries" {
+		t.Errorf("Expected the upstream result, got %+v", result.Content)
+	}
+
+	prompts, err := client.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		t.Fatalf("ListPrompts: %v", err)
+	}
+	if len(prompts.Prompts) != 1 || prompts.Prompts[0].Name != "notes__summarize" {
+		t.Errorf("Expected notes__summarize, got %+v", prompts.Prompts)
+	}
+
+	resources, err := client.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		t.Fatalf("ListResources: %v", err)
+	}
+	if len(resources.Resources) != 1 || resources.Resources[0].URI != "smart-spotlight://notes/notes://index" {
+		t.Errorf("Expected smart-spotlight://notes/notes://index, got %+v", resources.Resources)
+	}
+}
+
+// newPagedNotesServer serves two notes, a note template and two prompts, one
+// per page, answering reads with its name
+func newPagedNotesServer(t *testing.T, name string) *httptest.Server {
+	mcpServer := server.NewMCPServer(name, "1.0.0",
+		server.WithResourceCapabilities(false, false),
+		server.WithPromptCapabilities(false),
+		server.WithPaginationLimit(1))
+	read := func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: req.Params.URI, Text: name + ": " + req.Params.URI}}, nil
+	}
+	mcpServer.AddResource(mcp.NewResource("notes://index", "index"), read)
+	mcpServer.AddResource(mcp.NewResource("notes://archive", "archive"), read)
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate("notes://{id}", "note"), read)
+	for _, prompt := range []string{"summarize", "outline"} {
+		mcpServer.AddPrompt(mcp.NewPrompt(prompt),
+			func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+				return nil, nil
+			})
+	}
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	mux.Handle("/", server.NewSSEServer(mcpServer, server.WithBaseURL(ts.URL)))
+	t.Cleanup(func() {
+		ts.CloseClientConnections()
+		ts.Close()
+	})
+	return ts
+}
+
+func TestGatewayKeepsResourcesOfServersApart(t *testing.T) {
+	work, home := newPagedNotesServer(t, "work"), newPagedNotesServer(t, "home")
+	client := serveGateway(t, "secret", map[string]ServerConfigWrapper{
+		"work": {Config: SSEServerConfig{Url: work.URL + "/sse"}},
+		"home": {Config: SSEServerConfig{Url: home.URL + "/sse"}},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := initializeClient(ctx, client); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	resources, err := client.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		t.Fatalf("ListResources: %v", err)
+	}
+	if len(resources.Resources) != 4 {
+		t.Errorf("Expected every page of both servers, got %+v", resources.Resources)
+	}
+	templates, err := client.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+	if err != nil {
+		t.Fatalf("ListResourceTemplates: %v", err)
+	}
+	if len(templates.ResourceTemplates) != 2 {
+		t.Errorf("Expected a template per server, got %+v", templates.ResourceTemplates)
+	}
+	prompts, err := client.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		t.Fatalf("ListPrompts: %v", err)
+	}
+	if len(prompts.Prompts) != 4 {
+		t.Errorf("Expected every page of both servers, got %+v", prompts.Prompts)
+	}
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{uri: "smart-spotlight://home/notes://index", want: "home: notes://index"},
+		{uri: "smart-spotlight://work/notes://index", want: "work: notes://index"},
+		{uri: "smart-spotlight://work/notes://42", want: "work: notes://42"},
+	}
+	for _, tt := range tests {
+		req := mcp.ReadResourceRequest{}
+		req.Params.URI = tt.uri
+		result, err := client.ReadResource(ctx, req)
+		if err != nil {
+			t.Errorf("ReadResource %s: %v", tt.uri, err)
+			continue
+		}
+		text, ok := result.Contents[0].(mcp.TextResourceContents)
+		if !ok || text.Text != tt.want || text.URI != tt.uri {
+			t.Errorf("Expected %q at %s, got %+v", tt.want, tt.uri, result.Contents)
+		}
+	}
+}
+
+func TestGatewayRejectsUnauthorizedRequests(t *testing.T) {
+	gateway := NewGateway(GatewayOptions{}, "test")
+	if _, err := gateway.Handler(""); err == nil {
+		t.Errorf("Expected an empty token to be refused")
+	}
+	handler, err := gateway.Handler("secret")
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		token  string
+		status int
+	}{
+		{name: "wrong token", host: "127.0.0.1:7777", token: "wrong", status: http.StatusUnauthorized},
+		{name: "rebound host", host: "attacker.example:7777", token: "secret", status: http.StatusForbidden},
+		{name: "foreign origin", host: "localhost:7777", origin: "https://attacker.example", token: "secret", status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, GatewayEndpoint, strings.NewReader(`{}`))
+			req.Host = tt.host
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+
+	for _, host := range []string{"localhost:7777", "127.0.0.1", "[::1]:7777"} {
+		if !isLoopbackHost(host) {
+			t.Errorf("Expected %s to be a loopback host", host)
+		}
+	}
+}
+
+func TestGatewayAsksForConfirmation(t *testing.T) {
+	upstream := server.NewMCPServer("files", "1.0.0")
+	upstream.AddTool(mcp.NewTool("delete", mcp.WithString("path")),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("deleted " + req.GetString("path", "")), nil
+		})
+	upstreamClient, err := mcpclient.NewInProcessClient(upstream)
+	if err != nil {
+		t.Fatalf("NewInProcessClient: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := initializeClient(ctx, upstreamClient); err != nil {
+		t.Fatalf("Initialize upstream: %v", err)
+	}
+
+	events := make(chan PromptEvent, 1)
+	gateway := NewGateway(GatewayOptions{Emit: func(ev PromptEvent) { events <- ev }}, "test")
+	gateway.setClients(map[string]mcpclient.MCPClient{"files": upstreamClient}, nil)
+	defer gateway.Close()
+	if err := gateway.sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	client, err := mcpclient.NewInProcessClient(gateway.server)
+	if err != nil {
+		t.Fatalf("NewInProcessClient: %v", err)
+	}
+	defer client.Close()
+	if err := initializeClient(ctx, client); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	call := func(answer bool) *mcp.CallToolResult {
+		done := make(chan *mcp.CallToolResult, 1)
+		go func() {
+			req := mcp.CallToolRequest{}
+			req.Params.Name = "files__delete"
+			req.Params.Arguments = map[string]any{"path": "/tmp/x"}
+			result, err := client.CallTool(ctx, req)
+			if err != nil {
+				t.Errorf("CallTool: %v", err)
+			}
+			done <- result
+		}()
+
+		ev := <-events
+		data, _ := ev.Data.(map[string]any)
+		if ev.Type != EventConfirmationRequired || data["source"] != "gateway" || data["tool"] != "delete" {
+			t.Fatalf("Expected a gateway confirmation request, got %+v", ev)
+		}
+		if !gateway.Confirm(data["token"].(string), answer) {
+			t.Fatalf("Expected the token to belong to a waiting call")
+		}
+		return <-done
+	}
+
+	if result := call(false); result == nil || !result.IsError {
+		t.Errorf("Expected a declined call to fail, got %+v", result)
+	}
+	result := call(true)
+	if result == nil || result.IsError {
+		t.Fatalf("Expected a confirmed call to succeed, got %+v", result)
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); !ok || text.Text != "deleted /tmp/x" {
+		t.Errorf("Expected the upstream result, got %+v", result.Content)
+	}
+}
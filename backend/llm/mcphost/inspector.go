@@ -0,0 +1,274 @@
+package mcphost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"smart-spotlight-ai/backend/packages/oauth"
+	"smart-spotlight-ai/backend/packages/secrets"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	inspectTimeout  = 30 * time.Second
+	manualCallLimit = 2 * time.Minute
+)
+
+// ConnectOptions are what servers launch with besides their own config
+type ConnectOptions struct {
+	Secrets secrets.Lookup // resolves ${secret:...} references; nil while the store is locked
+	BaseEnv []string       // environment stdio servers start from; nil uses ours
+	Auth    *oauth.Manager // OAuth credentials of remote servers; nil sends none
+}
+
+// hooks returns client hooks that only resolve references and authenticate
+func (o ConnectOptions) hooks() *clientHooks {
+	return &clientHooks{
+		secrets: o.Secrets,
+		baseEnv: o.BaseEnv,
+		headers: func(name, url string) map[string]string {
+			return bearerHeaders(o.Auth, slog.Default(), name, url)
+		},
+	}
+}
+
+// ServerInfo is what a server reports about itself when it initializes
+type ServerInfo struct {
+	Name            string                 `json:"name"`
+	Version         string                 `json:"version"`
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    mcp.ServerCapabilities `json:"capabilities"`
+	Instructions    string                 `json:"instructions,omitempty"`
+	Command         string                 `json:"command,omitempty"` // executable a stdio server was resolved to
+}
+
+// ServerResources lists the resources and resource templates of a server
+type ServerResources struct {
+	Resources []mcp.Resource         `json:"resources"`
+	Templates []mcp.ResourceTemplate `json:"templates"`
+}
+
+// TestConnect launches or connects to a server that need not be saved,
+// initializes it and disconnects again. Its stderr lands in the logs under name.
+func (s *MCPService) TestConnect(name string, server ServerConfigWrapper) (*ServerInfo, error) {
+	hooks := s.clientHooks()
+	// a test connection must not touch the state of a running server
+	hooks.status = nil
+	hooks.exited = nil
+	hooks.unauthorized = nil
+
+	return testConnect(name, server, hooks)
+}
+
+// serverInfoFromResult keeps the parts of an initialize result we show and reuse
+func serverInfoFromResult(result *mcp.InitializeResult) ServerInfo {
+	return ServerInfo{
+		Name:            result.ServerInfo.Name,
+		Version:         result.ServerInfo.Version,
+		ProtocolVersion: result.ProtocolVersion,
+		Capabilities:    result.Capabilities,
+		Instructions:    result.Instructions,
+	}
+}
+
+// InspectTools lists every tool of a connected server with its input
+// schema, including tools hidden by the server's tool filter
+func (s *MCPService) InspectTools(server string) ([]mcp.Tool, error) {
+	client, ok := s.getClient(server)
+	if !ok {
+		return nil, fmt.Errorf("server %s is not connected", server)
+	}
+	return inspectTools(client)
+}
+
+// InspectResources lists the resources and resource templates of a connected server
+func (s *MCPService) InspectResources(server string) (*ServerResources, error) {
+	client, ok := s.getClient(server)
+	if !ok {
+		return nil, fmt.Errorf("server %s is not connected", server)
+	}
+	return inspectResources(server, client)
+}
+
+// InspectPrompts lists the prompts of a connected server with their arguments
+func (s *MCPService) InspectPrompts(server string) ([]mcp.Prompt, error) {
+	client, ok := s.getClient(server)
+	if !ok {
+		return nil, fmt.Errorf("server %s is not connected", server)
+	}
+	return inspectPrompts(server, client)
+}
+
+// CallToolManually invokes a tool the way the user typed it, bypassing the
+// model and confirmations, and returns the raw result. Tools the server's
+// filter leaves out stay out of reach here too. arguments is a JSON object;
+// empty means no arguments.
+func (s *MCPService) CallToolManually(server, tool, arguments string) (*mcp.CallToolResult, error) {
+	client, ok := s.getClient(server)
+	if !ok {
+		return nil, fmt.Errorf("server %s is not connected", server)
+	}
+	req, err := manualCall(server, s.toolFilter(server), tool, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), manualCallLimit)
+	defer cancel()
+	result, err := client.CallTool(ctx, req)
+	if err != nil {
+		if exitErr := s.serverExit(server); exitErr != nil {
+			return nil, exitErr
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// Inspector tests and inspects servers straight from their configs. The
+// MCPService only exists once an LLM provider is configured, which neither
+// needs; each call connects to the server and disconnects again.
+type Inspector struct {
+	options ConnectOptions
+}
+
+// NewInspector creates an inspector whose servers launch with options
+func NewInspector(options ConnectOptions) *Inspector {
+	return &Inspector{options: options}
+}
+
+// TestConnect launches or connects to a server, initializes it and
+// disconnects again
+func (i *Inspector) TestConnect(name string, server ServerConfigWrapper) (*ServerInfo, error) {
+	return testConnect(name, server, i.options.hooks())
+}
+
+// InspectTools lists every tool of a server, see MCPService.InspectTools
+func (i *Inspector) InspectTools(name string, server ServerConfigWrapper) ([]mcp.Tool, error) {
+	client, err := i.connect(name, server)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return inspectTools(client)
+}
+
+// InspectResources lists the resources and resource templates of a server
+func (i *Inspector) InspectResources(name string, server ServerConfigWrapper) (*ServerResources, error) {
+	client, err := i.connect(name, server)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return inspectResources(name, client)
+}
+
+// InspectPrompts lists the prompts of a server with their arguments
+func (i *Inspector) InspectPrompts(name string, server ServerConfigWrapper) ([]mcp.Prompt, error) {
+	client, err := i.connect(name, server)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return inspectPrompts(name, client)
+}
+
+// CallToolManually invokes a tool of a server, see MCPService.CallToolManually
+func (i *Inspector) CallToolManually(name string, server ServerConfigWrapper, tool, arguments string) (*mcp.CallToolResult, error) {
+	req, err := manualCall(name, server.Config.GetToolFilter(), tool, arguments)
+	if err != nil {
+		return nil, err
+	}
+	client, err := i.connect(name, server)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), manualCallLimit)
+	defer cancel()
+	return client.CallTool(ctx, req)
+}
+
+func (i *Inspector) connect(name string, server ServerConfigWrapper) (mcpclient.MCPClient, error) {
+	hooks := i.options.hooks()
+	server, _, err := prepareServer(server, hooks)
+	if err != nil {
+		return nil, err
+	}
+	return createMCPClient(name, server, hooks)
+}
+
+// testConnect connects to a server with hooks, initializes it and
+// disconnects again
+func testConnect(name string, server ServerConfigWrapper, hooks *clientHooks) (*ServerInfo, error) {
+	server, command, err := prepareServer(server, hooks)
+	if err != nil {
+		return nil, err
+	}
+
+	client, result, err := connectMCPClient(name, server, hooks)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	info := serverInfoFromResult(result)
+	info.Command = command
+	return &info, nil
+}
+
+func inspectTools(client mcpclient.MCPClient) ([]mcp.Tool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+	return listAllTools(ctx, client)
+}
+
+func inspectResources(server string, client mcpclient.MCPClient) (*ServerResources, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+
+	resources, err := listAllResources(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources of %s: %w", server, err)
+	}
+	templates, err := listAllResourceTemplates(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource templates of %s: %w", server, err)
+	}
+	return &ServerResources{Resources: resources, Templates: templates}, nil
+}
+
+func inspectPrompts(server string, client mcpclient.MCPClient) ([]mcp.Prompt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+
+	prompts, err := listAllPrompts(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompts of %s: %w", server, err)
+	}
+	return prompts, nil
+}
+
+// manualCall builds the request for a tool the user calls by hand
+func manualCall(server string, filter ToolFilter, tool, arguments string) (mcp.CallToolRequest, error) {
+	req := mcp.CallToolRequest{}
+	if !filter.Allows(tool) {
+		return req, fmt.Errorf("tool %s of %s is excluded by the server's tool filter", tool, server)
+	}
+
+	args := map[string]interface{}{}
+	if arguments != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return req, fmt.Errorf("arguments must be a JSON object: %w", err)
+		}
+	}
+	req.Params.Name = tool
+	req.Params.Arguments = args
+	return req, nil
+}
@@ -0,0 +1,157 @@
+package mcphost
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newInspectableSSEServer serves an MCP server with a tool, a resource, a
+// resource template and a prompt
+func newInspectableSSEServer(t *testing.T) *httptest.Server {
+	mcpServer := server.NewMCPServer("notes", "2.1.0",
+		server.WithInstructions("Search notes before creating new ones."),
+		server.WithResourceCapabilities(false, false),
+		server.WithPromptCapabilities(false))
+	mcpServer.AddTool(mcp.NewTool("search_notes", mcp.WithString("query", mcp.Required())),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("found: " + req.GetString("query", "")), nil
+		})
+	mcpServer.AddResource(mcp.NewResource("notes://index", "index"),
+		func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return nil, nil
+		})
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate("notes://{id}", "note"),
+		func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return nil, nil
+		})
+	mcpServer.AddPrompt(mcp.NewPrompt("summarize", mcp.WithArgument("topic")),
+		func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return nil, nil
+		})
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	mux.Handle("/", server.NewSSEServer(mcpServer, server.WithBaseURL(ts.URL)))
+	t.Cleanup(func() {
+		// the SSE stream never ends on its own
+		ts.CloseClientConnections()
+		ts.Close()
+	})
+	return ts
+}
+
+func TestInspectorTestConnect(t *testing.T) {
+	ts := newInspectableSSEServer(t)
+	svc := newTestService()
+	svc.settings = &MCPSettings{}
+
+	info, err := svc.TestConnect("notes", ServerConfigWrapper{Config: SSEServerConfig{Url: ts.URL + "/sse"}})
+	if err != nil {
+		t.Fatalf("TestConnect: %v", err)
+	}
+	if info.Name != "notes" || info.Version != "2.1.0" || info.ProtocolVersion == "" {
+		t.Errorf("Unexpected server info: %+v", info)
+	}
+	if info.Instructions != "Search notes before creating new ones." {
+		t.Errorf("Expected the server instructions, got %q", info.Instructions)
+	}
+	if info.Capabilities.Tools == nil || info.Capabilities.Prompts == nil {
+		t.Errorf("Expected tool and prompt capabilities, got %+v", info.Capabilities)
+	}
+
+	if _, ok := svc.getClient("notes"); ok {
+		t.Errorf("Expected a test connection not to register a client")
+	}
+	if statuses := svc.ServerStatuses(); len(statuses) != 0 {
+		t.Errorf("Expected a test connection not to record a status, got %+v", statuses)
+	}
+}
+
+func TestInspectorListsAndCalls(t *testing.T) {
+	ts := newInspectableSSEServer(t)
+	svc := newTestService()
+
+	client, err := createMCPClient("notes", ServerConfigWrapper{Config: SSEServerConfig{Url: ts.URL + "/sse"}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	svc.addClient("notes", client)
+
+	tools, err := svc.InspectTools("notes")
+	if err != nil || len(tools) != 1 || tools[0].InputSchema.Required[0] != "query" {
+		t.Errorf("Expected search_notes with its schema, got %+v (%v)", tools, err)
+	}
+
+	resources, err := svc.InspectResources("notes")
+	if err != nil || len(resources.Resources) != 1 || len(resources.Templates) != 1 {
+		t.Errorf("Expected one resource and one template, got %+v (%v)", resources, err)
+	}
+
+	prompts, err := svc.InspectPrompts("notes")
+	if err != nil || len(prompts) != 1 || prompts[0].Arguments[0].Name != "topic" {
+		t.Errorf("Expected the summarize prompt, got %+v (%v)", prompts, err)
+	}
+
+	result, err := svc.CallToolManually("notes", "search_notes", `{"query": "groceries"}`)
+	if err != nil {
+		t.Fatalf("CallToolManually: %v", err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; text != "found: groceries" {
+		t.Errorf("Expected the raw tool result, got %q", text)
+	}
+
+	if _, err := svc.CallToolManually("notes", "search_notes", `["groceries"]`); err == nil ||
+		!strings.Contains(err.Error(), "JSON object") {
+		t.Errorf("Expected an error for non-object arguments, got %v", err)
+	}
+	svc.setServerFilters(&MCPConfig{MCPServers: map[string]ServerConfigWrapper{
+		"notes": {Config: SSEServerConfig{ToolFilter: ToolFilter{ExcludeTools: []string{"search_*"}}}},
+	}})
+	if _, err := svc.CallToolManually("notes", "search_notes", `{"query": "groceries"}`); err == nil ||
+		!strings.Contains(err.Error(), "excluded") {
+		t.Errorf("Expected an excluded tool to be refused, got %v", err)
+	}
+	if _, err := svc.InspectTools("missing"); err == nil {
+		t.Errorf("Expected an error for an unknown server")
+	}
+}
+
+func TestStandaloneInspector(t *testing.T) {
+	ts := newInspectableSSEServer(t)
+	inspector := NewInspector(ConnectOptions{})
+	server := ServerConfigWrapper{Config: SSEServerConfig{Url: ts.URL + "/sse"}}
+
+	info, err := inspector.TestConnect("notes", server)
+	if err != nil {
+		t.Fatalf("TestConnect: %v", err)
+	}
+	if info.Name != "notes" {
+		t.Errorf("Expected server name notes, got %s", info.Name)
+	}
+
+	tools, err := inspector.InspectTools("notes", server)
+	if err != nil || len(tools) != 1 {
+		t.Fatalf("Expected one tool, got %v, %v", tools, err)
+	}
+	result, err := inspector.CallToolManually("notes", server, "search_notes", `{"query": "groceries"}`)
+	if err != nil {
+		t.Fatalf("CallToolManually: %v", err)
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); !ok || text.Text != "found: groceries" {
+		t.Errorf("Expected the tool result, got %+v", result.Content)
+	}
+
+	filtered := ServerConfigWrapper{Config: SSEServerConfig{Url: ts.URL + "/sse",
+		ToolFilter: ToolFilter{ExcludeTools: []string{"search_*"}}}}
+	if _, err := inspector.CallToolManually("notes", filtered, "search_notes", `{}`); err == nil ||
+		!strings.Contains(err.Error(), "excluded") {
+		t.Errorf("Expected an excluded tool to be refused, got %v", err)
+	}
+}
@@ -3,15 +3,20 @@ package mcphost
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/exec"
 	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/secrets"
 
 	"strings"
 	"time"
 
 	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -26,12 +31,15 @@ type MCPConfig struct {
 
 type ServerConfig interface {
 	GetType() string
+	GetToolFilter() ToolFilter
 }
 
 type STDIOServerConfig struct {
 	Command string            `json:"command"`
 	Args    []string          `json:"args"`
 	Env     map[string]string `json:"env,omitempty"`
+	ToolFilter
+	ExecOptions
 }
 
 func (s STDIOServerConfig) GetType() string {
@@ -41,6 +49,7 @@ func (s STDIOServerConfig) GetType() string {
 type SSEServerConfig struct {
 	Url     string   `json:"url"`
 	Headers []string `json:"headers,omitempty"`
+	ToolFilter
 }
 
 func (s SSEServerConfig) GetType() string {
@@ -80,13 +89,16 @@ func (w ServerConfigWrapper) MarshalJSON() ([]byte, error) {
 	return json.Marshal(w.Config)
 }
 
-func mcpToolsToAnthropicTools(serverName string, mcpTools []mcp.Tool) []models.Tool {
-	anthropicTools := make([]models.Tool, len(mcpTools))
+func mcpToolsToAnthropicTools(serverName string, mcpTools []mcp.Tool, filter ToolFilter, names *toolNameRegistry) []models.Tool {
+	anthropicTools := make([]models.Tool, 0, len(mcpTools))
 
-	for i, tool := range mcpTools {
-		namespacedName := fmt.Sprintf("%s__%s", serverName, tool.Name)
+	for _, tool := range mcpTools {
+		if !filter.Allows(tool.Name) {
+			continue
+		}
+		namespacedName := names.name(serverName, tool.Name)
 
-		anthropicTools[i] = models.Tool{
+		anthropicTools = append(anthropicTools, models.Tool{
 			Name:        namespacedName,
 			Description: tool.Description,
 			InputSchema: models.Schema{
@@ -94,7 +106,7 @@ func mcpToolsToAnthropicTools(serverName string, mcpTools []mcp.Tool) []models.T
 				Properties: tool.InputSchema.Properties,
 				Required:   tool.InputSchema.Required,
 			},
-		}
+		})
 	}
 
 	return anthropicTools
@@ -132,98 +144,292 @@ func loadMCPConfig(settings *MCPSettings) (*MCPConfig, error) {
 	return &config, nil
 }
 
+// clientHooks lets the caller take part in creating remote clients
+type clientHooks struct {
+	// headers returns extra HTTP headers for a remote server
+	headers func(name, url string) map[string]string
+	// unauthorized is called when a remote server rejects the connection
+	// with 401; the server is then skipped instead of failing the whole load
+	unauthorized func(name string, cfg SSEServerConfig)
+	// elicitation returns the handler that answers a server's elicitation requests
+	elicitation func(name string) mcpclient.ElicitationHandler
+	// secrets resolves ${secret:...} references; nil while the store is locked
+	secrets secrets.Lookup
+	// exited is called when a stdio server is killed for exceeding a limit
+	exited func(name string, err error)
+	// baseEnv is the environment stdio servers start from; nil uses ours
+	baseEnv []string
+	// status receives the launch outcome of every server
+	status func(status ServerStatus)
+	// stderr receives each line a stdio server writes to stderr
+	stderr func(name, line string)
+	// initialized receives what a server reported about itself on initialize
+	initialized func(name string, info ServerInfo)
+}
+
+// report passes a server's launch outcome to the status hook
+func (h *clientHooks) report(status ServerStatus) {
+	if h != nil && h.status != nil {
+		h.status(status)
+	}
+}
+
 func createMCPClients(
 	config *MCPConfig,
+	hooks *clientHooks,
 ) (map[string]mcpclient.MCPClient, error) {
 	clients := make(map[string]mcpclient.MCPClient)
 
 	for name, server := range config.MCPServers {
-		var client mcpclient.MCPClient
-		var err error
-
-		if server.Config.GetType() == transportSSE {
-			sseConfig := server.Config.(SSEServerConfig)
-
-			options := []mcpclient.ClientOption{}
-
-			if sseConfig.Headers != nil {
-				// Parse headers from the config
-				headers := make(map[string]string)
-				for _, header := range sseConfig.Headers {
-					parts := strings.SplitN(header, ":", 2)
-					if len(parts) == 2 {
-						key := strings.TrimSpace(parts[0])
-						value := strings.TrimSpace(parts[1])
-						headers[key] = value
-					}
-				}
-				options = append(options, mcpclient.WithHeaders(headers))
-			}
-
-			client, err = mcpclient.NewSSEMCPClient(
-				sseConfig.Url,
-				options...,
-			)
-			if err == nil {
-				err = client.(*mcpclient.SSEMCPClient).Start(context.Background())
-			}
-		} else {
-			stdioConfig := server.Config.(STDIOServerConfig)
-			var env []string
-			for k, v := range stdioConfig.Env {
-				env = append(env, fmt.Sprintf("%s=%s", k, v))
-			}
-			client, err = mcpclient.NewStdioMCPClient(
-				stdioConfig.Command,
-				env,
-				stdioConfig.Args...)
+		server, command, err := prepareServer(server, hooks)
+		if err != nil {
+			// one misconfigured server should not take the others down
+			slog.Error("skipping server that cannot be launched", "name", name, "error", err)
+			hooks.report(ServerStatus{Name: name, State: ServerFailed, Error: err.Error()})
+			continue
 		}
+
+		client, err := createMCPClient(name, server, hooks)
 		if err != nil {
+			if sseConfig, ok := server.Config.(SSEServerConfig); ok &&
+				hooks != nil && hooks.unauthorized != nil && isUnauthorized(err) {
+				slog.Info("server requires authorization", "name", name)
+				hooks.report(ServerStatus{Name: name, State: ServerNeedsAuth, Error: err.Error()})
+				hooks.unauthorized(name, sseConfig)
+				continue
+			}
+			hooks.report(ServerStatus{Name: name, State: ServerFailed, Error: err.Error(), Command: command})
 			for _, c := range clients {
 				c.Close()
 			}
-			return nil, fmt.Errorf(
-				"failed to create MCP client for %s: %w",
-				name,
-				err,
-			)
+			return nil, err
+		}
+
+		hooks.report(ServerStatus{Name: name, State: ServerRunning, Command: command})
+		clients[name] = client
+	}
+
+	return clients, nil
+}
+
+// prepareServer resolves a server's references and, for stdio servers, finds
+// its executable up front so a missing one gets a clear message. It returns
+// the resolved command, empty for remote servers.
+func prepareServer(server ServerConfigWrapper, hooks *clientHooks) (ServerConfigWrapper, string, error) {
+	var lookup secrets.Lookup
+	var baseEnv []string
+	if hooks != nil {
+		lookup = hooks.secrets
+		baseEnv = hooks.baseEnv
+	}
+
+	server, err := resolveServerConfig(server, lookup)
+	if err != nil {
+		return server, "", err
+	}
+
+	stdioConfig, ok := server.Config.(STDIOServerConfig)
+	if !ok {
+		return server, "", nil
+	}
+	env := append(serverBaseEnv(baseEnv, stdioConfig.ScrubEnv), envList(stdioConfig.Env)...)
+	command, err := resolveCommand(stdioConfig.Command, envValue(env, "PATH"), stdioConfig.WorkingDir)
+	if err != nil {
+		return server, "", err
+	}
+	stdioConfig.Command = command
+	server.Config = stdioConfig
+	return server, command, nil
+}
+
+// createMCPClient connects to and initializes a single server
+func createMCPClient(
+	name string,
+	server ServerConfigWrapper,
+	hooks *clientHooks,
+) (mcpclient.MCPClient, error) {
+	client, result, err := connectMCPClient(name, server, hooks)
+	if err != nil {
+		return nil, err
+	}
+	if hooks != nil && hooks.initialized != nil {
+		hooks.initialized(name, serverInfoFromResult(result))
+	}
+	return client, nil
+}
+
+// connectMCPClient connects to a single server and returns what it
+// reported about itself during initialization
+func connectMCPClient(
+	name string,
+	server ServerConfigWrapper,
+	hooks *clientHooks,
+) (*mcpclient.Client, *mcp.InitializeResult, error) {
+	var t transport.Interface
+	var spawned *exec.Cmd
+	var authErrors *authErrorTransport
+	var err error
+
+	if server.Config.GetType() == transportSSE {
+		sseConfig := server.Config.(SSEServerConfig)
+
+		options := []transport.ClientOption{}
+
+		// Parse headers from the config
+		headers := make(map[string]string)
+		for _, header := range sseConfig.Headers {
+			parts := strings.SplitN(header, ":", 2)
+			if len(parts) == 2 {
+				key := strings.TrimSpace(parts[0])
+				value := strings.TrimSpace(parts[1])
+				headers[key] = value
+			}
+		}
+		if len(headers) > 0 {
+			options = append(options, transport.WithHeaders(headers))
+		}
+		// asked per request, so a token refreshed meanwhile is sent
+		if hooks != nil && hooks.headers != nil {
+			options = append(options, transport.WithHeaderFunc(func(ctx context.Context) map[string]string {
+				return hooks.headers(name, sseConfig.Url)
+			}))
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+		authErrors = &authErrorTransport{base: http.DefaultTransport}
+		if hooks != nil && hooks.unauthorized != nil {
+			authErrors.expired = func() {
+				slog.Info("server rejected its token, authorizing again", "name", name)
+				hooks.report(ServerStatus{Name: name, State: ServerNeedsAuth, Error: "authorization expired"})
+				hooks.unauthorized(name, sseConfig)
+			}
+		}
+		options = append(options, transport.WithHTTPClient(&http.Client{Transport: authErrors}))
 
-		slog.Info("Initializing server...", "name", name)
-		initRequest := mcp.InitializeRequest{}
-		initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
-		initRequest.Params.ClientInfo = mcp.Implementation{
-			Name:    "mcphost",
-			Version: "0.1.0",
+		t, err = transport.NewSSE(
+			sseConfig.Url,
+			options...,
+		)
+	} else {
+		stdioConfig := server.Config.(STDIOServerConfig)
+		var baseEnv []string
+		if hooks != nil {
+			baseEnv = hooks.baseEnv
 		}
-		initRequest.Params.Capabilities = mcp.ClientCapabilities{}
+		t = transport.NewStdioWithOptions(
+			stdioConfig.Command,
+			envList(stdioConfig.Env),
+			stdioConfig.Args,
+			transport.WithCommandFunc(func(ctx context.Context, command string, env []string, args []string) (*exec.Cmd, error) {
+				spawned, err = buildCommand(ctx, stdioConfig.ExecOptions, baseEnv, command, env, args)
+				return spawned, err
+			}))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"failed to create MCP client for %s: %w",
+			name,
+			err,
+		)
+	}
 
-		_, err = client.Initialize(ctx, initRequest)
-		if err != nil {
-			client.Close()
-			for _, c := range clients {
-				c.Close()
+	clientOptions := []mcpclient.ClientOption{}
+	if hooks != nil && hooks.elicitation != nil {
+		clientOptions = append(clientOptions,
+			mcpclient.WithElicitationHandler(hooks.elicitation(name)))
+	}
+	client := mcpclient.NewClient(t, clientOptions...)
+
+	// Start wires notifications and server-to-client requests to the client
+	if err := client.Start(context.Background()); err != nil {
+		return nil, nil, fmt.Errorf(
+			"failed to create MCP client for %s: %w",
+			name,
+			err,
+		)
+	}
+
+	if spawned != nil && spawned.Process != nil {
+		watchServerExit(name, server.Config.(STDIOServerConfig).Limits, spawned, hooks)
+	}
+	// read stderr before initializing so a server that fails to start leaves its reasons behind
+	var stderrDone chan struct{}
+	if stdio, ok := t.(*transport.Stdio); ok && stdio.Stderr() != nil {
+		stderrDone = make(chan struct{})
+		go func() {
+			defer close(stderrDone)
+			captureStderr(name, stdio.Stderr(), hooks)
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if stderrDone != nil {
+		// stderr only closes once the process is gone; stop waiting for its reply
+		go func() {
+			select {
+			case <-stderrDone:
+				cancel()
+			case <-ctx.Done():
 			}
-			return nil, fmt.Errorf(
-				"failed to initialize MCP client for %s: %w",
-				name,
-				err,
-			)
+		}()
+	}
+
+	slog.Info("Initializing server...", "name", name)
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "mcphost",
+		Version: "0.1.0",
+	}
+	initRequest.Params.Capabilities = mcp.ClientCapabilities{}
+
+	result, err := client.Initialize(ctx, initRequest)
+	if err != nil {
+		// closing cuts stderr off, so let a dying server's last words arrive first
+		if stderrDone != nil {
+			select {
+			case <-stderrDone:
+			case <-time.After(stderrDrainTimeout):
+			}
+		}
+		client.Close()
+		if errors.Is(err, context.Canceled) {
+			err = fmt.Errorf("server exited before it finished initializing (see its logs): %w", err)
 		}
+		return nil, nil, fmt.Errorf(
+			"failed to initialize MCP client for %s: %w",
+			name,
+			err,
+		)
+	}
 
-		clients[name] = client
+	if authErrors != nil {
+		authErrors.established.Store(true)
 	}
+	return client, result, nil
+}
 
-	return clients, nil
+// envList turns a server's env map into KEY=value pairs
+func envList(env map[string]string) []string {
+	var list []string
+	for k, v := range env {
+		list = append(list, fmt.Sprintf("%s=%s", k, v))
+	}
+	return list
+}
+
+// isUnauthorized reports whether a server rejected our credentials; see
+// authErrorTransport
+func isUnauthorized(err error) bool {
+	return mcpclient.IsOAuthAuthorizationRequiredError(err)
 }
 
 // ServerConfigService handles MCP server configuration operations
 type ServerConfigService struct {
 	settings *MCPSettings
 	clients  map[string]mcpclient.MCPClient
+	filters  map[string]ToolFilter
+	names    toolNameRegistry
 	logger   *slog.Logger
 }
 
@@ -243,12 +449,16 @@ func (s *ServerConfigService) LoadConfig() error {
 		return fmt.Errorf("error loading MCP config: %w", err)
 	}
 
-	clients, err := createMCPClients(config)
+	clients, err := createMCPClients(config, nil)
 	if err != nil {
 		return fmt.Errorf("error creating MCP clients: %w", err)
 	}
 
 	s.clients = clients
+	s.filters = make(map[string]ToolFilter, len(config.MCPServers))
+	for name, server := range config.MCPServers {
+		s.filters[name] = server.Config.GetToolFilter()
+	}
 	return nil
 }
 
@@ -275,7 +485,7 @@ func (s *ServerConfigService) ListTools(ctx context.Context) ([]models.Tool, err
 	var allTools []models.Tool
 
 	for serverName, mcpClient := range s.clients {
-		toolsResult, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+		mcpTools, err := listAllTools(ctx, mcpClient)
 		if err != nil {
 			s.logger.Error("error fetching tools",
 				"server", serverName,
@@ -283,11 +493,11 @@ func (s *ServerConfigService) ListTools(ctx context.Context) ([]models.Tool, err
 			continue
 		}
 
-		serverTools := mcpToolsToAnthropicTools(serverName, toolsResult.Tools)
+		serverTools := mcpToolsToAnthropicTools(serverName, mcpTools, s.filters[serverName], &s.names)
 		allTools = append(allTools, serverTools...)
 		s.logger.Info("tools loaded",
 			"server", serverName,
-			"count", len(toolsResult.Tools))
+			"count", len(mcpTools))
 	}
 
 	return allTools, nil
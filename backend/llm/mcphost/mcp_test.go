@@ -158,7 +158,7 @@ func TestMCPService(t *testing.T) {
 	logger.Info("constructed MCPSettings", "config", pp(set))
 
 	// ── 4. init service ──────────────────────────────────────────────────────
-	svc, err := NewMCPService(set)
+	svc, err := NewMCPService(context.Background(), set)
 	if err != nil {
 		t.Fatalf("NewMCPService: %v", err)
 	}
@@ -212,3 +212,40 @@ func TestMCPService(t *testing.T) {
 		}
 	})
 }
+
+func TestResolveServerConfig(t *testing.T) {
+	t.Setenv("SPOT_TEST_WORKSPACE", "/work")
+	lookup := secretMap{"github": "ghp_123"}
+
+	stdio := ServerConfigWrapper{Config: STDIOServerConfig{
+		Command: "gh-mcp",
+		Args:    []string{"--root", "${env:SPOT_TEST_WORKSPACE}"},
+		Env:     map[string]string{"GITHUB_TOKEN": "${secret:github}"},
+	}}
+	resolved, err := resolveServerConfig(stdio, lookup)
+	if err != nil {
+		t.Fatalf("resolveServerConfig: %v", err)
+	}
+	cfg := resolved.Config.(STDIOServerConfig)
+	if cfg.Args[1] != "/work" || cfg.Env["GITHUB_TOKEN"] != "ghp_123" {
+		t.Errorf("Unexpected resolved config: %+v", cfg)
+	}
+	if stdio.Config.(STDIOServerConfig).Env["GITHUB_TOKEN"] != "${secret:github}" {
+		t.Error("Expected the original config to keep its references")
+	}
+
+	sse := ServerConfigWrapper{Config: SSEServerConfig{
+		Url:     "https://api.example.com/mcp",
+		Headers: []string{"Authorization: Bearer ${secret:missing}"},
+	}}
+	if _, err := resolveServerConfig(sse, lookup); err == nil {
+		t.Error("Expected an error for a missing secret")
+	}
+}
+
+type secretMap map[string]string
+
+func (m secretMap) Get(name string) (string, bool) {
+	v, ok := m[name]
+	return v, ok
+}
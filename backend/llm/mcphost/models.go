@@ -4,8 +4,17 @@ import (
 	"context"
 	"log/slog"
 	"regexp"
+	"smart-spotlight-ai/backend/packages/llm/capabilities"
 	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers/anthropic"
+	"smart-spotlight-ai/backend/packages/llm/providers/azure"
+	"smart-spotlight-ai/backend/packages/llm/providers/bedrock"
+	"smart-spotlight-ai/backend/packages/llm/providers/ollama"
+	"smart-spotlight-ai/backend/packages/llm/providers/openai"
+	"smart-spotlight-ai/backend/packages/oauth"
+	"smart-spotlight-ai/backend/packages/secrets"
 	"strings"
+	"sync"
 	"time"
 
 	mcpclient "github.com/mark3labs/mcp-go/client"
@@ -13,25 +22,52 @@ import (
 
 // LLMProvider represents a single LLM provider configuration
 type LLMProvider struct {
+	Name         string // profile name reported with answers
 	ProviderName string
 	BaseURL      string
 	APIKey       string
 	ModelName    string
+	Params       models.GenerationParams
+	Ollama       ollama.Options    // num_ctx and keep_alive of ollama profiles
+	Anthropic    anthropic.Options // prompt caching and thinking of anthropic profiles
+	OpenAI       openai.Options    // API and reasoning effort of openai profiles
+	Azure        azure.Options     // api-version and OpenAI options of azure profiles
+	Bedrock      bedrock.Options   // region and access key of bedrock profiles
 	Metadata     map[string]string // Flexible metadata for provider-specific settings
+	Fallbacks    []LLMProvider     // tried in order when this provider fails
 }
 
 // MCPSettings represents the MCP configuration settings
 type MCPSettings struct {
-	ConfigFile    string
-	SystemPrompt  string // Actual system prompt content
-	MessageWindow int
-	Provider      LLMProvider // Single provider configuration
-	DebugMode     bool
+	ConfigFile     string
+	SystemPrompt   string // Persona text the user wrote; becomes .Persona in PromptTemplate
+	MessageWindow  int
+	Provider       LLMProvider // Single provider configuration
+	DebugMode      bool
+	Auth           *oauth.Manager         // Signs in to remote servers that require OAuth; nil disables OAuth
+	ToolTopK       int                    // Most relevant tools sent per request; 0 sends the whole catalog
+	PinnedTools    []string               // Globs over server__tool names that are always sent
+	Secrets        secrets.Lookup         // Resolves ${secret:...} references; nil while the store is locked
+	BaseEnv        []string               // Login-shell environment stdio servers start from; nil uses ours
+	LogDir         string                 // Where per-server log files are written; empty keeps logs in memory only
+	PromptTemplate string                 // text/template for the system prompt; empty uses DefaultPromptTemplate
+	Capabilities   *capabilities.Registry // what each model supports; nil assumes tools and an unknown context window
 }
 
 type PromptEvent struct {
-	Type string      // see consts above
-	Data interface{} // string, HistoryMessage, map[string]any, etc.
+	Type     string      // see consts above
+	Data     interface{} // string, HistoryMessage, map[string]any, etc.
+	Provider string      // profile that answered, set on final results
+	Usage    *Usage      // tokens the prompt took, set on final results
+}
+
+// Usage is the tokens the provider calls of a prompt took, summed over its
+// tool cycle
+type Usage struct {
+	InputTokens      int `json:"inputTokens"`
+	OutputTokens     int `json:"outputTokens"`
+	CacheReadTokens  int `json:"cacheReadTokens"`  // input read from the provider's prompt cache
+	CacheWriteTokens int `json:"cacheWriteTokens"` // input written to it
 }
 
 type confirmationReply struct {
@@ -44,9 +80,32 @@ type MCPService struct {
 	ctx context.Context // ← add
 
 	settings       *MCPSettings
+	providerMu     sync.RWMutex // guards provider, fallbacks, settings.Provider and the prompt settings
 	provider       models.Provider
+	fallbacks      []chainLink
+	turn           []chainLink // providers answering the current prompt
+	clientsMu      sync.RWMutex
 	mcpClients     map[string]mcpclient.MCPClient
+	secrets        secrets.Lookup
+	auth           *oauth.Manager
+	toolsMu        sync.RWMutex
 	tools          []models.Tool
+	serverTools    map[string][]models.Tool // per-server catalog, rebuilt on tools/list_changed
+	serverFilters  map[string]ToolFilter    // include/exclude patterns from the server config
+	toolIndex      *toolIndex               // BM25 index over tools, rebuilt with the catalog
+	toolNames      toolNameRegistry         // provider-safe function names <-> server tools
+	foundTools     map[string]bool          // tools found via search_tools during the current prompt; guarded by toolsMu
+	frozenTools    map[string]bool          // tools kept for the conversation while the prompt is cached; guarded by toolsMu
+	usage          Usage                    // tokens the current prompt took so far
+	logsMu         sync.Mutex
+	serverLogs     map[string]*serverLogBuffer
+	progressMu     sync.Mutex
+	inflightCalls  map[string]inflightCall // progress token -> running tool call
+	elicitMu       sync.Mutex
+	elicitations   map[string]*pendingElicitation // token -> request waiting for the user
+	statusMu       sync.Mutex
+	serverStatus   map[string]ServerStatus // launch outcome of every configured server
+	serverInit     map[string]ServerInfo   // what each connected server reported on initialize
 	logger         *slog.Logger
 	initialBackoff time.Duration
 	maxBackoff     time.Duration
@@ -64,6 +123,9 @@ const (
 	EventToolResult           = "tool_result"
 	EventAuthorization        = "authorization_required"
 	EventConfirmationRequired = "confirmation_required"
+	EventProgress             = "progress"
+	EventElicitation          = "elicitation_required"
+	EventThinking             = "thinking" // reasoning the model showed before answering; optional to display
 	EventFinalResult          = "final_result"
 	EventError                = "error"
 )
@@ -0,0 +1,333 @@
+package mcphost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	methodToolsListChanged = "notifications/tools/list_changed"
+	methodProgress         = "notifications/progress"
+	methodLogMessage       = "notifications/message"
+)
+
+// serverLogCapacity is the number of log records kept per server
+const serverLogCapacity = 500
+
+// ServerLogEntry is a single log record of an MCP server: a logging
+// notification or a line the server wrote to stderr
+type ServerLogEntry struct {
+	Server    string    `json:"server"`
+	Source    string    `json:"source"`
+	Level     string    `json:"level"`
+	Logger    string    `json:"logger,omitempty"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// serverLogBuffer keeps the most recent log records of one server and, when
+// a log directory is configured, appends every record to its log file
+type serverLogBuffer struct {
+	mu      sync.Mutex
+	entries []ServerLogEntry
+	limit   int
+	file    *rotatingFile
+}
+
+func newServerLogBuffer(limit int) *serverLogBuffer {
+	return &serverLogBuffer{limit: limit}
+}
+
+func (b *serverLogBuffer) append(entry ServerLogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries = append(b.entries, entry)
+	if over := len(b.entries) - b.limit; over > 0 {
+		b.entries = append([]ServerLogEntry(nil), b.entries[over:]...)
+	}
+	if b.file != nil {
+		if err := b.file.writeLine(formatLogLine(entry)); err != nil {
+			slog.Warn("failed to write server log", "server", entry.Server, "error", err)
+		}
+	}
+}
+
+func (b *serverLogBuffer) clear() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries = nil
+	if b.file != nil {
+		return b.file.remove()
+	}
+	return nil
+}
+
+// records returns every record of the server, read from its log files when
+// it has them so records that left the ring, or predate this run, are kept
+func (b *serverLogBuffer) records(server string) []ServerLogEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.file != nil {
+		entries, err := b.file.readEntries(server)
+		if err == nil {
+			return entries
+		}
+		slog.Warn("failed to read server log", "server", server, "error", err)
+	}
+	result := make([]ServerLogEntry, len(b.entries))
+	copy(result, b.entries)
+	return result
+}
+
+// closeFile closes the log file; the next record reopens it
+func (b *serverLogBuffer) closeFile() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.file != nil {
+		b.file.close()
+	}
+}
+
+// inflightCall identifies the tool call a progress token belongs to
+type inflightCall struct {
+	Server string
+	Tool   string
+}
+
+// registerNotificationHandlers subscribes the service to notifications from one server
+func (s *MCPService) registerNotificationHandlers(server string, client mcpclient.MCPClient) {
+	client.OnNotification(func(n mcp.JSONRPCNotification) {
+		s.handleNotification(server, n)
+	})
+}
+
+// handleNotification dispatches a server notification by method
+func (s *MCPService) handleNotification(server string, n mcp.JSONRPCNotification) {
+	switch n.Method {
+	case methodToolsListChanged:
+		// Re-listing sends a request on the same client, so it must not
+		// run on the goroutine that is delivering this notification.
+		go func() {
+			if err := s.refreshServerTools(server); err != nil {
+				s.logger.Error("failed to refresh tools", "server", server, "error", err)
+			}
+		}()
+	case methodProgress:
+		s.handleProgress(server, n.Params.AdditionalFields)
+	case methodLogMessage:
+		s.appendServerLog(server, n.Params.AdditionalFields)
+	default:
+		s.logger.Debug("unhandled notification", "server", server, "method", n.Method)
+	}
+}
+
+// handleProgress relays a progress notification for an in-flight tool call
+func (s *MCPService) handleProgress(server string, params map[string]interface{}) {
+	token := fmt.Sprint(params["progressToken"])
+
+	s.progressMu.Lock()
+	call, ok := s.inflightCalls[token]
+	s.progressMu.Unlock()
+	if !ok {
+		s.logger.Debug("progress for unknown token", "server", server, "token", token)
+		return
+	}
+
+	data := map[string]any{
+		"token":    token,
+		"server":   call.Server,
+		"tool":     call.Tool,
+		"progress": params["progress"],
+	}
+	if total, ok := params["total"]; ok {
+		data["total"] = total
+	}
+	if msg, ok := params["message"].(string); ok {
+		data["message"] = msg
+	}
+
+	s.emit(PromptEvent{Type: EventProgress, Data: data})
+}
+
+// appendServerLog stores a notifications/message record in the server's log buffer
+func (s *MCPService) appendServerLog(server string, params map[string]interface{}) {
+	entry := ServerLogEntry{
+		Server:    server,
+		Source:    LogSourceNotification,
+		Timestamp: time.Now(),
+	}
+	if level, ok := params["level"].(string); ok {
+		entry.Level = level
+	}
+	if logger, ok := params["logger"].(string); ok {
+		entry.Logger = logger
+	}
+	switch data := params["data"].(type) {
+	case string:
+		entry.Message = data
+	case nil:
+	default:
+		raw, _ := json.Marshal(data)
+		entry.Message = string(raw)
+	}
+
+	s.serverLogBuffer(server).append(entry)
+}
+
+// trackProgress registers a progress token for a tool call and returns a func that releases it
+func (s *MCPService) trackProgress(token, server, tool string) func() {
+	s.progressMu.Lock()
+	if s.inflightCalls == nil {
+		s.inflightCalls = make(map[string]inflightCall)
+	}
+	s.inflightCalls[token] = inflightCall{Server: server, Tool: tool}
+	s.progressMu.Unlock()
+
+	return func() {
+		s.progressMu.Lock()
+		delete(s.inflightCalls, token)
+		s.progressMu.Unlock()
+	}
+}
+
+// listAllTools fetches a server's tools page by page, following nextCursor
+func listAllTools(ctx context.Context, client mcpclient.MCPClient) ([]mcp.Tool, error) {
+	return listAllPages(ctx, func(ctx context.Context, cursor mcp.Cursor) ([]mcp.Tool, mcp.Cursor, error) {
+		req := mcp.ListToolsRequest{}
+		req.Params.Cursor = cursor
+		page, err := client.ListToolsByPage(ctx, req)
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Tools, page.NextCursor, nil
+	})
+}
+
+// listAllPrompts fetches a server's prompts page by page
+func listAllPrompts(ctx context.Context, client mcpclient.MCPClient) ([]mcp.Prompt, error) {
+	return listAllPages(ctx, func(ctx context.Context, cursor mcp.Cursor) ([]mcp.Prompt, mcp.Cursor, error) {
+		req := mcp.ListPromptsRequest{}
+		req.Params.Cursor = cursor
+		page, err := client.ListPromptsByPage(ctx, req)
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Prompts, page.NextCursor, nil
+	})
+}
+
+// listAllResources fetches a server's resources page by page
+func listAllResources(ctx context.Context, client mcpclient.MCPClient) ([]mcp.Resource, error) {
+	return listAllPages(ctx, func(ctx context.Context, cursor mcp.Cursor) ([]mcp.Resource, mcp.Cursor, error) {
+		req := mcp.ListResourcesRequest{}
+		req.Params.Cursor = cursor
+		page, err := client.ListResourcesByPage(ctx, req)
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Resources, page.NextCursor, nil
+	})
+}
+
+// listAllResourceTemplates fetches a server's resource templates page by page
+func listAllResourceTemplates(ctx context.Context, client mcpclient.MCPClient) ([]mcp.ResourceTemplate, error) {
+	return listAllPages(ctx, func(ctx context.Context, cursor mcp.Cursor) ([]mcp.ResourceTemplate, mcp.Cursor, error) {
+		req := mcp.ListResourceTemplatesRequest{}
+		req.Params.Cursor = cursor
+		page, err := client.ListResourceTemplatesByPage(ctx, req)
+		if err != nil {
+			return nil, "", err
+		}
+		return page.ResourceTemplates, page.NextCursor, nil
+	})
+}
+
+// listAllPages calls list until a page comes without nextCursor. A server
+// repeating a cursor would otherwise be listed forever.
+func listAllPages[T any](ctx context.Context, list func(context.Context, mcp.Cursor) ([]T, mcp.Cursor, error)) ([]T, error) {
+	var items []T
+	seen := map[mcp.Cursor]bool{}
+
+	var cursor mcp.Cursor
+	for {
+		page, next, err := list(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+
+		if next == "" {
+			return items, nil
+		}
+		if seen[next] {
+			return nil, fmt.Errorf("server repeated pagination cursor %q", next)
+		}
+		seen[next] = true
+		cursor = next
+	}
+}
+
+// refreshServerTools re-lists one server's tools and rebuilds the combined catalog
+func (s *MCPService) refreshServerTools(server string) error {
+	client, ok := s.getClient(server)
+	if !ok {
+		return fmt.Errorf("unknown server: %s", server)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	mcpTools, err := listAllTools(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	s.setServerTools(server, mcpToolsToAnthropicTools(server, mcpTools, s.toolFilter(server), &s.toolNames))
+	s.logger.Info("tools refreshed", "server", server, "count", len(mcpTools))
+	return nil
+}
+
+// setServerTools replaces one server's tools in the catalog
+func (s *MCPService) setServerTools(server string, tools []models.Tool) {
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+
+	if s.serverTools == nil {
+		s.serverTools = make(map[string][]models.Tool)
+	}
+	s.serverTools[server] = tools
+
+	names := make([]string, 0, len(s.serverTools))
+	for name := range s.serverTools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	all := []models.Tool{}
+	for _, name := range names {
+		all = append(all, s.serverTools[name]...)
+	}
+	s.tools = all
+	s.toolIndex = newToolIndex(all)
+}
+
+// currentTools returns a snapshot of the combined tool catalog
+func (s *MCPService) currentTools() []models.Tool {
+	s.toolsMu.RLock()
+	defer s.toolsMu.RUnlock()
+	return s.tools
+}
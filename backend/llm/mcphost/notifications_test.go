@@ -0,0 +1,159 @@
+package mcphost
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// pagedClient serves tools/list one page at a time; everything else is unimplemented
+type pagedClient struct {
+	mcpclient.MCPClient
+	pages [][]string
+	calls int
+}
+
+func (c *pagedClient) ListToolsByPage(ctx context.Context, req mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	page := 0
+	if req.Params.Cursor != "" {
+		fmt.Sscanf(string(req.Params.Cursor), "page-%d", &page)
+	}
+	c.calls++
+
+	result := &mcp.ListToolsResult{}
+	for _, name := range c.pages[page] {
+		result.Tools = append(result.Tools, mcp.NewTool(name))
+	}
+	if page+1 < len(c.pages) {
+		result.NextCursor = mcp.Cursor(fmt.Sprintf("page-%d", page+1))
+	}
+	return result, nil
+}
+
+func newTestService() *MCPService {
+	return &MCPService{
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		EventChan: make(chan PromptEvent, 10),
+	}
+}
+
+func notification(method string, params map[string]interface{}) mcp.JSONRPCNotification {
+	n := mcp.JSONRPCNotification{JSONRPC: mcp.JSONRPC_VERSION}
+	n.Method = method
+	n.Params.AdditionalFields = params
+	return n
+}
+
+func TestListAllToolsFollowsCursor(t *testing.T) {
+	client := &pagedClient{pages: [][]string{{"a", "b"}, {"c"}, {"d"}}}
+
+	tools, err := listAllTools(context.Background(), client)
+	if err != nil {
+		t.Fatalf("listAllTools: %v", err)
+	}
+	if len(tools) != 4 {
+		t.Fatalf("Expected 4 tools, got %d", len(tools))
+	}
+	if client.calls != 3 {
+		t.Errorf("Expected 3 page requests, got %d", client.calls)
+	}
+}
+
+func TestToolsListChangedRefreshesCatalog(t *testing.T) {
+	svc := newTestService()
+	client := &pagedClient{pages: [][]string{{"read_file"}}}
+	svc.mcpClients = map[string]mcpclient.MCPClient{"fs": client}
+
+	if err := svc.refreshServerTools("fs"); err != nil {
+		t.Fatalf("refreshServerTools: %v", err)
+	}
+	if got := len(svc.currentTools()); got != 1 {
+		t.Fatalf("Expected 1 tool, got %d", got)
+	}
+
+	client.pages = [][]string{{"read_file", "write_file"}}
+	svc.handleNotification("fs", notification(methodToolsListChanged, nil))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(svc.currentTools()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("catalog not refreshed, have %d tools", len(svc.currentTools()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if name := svc.currentTools()[1].Name; name != "fs__write_file" {
+		t.Errorf("Expected fs__write_file, got %s", name)
+	}
+}
+
+func TestProgressNotificationIsRelayed(t *testing.T) {
+	svc := newTestService()
+	release := svc.trackProgress("tok-1", "db", "export")
+
+	svc.handleNotification("db", notification(methodProgress, map[string]interface{}{
+		"progressToken": "tok-1",
+		"progress":      float64(3),
+		"total":         float64(10),
+	}))
+
+	select {
+	case ev := <-svc.EventChan:
+		if ev.Type != EventProgress {
+			t.Fatalf("Expected %s event, got %s", EventProgress, ev.Type)
+		}
+		data := ev.Data.(map[string]any)
+		if data["token"] != "tok-1" || data["tool"] != "export" || data["total"] != float64(10) {
+			t.Errorf("Unexpected progress payload: %v", data)
+		}
+	default:
+		t.Fatal("Expected a progress event")
+	}
+
+	release()
+	svc.handleNotification("db", notification(methodProgress, map[string]interface{}{
+		"progressToken": "tok-1",
+		"progress":      float64(4),
+	}))
+	if len(svc.EventChan) != 0 {
+		t.Error("Expected no event for a finished call")
+	}
+}
+
+func TestLogMessagesAreBufferedPerServer(t *testing.T) {
+	svc := newTestService()
+
+	for i := 0; i < serverLogCapacity+5; i++ {
+		svc.handleNotification("fs", notification(methodLogMessage, map[string]interface{}{
+			"level": "info",
+			"data":  fmt.Sprintf("line %d", i),
+		}))
+	}
+	svc.handleNotification("db", notification(methodLogMessage, map[string]interface{}{
+		"level":  "error",
+		"logger": "pool",
+		"data":   map[string]interface{}{"code": 7},
+	}))
+
+	fsLogs := svc.TailServerLogs("fs", 0)
+	if len(fsLogs) != serverLogCapacity {
+		t.Fatalf("Expected %d entries, got %d", serverLogCapacity, len(fsLogs))
+	}
+	if fsLogs[0].Message != "line 5" {
+		t.Errorf("Expected oldest entries to be dropped, first is %q", fsLogs[0].Message)
+	}
+
+	dbLogs := svc.TailServerLogs("db", 0)
+	if len(dbLogs) != 1 || dbLogs[0].Logger != "pool" || dbLogs[0].Message != `{"code":7}` {
+		t.Errorf("Unexpected db logs: %+v", dbLogs)
+	}
+
+	if logs := svc.TailServerLogs("missing", 0); len(logs) != 0 {
+		t.Errorf("Expected no logs for unknown server, got %d", len(logs))
+	}
+}
@@ -0,0 +1,75 @@
+package mcphost
+
+import (
+	"fmt"
+
+	"smart-spotlight-ai/backend/packages/secrets"
+)
+
+// resolveServerConfig expands ${env:...} and ${secret:...} references in a
+// server config. It runs right before a server is launched so resolved
+// values never reach the config file or the frontend.
+func resolveServerConfig(server ServerConfigWrapper, lookup secrets.Lookup) (ServerConfigWrapper, error) {
+	switch cfg := server.Config.(type) {
+	case STDIOServerConfig:
+		command, err := secrets.Expand(cfg.Command, lookup)
+		if err != nil {
+			return server, fmt.Errorf("command: %w", err)
+		}
+		cfg.Command = command
+
+		args := make([]string, len(cfg.Args))
+		for i, arg := range cfg.Args {
+			if args[i], err = secrets.Expand(arg, lookup); err != nil {
+				return server, fmt.Errorf("argument %d: %w", i+1, err)
+			}
+		}
+		cfg.Args = args
+
+		if cfg.Env != nil {
+			env := make(map[string]string, len(cfg.Env))
+			for key, value := range cfg.Env {
+				if env[key], err = secrets.Expand(value, lookup); err != nil {
+					return server, fmt.Errorf("env %s: %w", key, err)
+				}
+			}
+			cfg.Env = env
+		}
+
+		if cfg.WorkingDir, err = secrets.Expand(cfg.WorkingDir, lookup); err != nil {
+			return server, fmt.Errorf("working directory: %w", err)
+		}
+		server.Config = cfg
+
+	case SSEServerConfig:
+		url, err := secrets.Expand(cfg.Url, lookup)
+		if err != nil {
+			return server, fmt.Errorf("url: %w", err)
+		}
+		cfg.Url = url
+
+		headers := make([]string, len(cfg.Headers))
+		for i, header := range cfg.Headers {
+			if headers[i], err = secrets.Expand(header, lookup); err != nil {
+				return server, fmt.Errorf("header %d: %w", i+1, err)
+			}
+		}
+		cfg.Headers = headers
+		server.Config = cfg
+	}
+	return server, nil
+}
+
+// SetSecrets makes an unlocked secret store available to servers launched afterwards
+func (s *MCPService) SetSecrets(lookup secrets.Lookup) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	s.secrets = lookup
+}
+
+// secretLookup returns the secret store, or nil while it is locked
+func (s *MCPService) secretLookup() secrets.Lookup {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	return s.secrets
+}
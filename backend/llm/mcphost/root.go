@@ -8,10 +8,7 @@ import (
 	"os"
 	"smart-spotlight-ai/backend/packages/llm/history"
 	"smart-spotlight-ai/backend/packages/llm/models"
-	"smart-spotlight-ai/backend/packages/llm/providers/anthropic"
-	"smart-spotlight-ai/backend/packages/llm/providers/google"
-	"smart-spotlight-ai/backend/packages/llm/providers/ollama"
-	"smart-spotlight-ai/backend/packages/llm/providers/openai"
+	"smart-spotlight-ai/backend/packages/llm/providers"
 	"strings"
 	"time"
 
@@ -22,43 +19,36 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-func createProvider(ctx context.Context, settings *MCPSettings) (models.Provider, error) {
-	providerName := settings.Provider.ProviderName
-	modelName := settings.Provider.ModelName
-	systemPrompt := settings.SystemPrompt
-	apiKey := settings.Provider.APIKey
+func createProvider(ctx context.Context, provider LLMProvider, systemPrompt string) (models.Provider, error) {
+	return providers.New(ctx, providers.Config{
+		Name:         provider.Name,
+		Provider:     provider.ProviderName,
+		BaseURL:      provider.BaseURL,
+		APIKey:       provider.APIKey,
+		Model:        provider.ModelName,
+		SystemPrompt: systemPrompt,
+		Params:       provider.Params,
+		Ollama:       provider.Ollama,
+		Anthropic:    provider.Anthropic,
+		OpenAI:       provider.OpenAI,
+		Azure:        provider.Azure,
+		Bedrock:      provider.Bedrock,
+	})
+}
 
-	if apiKey == "" {
-		return nil, fmt.Errorf(
-			" API key not provided  variable",
-		)
+// validateProvider checks the required provider fields. The base URL is
+// optional, every provider but azure falls back to its public endpoint.
+func validateProvider(provider LLMProvider) error {
+	if provider.ProviderName == "" {
+		return fmt.Errorf("provider name is required")
 	}
-
-	// Use the provider configuration
-	switch providerName {
-	case "openai":
-		slog.Info("Creating OpenAI provider")
-
-		return openai.NewProvider(apiKey, settings.Provider.BaseURL, modelName, systemPrompt), nil
-
-	// You can add more provider types here as needed
-	case "anthropic":
-
-		slog.Info("Creating Anthropic provider")
-		return anthropic.NewProvider(apiKey, settings.Provider.BaseURL, modelName, systemPrompt), nil
-
-	case "ollama":
-		slog.Info("Creating Ollama provider")
-		return ollama.NewProvider(modelName, systemPrompt)
-
-	case "google":
-		slog.Info("Creating Google provider")
-
-		return google.NewProvider(ctx, apiKey, modelName, systemPrompt)
-
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", providerName)
+	if provider.APIKey == "" && providers.NeedsAPIKey(provider.ProviderName) {
+		return fmt.Errorf("provider API key is required")
 	}
+	if provider.ModelName == "" {
+		return fmt.Errorf("provider model name is required")
+	}
+	return nil
 }
 
 // NewMCPService creates a new MCP service
@@ -67,18 +57,8 @@ func NewMCPService(ctx context.Context, settings *MCPSettings) (*MCPService, err
 		return nil, fmt.Errorf("settings cannot be nil")
 	}
 
-	// Validate required provider fields
-	if settings.Provider.ProviderName == "" {
-		return nil, fmt.Errorf("provider name is required")
-	}
-	if settings.Provider.BaseURL == "" {
-		return nil, fmt.Errorf("provider base URL is required")
-	}
-	if settings.Provider.APIKey == "" {
-		return nil, fmt.Errorf("provider API key is required")
-	}
-	if settings.Provider.ModelName == "" {
-		return nil, fmt.Errorf("provider model name is required")
+	if err := validateProvider(settings.Provider); err != nil {
+		return nil, err
 	}
 
 	// Only metadata is optional, initialize if not provided
@@ -100,15 +80,18 @@ func NewMCPService(ctx context.Context, settings *MCPSettings) (*MCPService, err
 		Level: logLevel,
 	}))
 
-	provider, err := createProvider(context.Background(), settings)
+	chain, err := buildChain(context.Background(), settings.Provider, settings.SystemPrompt, logger)
 	if err != nil {
-		return nil, fmt.Errorf("error creating provider: %w", err)
+		return nil, err
 	}
 
 	return &MCPService{
 		ctx:            ctx,
 		settings:       settings,
-		provider:       provider,
+		provider:       chain[0].provider,
+		fallbacks:      chain[1:],
+		auth:           settings.Auth,
+		secrets:        settings.Secrets,
 		logger:         logger,
 		initialBackoff: initialBackoff,
 		maxBackoff:     maxBackoff,
@@ -122,6 +105,33 @@ func NewMCPService(ctx context.Context, settings *MCPSettings) (*MCPService, err
 
 // handleDirectFollowUp creates a direct follow-up prompt with tool results
 
+// SetProvider switches to another provider configuration and fallback
+// chain. The next prompt uses it; one already running finishes with the
+// previous providers.
+func (s *MCPService) SetProvider(config LLMProvider) error {
+	if config.Metadata == nil {
+		config.Metadata = make(map[string]string)
+	}
+	persona, _ := s.promptSettings()
+	chain, err := buildChain(s.ctx, config, persona, s.logger)
+	if err != nil {
+		return err
+	}
+
+	s.providerMu.Lock()
+	defer s.providerMu.Unlock()
+	s.provider = chain[0].provider
+	s.fallbacks = chain[1:]
+	s.settings.Provider = config
+	return nil
+}
+
+func (s *MCPService) providerConfig() LLMProvider {
+	s.providerMu.RLock()
+	defer s.providerMu.RUnlock()
+	return s.settings.Provider
+}
+
 func (s *MCPService) emit(ev PromptEvent) {
 	if s.waitingConfirm && ev.Type != EventConfirmationRequired {
 		// suppress everything except the confirmation itself
@@ -131,11 +141,22 @@ func (s *MCPService) emit(ev PromptEvent) {
 }
 
 func (s *MCPService) Search(query string) error {
+	return s.SearchWith(query, nil)
+}
+
+// SearchWith queues a prompt answered by route and its fallbacks instead of
+// the configured provider; a nil route uses the configured one
+func (s *MCPService) SearchWith(query string, route *LLMProvider) error {
+	provider := s.providerConfig()
+	if route != nil {
+		provider = *route
+	}
 	s.logger.Info("Search request received",
 		"query", query,
 		"timestamp", time.Now().Format(time.RFC3339),
-		"provider", s.settings.Provider.ProviderName,
-		"model", s.settings.Provider.ModelName)
+		"profile", provider.Name,
+		"provider", provider.ProviderName,
+		"model", provider.ModelName)
 
 	// Log query characteristics
 	s.logger.Debug("Query details",
@@ -148,7 +169,7 @@ func (s *MCPService) Search(query string) error {
 		"event_type", EventPrompt)
 
 	// Create and send the event
-	event := PromptEvent{Type: EventPrompt, Data: query}
+	event := PromptEvent{Type: EventPrompt, Data: promptRequest{Query: query, Route: route}}
 
 	// Try to send with timeout to detect potential deadlocks
 	select {
@@ -184,24 +205,41 @@ func (s *MCPService) InitializeClients() error {
 		return err
 	}
 
-	clients, err := createMCPClients(config)
+	s.setServerFilters(config)
+
+	s.statusMu.Lock()
+	s.serverStatus = nil
+	s.serverInit = nil
+	s.statusMu.Unlock()
+
+	clients, err := createMCPClients(config, s.clientHooks())
 	if err != nil {
 		return err
 	}
 
+	s.clientsMu.Lock()
+	previous := s.mcpClients
 	s.mcpClients = clients
+	s.clientsMu.Unlock()
+
+	// reinitializing replaces every connection
+	for name, client := range previous {
+		if err := client.Close(); err != nil {
+			s.logger.Warn("failed to close server", "name", name, "error", err)
+		}
+	}
+	s.closeServerLogs()
+	s.toolsMu.Lock()
 	s.tools = []models.Tool{}
+	s.serverTools = make(map[string][]models.Tool)
+	s.toolsMu.Unlock()
 
 	for name, client := range clients {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+		s.registerNotificationHandlers(name, client)
 
-		toolsResult, err := client.ListTools(ctx, mcp.ListToolsRequest{})
-		if err != nil {
+		if err := s.refreshServerTools(name); err != nil {
 			s.logger.Error("failed to fetch tools", "server", name, "error", err)
-			continue
 		}
-		s.tools = append(s.tools, mcpToolsToAnthropicTools(name, toolsResult.Tools)...)
 	}
 	return nil
 }
@@ -214,7 +252,25 @@ func (s *MCPService) RunPromptWithChannels(
 	if evt.Type != EventPrompt {
 		return nil
 	}
-	prompt := evt.Data.(string)
+	request, ok := evt.Data.(promptRequest)
+	if !ok {
+		request = promptRequest{Query: evt.Data.(string)}
+	}
+	prompt := request.Query
+	s.resetFoundTools()
+	s.usage = Usage{}
+
+	s.turn = s.defaultChain()
+	if request.Route != nil {
+		persona, _ := s.promptSettings()
+		chain, err := buildChain(ctx, *request.Route, persona, s.logger)
+		if err != nil {
+			s.emit(PromptEvent{Type: EventError, Data: err.Error()})
+			return nil
+		}
+		s.turn = chain
+	}
+	s.refreshSystemPrompt(time.Now())
 	*messages = append(*messages,
 		history.HistoryMessage{Role: "user",
 			Content: []history.ContentBlock{{Type: "text", Text: prompt}}})
@@ -236,21 +292,25 @@ func (s *MCPService) runLLMWithToolCycle(
 		*messages = pruneMessages(*messages, win)
 	}
 
-	/* ─ 2. Adapt history to models.Message ───────────────────────────── */
-	llmMsgs := make([]models.Message, len(*messages))
-	for i := range *messages {
-		llmMsgs[i] = &(*messages)[i]
-	}
-
-	/* ─ 3. Provider call ─────────────────────────────────────────────── */
-	msg, err := s.provider.CreateMessage(ctx, prompt, llmMsgs, s.tools)
+	/* ─ 2. Provider call, sized to each model's context window ───────── */
+	// the prompt is already the last message of the history; handing it to
+	// the provider again would send it twice and shift the cached prefix
+	tools := s.selectTools(conversationQuery(prompt, *messages))
+	msg, answeredBy, err := s.createMessage(ctx, "", *messages, tools)
 	if err != nil {
 		s.emit(PromptEvent{Type: EventError, Data: err.Error()})
 		return nil
 	}
 
-	/* ─ 4. Gather assistant text + tool_use blocks  ──────────────────── */
+	/* ─ 3. Gather assistant thinking, text + tool_use blocks  ───────── */
 	var assistantBlocks []history.ContentBlock
+	if thinking, ok := msg.(models.ThinkingMessage); ok {
+		// kept first in the turn: tool use after thinking needs it sent back
+		if blocks := history.ThinkingBlocks(thinking.GetThinking()); len(blocks) > 0 {
+			assistantBlocks = append(assistantBlocks, blocks...)
+			s.emitThinking(blocks, answeredBy)
+		}
+	}
 	if txt := msg.GetContent(); txt != "" {
 		assistantBlocks = append(assistantBlocks,
 			history.ContentBlock{Type: "text", Text: txt})
@@ -262,24 +322,40 @@ func (s *MCPService) runLLMWithToolCycle(
 				Name: call.GetName(), Input: rawArgs})
 	}
 
-	/* ─ 5. ✨ Append assistant message BEFORE tool results  ───────────── */
+	/* ─ 4. ✨ Append assistant message BEFORE tool results  ───────────── */
 	*messages = append(*messages, history.HistoryMessage{
 		Role:    msg.GetRole(), // "assistant"
 		Content: assistantBlocks,
 	})
 
-	/* ─ 6. Execute each tool call & append tool_result message ───────── */
+	/* ─ 5. Execute each tool call & append tool_result message ───────── */
 	for _, call := range msg.GetToolCalls() {
-		parts := strings.Split(call.GetName(), "__")
-		if len(parts) != 2 {
-			s.emit(PromptEvent{Type: EventError, Data: "invalid tool name format"})
+		if call.GetName() == searchToolsName {
+			found := s.searchTools(call.GetArguments())
+			*messages = append(*messages, textToolResult(call.GetID(), found))
+			continue
+		}
+
+		ref, ok := s.toolNames.lookup(call.GetName())
+		if !ok {
+			s.logger.Warn("model called an unknown tool", "name", call.GetName())
+			*messages = append(*messages, textToolResult(call.GetID(),
+				fmt.Sprintf("tool %s does not exist", call.GetName())))
+			continue
+		}
+		server, tool := ref.Server, ref.Tool
+
+		// the model may name a tool we never offered it
+		if !s.toolFilter(server).Allows(tool) {
+			s.logger.Warn("blocked call to filtered tool", "server", server, "tool", tool)
+			*messages = append(*messages, textToolResult(call.GetID(),
+				fmt.Sprintf("tool %s is not available", call.GetName())))
 			continue
 		}
-		server, tool := parts[0], parts[1]
 
 		args := call.GetArguments() // map[string]any
 
-		if need, token := s.confirmationRequired(server, tool, args); need {
+		if need, token := confirmationRequired(server, tool, args); need {
 			s.waitingConfirm = true
 			argJSON, _ := json.MarshalIndent(args, "", "  ")
 
@@ -319,15 +395,25 @@ func (s *MCPService) runLLMWithToolCycle(
 			}
 		}
 
-		client := s.mcpClients[server]
+		client, ok := s.getClient(server)
+		if !ok {
+			s.emit(PromptEvent{Type: EventError, Data: fmt.Sprintf("server %s is not connected", server)})
+			return nil
+		}
 
 		req := mcp.CallToolRequest{}               // zero-value struct
 		req.Params.Name = tool                     // e.g. "list_tables"
 		req.Params.Arguments = call.GetArguments() // map[string]any
 
+		// ask the server for notifications/progress tied to this call
+		progressToken := uuid.NewString()
+		req.Params.Meta = &mcp.Meta{ProgressToken: progressToken}
+		release := s.trackProgress(progressToken, server, tool)
+
 		toolStart := time.Now()
 		res, err := client.CallTool(ctx, req)
 		toolMS := time.Since(toolStart).Milliseconds()
+		release()
 
 		slog.Debug("tool call",
 			"server", server,
@@ -343,6 +429,9 @@ func (s *MCPService) runLLMWithToolCycle(
 		// 	Params: mcp.ToolParams{Name: tool, Arguments: call.GetArguments()},
 		// })
 		if err != nil {
+			if exitErr := s.serverExit(server); exitErr != nil {
+				err = exitErr
+			}
 			s.emit(PromptEvent{Type: EventError, Data: err.Error()})
 			return nil
 		}
@@ -367,13 +456,14 @@ func (s *MCPService) runLLMWithToolCycle(
 		})
 	}
 
-	/* ─ 7. Recurse once (if any tool was used) otherwise emit final ──── */
+	/* ─ 6. Recurse once (if any tool was used) otherwise emit final ──── */
 	if len(msg.GetToolCalls()) > 0 {
 		return s.runLLMWithToolCycle(ctx, "", messages)
 	}
 
 	final := (*messages)[len(*messages)-1] // last assistant message
-	s.emit(PromptEvent{Type: EventFinalResult, Data: final})
+	usage := s.usage
+	s.emit(PromptEvent{Type: EventFinalResult, Data: final, Provider: answeredBy, Usage: &usage})
 	return nil
 }
 
@@ -395,7 +485,7 @@ func (s *MCPService) EmitPublic(evtname string, evtType, out any) {
 // Confirmation hook (stub)
 // -----------------------------------------------------------------------------
 
-func (s *MCPService) confirmationRequired(
+func confirmationRequired(
 	server string,
 	tool string,
 	args map[string]any, // full argument map
@@ -415,6 +505,32 @@ func (s *MCPService) confirmationRequired(
 	return false, ""
 }
 
+// emitThinking shows the readable part of the model's thinking
+func (s *MCPService) emitThinking(blocks []history.ContentBlock, answeredBy string) {
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == history.BlockThinking && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	if len(parts) > 0 {
+		s.emit(PromptEvent{Type: EventThinking, Data: strings.Join(parts, "\n\n"), Provider: answeredBy})
+	}
+}
+
+// textToolResult builds the tool message answering a call with plain text
+func textToolResult(toolUseID, text string) history.HistoryMessage {
+	return history.HistoryMessage{
+		Role: "tool",
+		Content: []history.ContentBlock{{
+			Type:      "tool_result",
+			ToolUseID: toolUseID,
+			Content:   []mcp.Content{mcp.NewTextContent(text)},
+			Text:      text,
+		}},
+	}
+}
+
 // -----------------------------------------------------------------------------
 // Context pruning (unchanged)
 // -----------------------------------------------------------------------------
@@ -0,0 +1,307 @@
+package mcphost
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ExecOptions controls how a stdio server process is launched. The zero
+// value runs the command like before: inherited environment, no limits.
+type ExecOptions struct {
+	WorkingDir string          `json:"workingDir,omitempty"`
+	ScrubEnv   bool            `json:"scrubEnv,omitempty"` // start from a minimal environment instead of ours
+	Limits     *ResourceLimits `json:"limits,omitempty"`
+	Sandbox    *SandboxOptions `json:"sandbox,omitempty"`
+}
+
+// ResourceLimits caps what a server process may use; zero means unlimited.
+// MemoryMB caps resident memory through the cgroup of a systemd user scope
+// rather than address space, which runtimes like V8 reserve far beyond what
+// they use; it needs systemd-run on Linux and the kernel stops a server over
+// it with SIGKILL.
+type ResourceLimits struct {
+	CPUSeconds int `json:"cpuSeconds,omitempty"`
+	MemoryMB   int `json:"memoryMB,omitempty"`
+	OpenFiles  int `json:"openFiles,omitempty"`
+}
+
+// SandboxOptions runs the server under bubblewrap in fresh namespaces. Only
+// system directories, the installation of the resolved command and the
+// listed paths are visible inside. Files a server loads from elsewhere, e.g.
+// an interpreter outside its command's installation, belong in ReadOnlyPaths.
+type SandboxOptions struct {
+	ReadOnlyPaths []string `json:"readOnlyPaths,omitempty"`
+	WritablePaths []string `json:"writablePaths,omitempty"`
+	AllowNetwork  bool     `json:"allowNetwork,omitempty"`
+}
+
+// scrubbedEnvKeys are the parent variables a scrubbed environment keeps so
+// commands can still be found and locales work
+var scrubbedEnvKeys = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "LANG", "LC_ALL", "TMPDIR",
+	"SystemRoot", "TEMP", "TMP",
+}
+
+// systemPaths are mounted read-only in every sandbox
+var systemPaths = []string{"/usr", "/bin", "/sbin", "/lib", "/lib64", "/etc"}
+
+// buildCommand turns a server command into the process to spawn, applying
+// the working directory, environment, limits and sandbox
+func buildCommand(ctx context.Context, opts ExecOptions, base []string, command string, env []string, args []string) (*exec.Cmd, error) {
+	if opts.Sandbox != nil {
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("sandboxing is only supported on Linux")
+		}
+		bwrap, err := exec.LookPath("bwrap")
+		if err != nil {
+			return nil, fmt.Errorf("sandboxing requires bubblewrap (bwrap) to be installed: %w", err)
+		}
+		args = append(sandboxArgs(*opts.Sandbox, opts.WorkingDir, command), args...)
+		command = bwrap
+	}
+
+	if script := limitScript(opts.Limits); script != "" {
+		if runtime.GOOS == "windows" {
+			return nil, fmt.Errorf("resource limits are not supported on Windows")
+		}
+		// the shell sets the limits and then replaces itself with the server,
+		// passing the command as $0 so arguments need no quoting
+		args = append([]string{"-c", script + `exec "$0" "$@"`, command}, args...)
+		command = "/bin/sh"
+	}
+
+	if opts.Limits != nil && opts.Limits.MemoryMB > 0 {
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("memory limits are only supported on Linux")
+		}
+		systemdRun, err := exec.LookPath("systemd-run")
+		if err != nil {
+			return nil, fmt.Errorf("memory limits require systemd-run to be installed: %w", err)
+		}
+		unit := "spotlight-mcp-" + uuid.NewString()
+		args = append(append(memoryScopeArgs(unit, opts.Limits.MemoryMB), command), args...)
+		command = systemdRun
+	}
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Env = append(serverBaseEnv(base, opts.ScrubEnv), env...)
+	if opts.Sandbox == nil {
+		cmd.Dir = opts.WorkingDir
+	}
+	return cmd, nil
+}
+
+// serverBaseEnv returns the environment a server starts from: base (the
+// login shell environment), or ours when base is nil, optionally scrubbed
+func serverBaseEnv(base []string, scrub bool) []string {
+	if base == nil {
+		base = os.Environ()
+	}
+	if !scrub {
+		return append([]string{}, base...)
+	}
+	var env []string
+	for _, key := range scrubbedEnvKeys {
+		for _, kv := range base {
+			if strings.HasPrefix(kv, key+"=") {
+				env = append(env, kv)
+			}
+		}
+	}
+	return env
+}
+
+// limitScript returns the ulimit commands for the configured limits. The CPU
+// soft limit sits a second below the hard one so the kernel sends SIGXCPU,
+// which tells us why the server died, while the hard limit keeps the server
+// from raising it.
+func limitScript(limits *ResourceLimits) string {
+	if limits == nil {
+		return ""
+	}
+	var script strings.Builder
+	if limits.CPUSeconds > 0 {
+		script.WriteString("ulimit -t " + strconv.Itoa(limits.CPUSeconds+1) + " && ")
+		script.WriteString("ulimit -S -t " + strconv.Itoa(limits.CPUSeconds) + " && ")
+	}
+	if limits.OpenFiles > 0 {
+		script.WriteString("ulimit -n " + strconv.Itoa(limits.OpenFiles) + " && ")
+	}
+	return script.String()
+}
+
+// memoryScopeArgs returns the systemd-run arguments that precede the server
+// command. A scope execs the command in place, so the server keeps the pid we
+// watch, and swap is capped too so the limit cannot be sidestepped. The unit
+// is named so its result can be asked for once the server exits.
+func memoryScopeArgs(unit string, memoryMB int) []string {
+	return []string{"--user", "--scope", "--quiet", "--unit=" + unit,
+		"-p", "MemoryMax=" + strconv.Itoa(memoryMB) + "M", "-p", "MemorySwapMax=0", "--"}
+}
+
+// scopeUnit returns the scope a command built by buildCommand runs in, or ""
+// when it has no memory limit
+func scopeUnit(args []string) string {
+	if len(args) == 0 || filepath.Base(args[0]) != "systemd-run" {
+		return ""
+	}
+	for _, arg := range args[1:] {
+		if arg == "--" {
+			break
+		}
+		if unit, ok := strings.CutPrefix(arg, "--unit="); ok {
+			return unit + ".scope"
+		}
+	}
+	return ""
+}
+
+// sandboxArgs returns the bwrap arguments that precede the server command
+func sandboxArgs(sandbox SandboxOptions, workingDir, command string) []string {
+	args := []string{"--die-with-parent", "--unshare-all"}
+	if sandbox.AllowNetwork {
+		args = append(args, "--share-net")
+	}
+	for _, path := range systemPaths {
+		args = append(args, "--ro-bind-try", path, path)
+	}
+	args = append(args, "--proc", "/proc", "--dev", "/dev", "--tmpfs", "/tmp")
+	// the command was resolved on the host, e.g. to a node version manager's
+	// installation; bound before the listed paths so it cannot cover them
+	for _, dir := range commandDirs(command, workingDir) {
+		if !sandboxMounts(sandbox, dir) {
+			args = append(args, "--ro-bind", dir, dir)
+		}
+	}
+	for _, path := range sandbox.ReadOnlyPaths {
+		args = append(args, "--ro-bind", path, path)
+	}
+	for _, path := range sandbox.WritablePaths {
+		args = append(args, "--bind", path, path)
+	}
+	if workingDir != "" {
+		args = append(args, "--chdir", workingDir)
+	}
+	return append(args, "--", command)
+}
+
+// commandDirs returns the installations holding command and, when it is a
+// symlink, its target. A bare name is looked up inside the sandbox.
+func commandDirs(command, workingDir string) []string {
+	path := command
+	if !filepath.IsAbs(path) {
+		if !strings.ContainsRune(path, '/') || workingDir == "" {
+			return nil
+		}
+		path = filepath.Join(workingDir, path)
+	}
+	paths := []string{path}
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		paths = append(paths, target)
+	}
+
+	var dirs []string
+	for _, path := range paths {
+		dir := installRoot(filepath.Dir(path))
+		covered := false
+		for _, seen := range dirs {
+			covered = covered || pathWithin(dir, seen)
+		}
+		if !covered {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// installRoot widens the directory of an executable to the installation it
+// belongs to. Version managers keep libraries next to their bin and shims
+// directories, e.g. an nvm npx in versions/node/v20/bin loads
+// versions/node/v20/lib, so those are bound with their parent unless that
+// is the home or root directory.
+func installRoot(dir string) string {
+	switch filepath.Base(dir) {
+	case "bin", "shims":
+		parent := filepath.Dir(dir)
+		home, _ := os.UserHomeDir()
+		if parent != string(filepath.Separator) && parent != home {
+			return parent
+		}
+	}
+	return dir
+}
+
+// sandboxMounts reports whether dir is already visible in the sandbox
+func sandboxMounts(sandbox SandboxOptions, dir string) bool {
+	mounts := append(append(append([]string{}, systemPaths...), sandbox.ReadOnlyPaths...), sandbox.WritablePaths...)
+	for _, mount := range mounts {
+		if pathWithin(dir, mount) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathWithin reports whether path is root or below it
+func pathWithin(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// describeLimitExit explains a server exit caused by one of its limits, or
+// returns nil when the exit had nothing to do with them
+func describeLimitExit(name string, limits *ResourceLimits, exit processExit) error {
+	if limits == nil {
+		return nil
+	}
+	switch {
+	case exit.cpuLimit && limits.CPUSeconds > 0:
+		return fmt.Errorf("server %s was killed after exceeding its CPU time limit of %ds",
+			name, limits.CPUSeconds)
+	case exit.memoryKill && limits.MemoryMB > 0:
+		return fmt.Errorf("server %s %s after exceeding its memory limit of %d MB",
+			name, exit.reason, limits.MemoryMB)
+	case exit.abnormal && limits.OpenFiles > 0:
+		return fmt.Errorf("server %s %s; it may have run out of its %d open files",
+			name, exit.reason, limits.OpenFiles)
+	}
+	return nil
+}
+
+// processExit summarizes how a server process ended
+type processExit struct {
+	reason     string // e.g. "was killed by signal 9" or "exited with status 1"
+	abnormal   bool   // killed by a signal or exited non-zero
+	cpuLimit   bool   // killed by SIGXCPU
+	memoryKill bool   // its memory scope was stopped by the OOM killer
+}
+
+// watchServerExit reports a server that is killed by one of its limits
+func watchServerExit(name string, limits *ResourceLimits, cmd *exec.Cmd, hooks *clientHooks) {
+	if limits == nil {
+		return
+	}
+	unit := scopeUnit(cmd.Args)
+	watchExit(cmd.Process.Pid, func(exit processExit) {
+		if unit != "" {
+			exit.memoryKill = scopeOOMKilled(unit)
+		}
+		err := describeLimitExit(name, limits, exit)
+		if err == nil {
+			return
+		}
+		slog.Error("server stopped", "name", name, "error", err)
+		if hooks != nil && hooks.exited != nil {
+			hooks.exited(name, err)
+		}
+	})
+}
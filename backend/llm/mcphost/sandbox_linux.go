@@ -0,0 +1,114 @@
+//go:build linux
+
+package mcphost
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// scopeResultTimeout bounds the wait for systemd to settle a memory scope
+const scopeResultTimeout = 2 * time.Second
+
+// watchExit calls done once the process ends. It waits with WNOWAIT so the
+// process stays reapable by the transport, which calls Wait on Close.
+func watchExit(pid int, done func(processExit)) {
+	go func() {
+		var info unix.Siginfo
+		for {
+			err := unix.Waitid(unix.P_PID, pid, &info, unix.WEXITED|unix.WNOWAIT, nil)
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if err != nil {
+				return
+			}
+			break
+		}
+
+		status, ok := zombieStatus(pid)
+		if !ok {
+			// already reaped, so the transport closed it on purpose
+			return
+		}
+		done(exitFromStatus(status))
+	}()
+}
+
+// zombieStatus reads the wait status of an exited, not yet reaped process
+// from the exit_code field (52) of /proc/<pid>/stat
+func zombieStatus(pid int) (syscall.WaitStatus, bool) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, false
+	}
+	// the command name may contain spaces; fields after it start at 3
+	end := strings.LastIndexByte(string(data), ')')
+	if end < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 50 {
+		return 0, false
+	}
+	code, err := strconv.Atoi(fields[49])
+	if err != nil {
+		return 0, false
+	}
+	return syscall.WaitStatus(code), true
+}
+
+// exitFromStatus summarizes a wait status
+func exitFromStatus(status syscall.WaitStatus) processExit {
+	if status.Signaled() {
+		return processExit{
+			reason:   fmt.Sprintf("was killed by signal %d (%s)", int(status.Signal()), status.Signal()),
+			abnormal: true,
+			cpuLimit: status.Signal() == syscall.SIGXCPU,
+		}
+	}
+	return processExit{
+		reason:   fmt.Sprintf("exited with status %d", status.ExitStatus()),
+		abnormal: status.ExitStatus() != 0,
+	}
+}
+
+// scopeOOMKilled reports whether systemd stopped a memory scope because the
+// OOM killer killed a process in it, and then clears the failed unit. It
+// waits for systemd to notice the scope emptied.
+func scopeOOMKilled(unit string) bool {
+	defer exec.Command("systemctl", "--user", "reset-failed", unit).Run()
+
+	deadline := time.Now().Add(scopeResultTimeout)
+	for {
+		out, err := exec.Command("systemctl", "--user", "show", "--property=ActiveState,Result", unit).Output()
+		if err != nil {
+			return false
+		}
+		props := map[string]string{}
+		for _, line := range strings.Split(string(out), "\n") {
+			if key, value, ok := strings.Cut(line, "="); ok {
+				props[key] = value
+			}
+		}
+		state := props["ActiveState"]
+		if (state != "active" && state != "deactivating") || time.Now().After(deadline) {
+			return props["Result"] == "oom-kill"
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// detachSession starts cmd in its own session so an interactive shell
+// cannot grab the terminal we were started from
+func detachSession(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
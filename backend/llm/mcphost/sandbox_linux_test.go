@@ -0,0 +1,38 @@
+//go:build linux
+
+package mcphost
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCPULimitKillIsReported(t *testing.T) {
+	limits := &ResourceLimits{CPUSeconds: 1}
+	cmd, err := buildCommand(context.Background(), ExecOptions{Limits: limits}, nil, "/bin/sh", nil,
+		[]string{"-c", "while :; do :; done"})
+	if err != nil {
+		t.Fatalf("buildCommand: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	defer cmd.Wait()
+
+	exits := make(chan error, 1)
+	watchServerExit("spin", limits, cmd, &clientHooks{
+		exited: func(name string, err error) { exits <- err },
+	})
+
+	select {
+	case err := <-exits:
+		if !strings.Contains(err.Error(), "server spin was killed after exceeding its CPU time limit of 1s") {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("Expected the server to be killed by its CPU limit")
+	}
+}
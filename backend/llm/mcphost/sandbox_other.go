@@ -0,0 +1,15 @@
+//go:build !linux
+
+package mcphost
+
+import "os/exec"
+
+// watchExit is a no-op where exit statuses cannot be observed without
+// reaping the process; limit kills then surface as plain transport errors
+func watchExit(pid int, done func(processExit)) {}
+
+// scopeOOMKilled is never asked for; memory limits need Linux
+func scopeOOMKilled(unit string) bool { return false }
+
+// detachSession is a no-op where sessions cannot be set up portably
+func detachSession(cmd *exec.Cmd) {}
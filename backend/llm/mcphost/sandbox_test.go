@@ -0,0 +1,190 @@
+package mcphost
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStdioConfigExecOptions(t *testing.T) {
+	var server ServerConfigWrapper
+	data := `{"command": "npx", "args": ["server"], "workingDir": "/srv",
+		"scrubEnv": true, "limits": {"cpuSeconds": 10, "memoryMB": 512},
+		"sandbox": {"writablePaths": ["/srv"]}}`
+	if err := json.Unmarshal([]byte(data), &server); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	cfg := server.Config.(STDIOServerConfig)
+	if cfg.WorkingDir != "/srv" || !cfg.ScrubEnv || cfg.Limits.MemoryMB != 512 {
+		t.Errorf("Unexpected exec options: %+v", cfg.ExecOptions)
+	}
+	if cfg.Sandbox == nil || cfg.Sandbox.WritablePaths[0] != "/srv" {
+		t.Errorf("Expected a sandbox with /srv writable, got %+v", cfg.Sandbox)
+	}
+}
+
+func TestBuildCommandEnvironment(t *testing.T) {
+	t.Setenv("SPOT_TEST_PARENT", "leaked")
+
+	cmd, err := buildCommand(context.Background(), ExecOptions{WorkingDir: "/tmp"}, nil, "server", []string{"TOKEN=abc"}, nil)
+	if err != nil {
+		t.Fatalf("buildCommand: %v", err)
+	}
+	if !slices.Contains(cmd.Env, "SPOT_TEST_PARENT=leaked") || !slices.Contains(cmd.Env, "TOKEN=abc") {
+		t.Errorf("Expected the parent environment plus the server env")
+	}
+	if cmd.Dir != "/tmp" {
+		t.Errorf("Expected working directory /tmp, got %s", cmd.Dir)
+	}
+
+	cmd, err = buildCommand(context.Background(), ExecOptions{ScrubEnv: true}, nil, "server", []string{"TOKEN=abc"}, nil)
+	if err != nil {
+		t.Fatalf("buildCommand: %v", err)
+	}
+	if slices.Contains(cmd.Env, "SPOT_TEST_PARENT=leaked") {
+		t.Errorf("Expected a scrubbed environment, got %v", cmd.Env)
+	}
+	if !slices.Contains(cmd.Env, "TOKEN=abc") {
+		t.Errorf("Expected the server env to survive scrubbing, got %v", cmd.Env)
+	}
+}
+
+func TestBuildCommandLimits(t *testing.T) {
+	opts := ExecOptions{Limits: &ResourceLimits{CPUSeconds: 5, OpenFiles: 64}}
+	cmd, err := buildCommand(context.Background(), opts, nil, "server", nil, []string{"--flag", "two words"})
+	if err != nil {
+		t.Fatalf("buildCommand: %v", err)
+	}
+
+	expected := []string{"/bin/sh", "-c",
+		`ulimit -t 6 && ulimit -S -t 5 && ulimit -n 64 && exec "$0" "$@"`,
+		"server", "--flag", "two words"}
+	if !slices.Equal(cmd.Args, expected) {
+		t.Errorf("Expected %q, got %q", expected, cmd.Args)
+	}
+}
+
+func TestBuildCommandMemoryLimit(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("memory limits need Linux")
+	}
+	opts := ExecOptions{Limits: &ResourceLimits{MemoryMB: 256}}
+	cmd, err := buildCommand(context.Background(), opts, nil, "server", nil, []string{"--flag"})
+	if _, lookErr := exec.LookPath("systemd-run"); lookErr != nil {
+		if err == nil || !strings.Contains(err.Error(), "systemd-run") {
+			t.Errorf("Expected an error naming systemd-run, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("buildCommand: %v", err)
+	}
+
+	unit := scopeUnit(cmd.Args)
+	if !strings.HasPrefix(unit, "spotlight-mcp-") || !strings.HasSuffix(unit, ".scope") {
+		t.Fatalf("Expected a named scope, got %q in %q", unit, cmd.Args)
+	}
+	expected := append(memoryScopeArgs(strings.TrimSuffix(unit, ".scope"), 256), "server", "--flag")
+	if !slices.Equal(cmd.Args[1:], expected) || !slices.Contains(expected, "MemoryMax=256M") {
+		t.Errorf("Expected systemd-run %q, got %q", expected, cmd.Args)
+	}
+	if scopeUnit([]string{"server", "--unit=x"}) != "" {
+		t.Errorf("Expected no scope for a command without a memory limit")
+	}
+}
+
+func TestSandboxArgs(t *testing.T) {
+	args := sandboxArgs(SandboxOptions{
+		ReadOnlyPaths: []string{"/data"},
+		WritablePaths: []string{"/work"},
+	}, "/work", "server")
+
+	joined := strings.Join(args, " ")
+	for _, want := range []string{"--unshare-all", "--ro-bind /data /data", "--bind /work /work", "--chdir /work"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in %s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "--share-net") {
+		t.Errorf("Expected no network unless allowed")
+	}
+	if !strings.HasSuffix(joined, "-- server") {
+		t.Errorf("Expected the command last, got %s", joined)
+	}
+}
+
+func TestSandboxBindsResolvedCommand(t *testing.T) {
+	// nvm's layout: bin/npx links into the npm package under lib
+	home := t.TempDir()
+	root := filepath.Join(home, "nvm", "versions", "node", "v20")
+	npm := filepath.Join(root, "lib", "node_modules", "npm", "bin")
+	for _, dir := range []string{filepath.Join(root, "bin"), npm} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(npm, "npx-cli.js"), nil, 0755); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	command := filepath.Join(root, "bin", "npx")
+	if err := os.Symlink(filepath.Join(npm, "npx-cli.js"), command); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	if dirs := commandDirs(command, ""); !slices.Equal(dirs, []string{root}) {
+		t.Errorf("Expected the whole installation %s, got %v", root, dirs)
+	}
+	joined := strings.Join(sandboxArgs(SandboxOptions{}, "", command), " ")
+	if !strings.Contains(joined, "--ro-bind "+root+" "+root) {
+		t.Errorf("Expected %s to be bound in %s", root, joined)
+	}
+
+	// directories the sandbox already shows are not bound again
+	joined = strings.Join(sandboxArgs(SandboxOptions{WritablePaths: []string{home}}, "", command), " ")
+	if strings.Contains(joined, "--ro-bind "+root) {
+		t.Errorf("Expected no extra bind under a writable path, got %s", joined)
+	}
+	if !sandboxMounts(SandboxOptions{}, "/usr/bin") || sandboxMounts(SandboxOptions{}, "/usrlocal") {
+		t.Errorf("Expected only /usr and what is below it to be mounted already")
+	}
+
+	// a bin directory right in the home directory does not expose all of it
+	userHome, _ := os.UserHomeDir()
+	if got := installRoot(filepath.Join(userHome, "bin")); got != filepath.Join(userHome, "bin") {
+		t.Errorf("Expected ~/bin to stay as is, got %s", got)
+	}
+}
+
+func TestDescribeLimitExit(t *testing.T) {
+	limits := &ResourceLimits{CPUSeconds: 10, MemoryMB: 512}
+	killed := processExit{reason: "was killed by signal 9 (killed)", abnormal: true, memoryKill: true}
+
+	if err := describeLimitExit("fs", limits, processExit{abnormal: true, cpuLimit: true}); err == nil ||
+		!strings.Contains(err.Error(), "CPU time limit of 10s") {
+		t.Errorf("Expected a CPU limit error, got %v", err)
+	}
+	if err := describeLimitExit("fs", limits, killed); err == nil || !strings.Contains(err.Error(), "memory limit of 512 MB") {
+		t.Errorf("Expected a memory limit error, got %v", err)
+	}
+	// a crash the scope did not record as an OOM kill is not blamed on memory
+	crashed := processExit{reason: "was killed by signal 11 (segmentation fault)", abnormal: true}
+	if err := describeLimitExit("fs", limits, crashed); err != nil {
+		t.Errorf("Expected a crash not to be blamed on the memory limit, got %v", err)
+	}
+	if err := describeLimitExit("fs", &ResourceLimits{CPUSeconds: 10}, killed); err != nil {
+		t.Errorf("Expected no memory error without a memory limit, got %v", err)
+	}
+	if err := describeLimitExit("fs", limits, processExit{reason: "exited with status 0"}); err != nil {
+		t.Errorf("Expected a clean exit to be ignored, got %v", err)
+	}
+	if err := describeLimitExit("fs", nil, killed); err != nil {
+		t.Errorf("Expected no error without limits, got %v", err)
+	}
+}
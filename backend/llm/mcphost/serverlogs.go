@@ -0,0 +1,270 @@
+package mcphost
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	logFileMaxSize = 1 << 20 // rotate a server's log file after 1 MB
+	logFileBackups = 3       // rotated files kept next to the current one
+
+	// stderrDrainTimeout bounds the wait for stderr of a server that failed to initialize
+	stderrDrainTimeout = 500 * time.Millisecond
+)
+
+// Sources of a ServerLogEntry
+const (
+	LogSourceStderr       = "stderr"
+	LogSourceNotification = "notification"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// rotatingFile appends lines to a file and rotates it when it grows too big:
+// name.log becomes name.log.1, name.log.1 becomes name.log.2 and so on
+type rotatingFile struct {
+	path string
+	file *os.File
+	size int64
+}
+
+func (f *rotatingFile) writeLine(line string) error {
+	if f.file == nil {
+		if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return err
+		}
+		f.file, f.size = file, info.Size()
+	}
+
+	if f.size > 0 && f.size+int64(len(line))+1 > logFileMaxSize {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+		return f.writeLine(line)
+	}
+
+	n, err := f.file.WriteString(line + "\n")
+	f.size += int64(n)
+	return err
+}
+
+func (f *rotatingFile) rotate() error {
+	f.close()
+	for i := logFileBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+	}
+	return os.Rename(f.path, f.path+".1")
+}
+
+// remove deletes the file and its rotated copies
+func (f *rotatingFile) remove() error {
+	f.close()
+	paths := []string{f.path}
+	for i := 1; i <= logFileBackups; i++ {
+		paths = append(paths, fmt.Sprintf("%s.%d", f.path, i))
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *rotatingFile) close() {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+}
+
+// readEntries parses the file and its rotated copies back into records,
+// oldest first. Lines that do not start a record continue the previous one.
+func (f *rotatingFile) readEntries(server string) ([]ServerLogEntry, error) {
+	var entries []ServerLogEntry
+	for i := logFileBackups; i >= 0; i-- {
+		path := f.path
+		if i > 0 {
+			path = fmt.Sprintf("%s.%d", f.path, i)
+		}
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), logFileMaxSize)
+		for scanner.Scan() {
+			entry, ok := parseLogLine(server, scanner.Text())
+			if !ok {
+				if n := len(entries); n > 0 {
+					entries[n-1].Message += "\n" + scanner.Text()
+				}
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// formatLogLine renders an entry for the log file. A logger without a level
+// is written with level "-" so parseLogLine can tell them apart.
+func formatLogLine(entry ServerLogEntry) string {
+	label := entry.Source
+	if entry.Level != "" {
+		label += " " + entry.Level
+	} else if entry.Logger != "" {
+		label += " -"
+	}
+	if entry.Logger != "" {
+		label += " " + entry.Logger
+	}
+	return fmt.Sprintf("%s [%s] %s", entry.Timestamp.Format(time.RFC3339), label, entry.Message)
+}
+
+// parseLogLine reverses formatLogLine
+func parseLogLine(server, line string) (ServerLogEntry, bool) {
+	stamp, rest, ok := strings.Cut(line, " [")
+	if !ok {
+		return ServerLogEntry{}, false
+	}
+	timestamp, err := time.Parse(time.RFC3339, stamp)
+	if err != nil {
+		return ServerLogEntry{}, false
+	}
+	label, message, ok := strings.Cut(rest, "] ")
+	if !ok {
+		return ServerLogEntry{}, false
+	}
+	source, label, _ := strings.Cut(label, " ")
+	if source != LogSourceStderr && source != LogSourceNotification {
+		return ServerLogEntry{}, false
+	}
+
+	entry := ServerLogEntry{Server: server, Source: source, Message: message, Timestamp: timestamp}
+	level, logger, _ := strings.Cut(label, " ")
+	if level != "-" {
+		entry.Level = level
+	}
+	entry.Logger = logger
+	return entry, true
+}
+
+// logFileName returns the log file of a server. Characters unsafe in file
+// names are replaced, so a short hash of the name keeps e.g. a/b and a_b apart.
+func logFileName(server string) string {
+	sum := sha256.Sum256([]byte(server))
+	return unsafeFileChars.ReplaceAllString(server, "_") + "-" + hex.EncodeToString(sum[:4]) + ".log"
+}
+
+// serverLogBuffer returns the log buffer of a server, creating it on first use
+func (s *MCPService) serverLogBuffer(server string) *serverLogBuffer {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+
+	if s.serverLogs == nil {
+		s.serverLogs = make(map[string]*serverLogBuffer)
+	}
+	buf, ok := s.serverLogs[server]
+	if !ok {
+		buf = newServerLogBuffer(serverLogCapacity)
+		if s.settings != nil && s.settings.LogDir != "" {
+			buf.file = &rotatingFile{path: filepath.Join(s.settings.LogDir, logFileName(server))}
+		}
+		s.serverLogs[server] = buf
+	}
+	return buf
+}
+
+// closeServerLogs closes the log files of every server. Records written
+// afterwards reopen them, so nothing holds a file of a removed server.
+func (s *MCPService) closeServerLogs() {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+
+	for _, buf := range s.serverLogs {
+		buf.closeFile()
+	}
+}
+
+// captureStderr records every line a server writes to stderr. Reading also
+// keeps a chatty server from blocking on a full pipe.
+func captureStderr(name string, stderr io.Reader, hooks *clientHooks) {
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if hooks != nil && hooks.stderr != nil {
+			hooks.stderr(name, scanner.Text())
+		} else {
+			slog.Debug("server stderr", "name", name, "line", scanner.Text())
+		}
+	}
+}
+
+// appendStderr stores a line a server wrote to stderr
+func (s *MCPService) appendStderr(server, line string) {
+	s.serverLogBuffer(server).append(ServerLogEntry{
+		Server:    server,
+		Source:    LogSourceStderr,
+		Message:   line,
+		Timestamp: time.Now(),
+	})
+}
+
+// TailServerLogs returns the last n log records of a server, oldest first,
+// including those only left in its log files; n <= 0 returns everything
+func (s *MCPService) TailServerLogs(server string, n int) []ServerLogEntry {
+	entries := s.serverLogBuffer(server).records(server)
+	if n > 0 && len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return entries
+}
+
+// SearchServerLogs returns the records of a server, buffered or in its log
+// files, that contain query, ignoring case
+func (s *MCPService) SearchServerLogs(server, query string) []ServerLogEntry {
+	query = strings.ToLower(query)
+	matches := []ServerLogEntry{}
+	for _, entry := range s.serverLogBuffer(server).records(server) {
+		if strings.Contains(strings.ToLower(entry.Message), query) ||
+			strings.Contains(strings.ToLower(entry.Logger), query) ||
+			strings.Contains(strings.ToLower(entry.Level), query) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// ClearServerLogs drops the buffered records of a server and deletes its log files
+func (s *MCPService) ClearServerLogs(server string) error {
+	return s.serverLogBuffer(server).clear()
+}
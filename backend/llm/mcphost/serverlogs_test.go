@@ -0,0 +1,109 @@
+package mcphost
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStderrOfFailedServerIsKept(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	svc := newTestService()
+
+	server := ServerConfigWrapper{Config: STDIOServerConfig{
+		Command: "/bin/sh",
+		Args:    []string{"-c", "echo 'missing API_KEY' >&2; exit 1"},
+	}}
+	if _, err := createMCPClient("broken", server, &clientHooks{stderr: svc.appendStderr}); err == nil {
+		t.Fatal("Expected the server to fail to initialize")
+	}
+
+	logs := svc.TailServerLogs("broken", 0)
+	if len(logs) != 1 || logs[0].Message != "missing API_KEY" || logs[0].Source != LogSourceStderr {
+		t.Errorf("Expected the server's stderr in its logs, got %+v", logs)
+	}
+}
+
+func TestTailAndSearchServerLogs(t *testing.T) {
+	svc := newTestService()
+	svc.appendStderr("fs", "starting")
+	svc.appendServerLog("fs", map[string]interface{}{"level": "error", "data": "Permission denied: /etc"})
+	svc.appendStderr("fs", "retrying")
+
+	if tail := svc.TailServerLogs("fs", 2); len(tail) != 2 || tail[1].Message != "retrying" {
+		t.Errorf("Expected the last two records, got %+v", tail)
+	}
+	if matches := svc.SearchServerLogs("fs", "permission"); len(matches) != 1 || matches[0].Level != "error" {
+		t.Errorf("Expected one match, got %+v", matches)
+	}
+	if matches := svc.SearchServerLogs("fs", "nothing like this"); len(matches) != 0 {
+		t.Errorf("Expected no matches, got %+v", matches)
+	}
+}
+
+func TestServerLogFilesRotateAndClear(t *testing.T) {
+	dir := t.TempDir()
+	svc := newTestService()
+	svc.settings = &MCPSettings{LogDir: dir}
+
+	line := strings.Repeat("x", 1024)
+	for i := 0; i < logFileMaxSize/len(line)+10; i++ {
+		svc.appendStderr("my server", line)
+	}
+
+	if logFileName("a/b") == logFileName("a_b") {
+		t.Errorf("Expected a/b and a_b to log to different files")
+	}
+	current := filepath.Join(dir, logFileName("my server"))
+	for _, path := range []string{current, current + ".1"} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", path, err)
+		}
+		if info.Size() > logFileMaxSize {
+			t.Errorf("Expected %s to stay under %d bytes, got %d", path, logFileMaxSize, info.Size())
+		}
+	}
+
+	if err := svc.ClearServerLogs("my server"); err != nil {
+		t.Fatalf("ClearServerLogs: %v", err)
+	}
+	if _, err := os.Stat(current + ".1"); !os.IsNotExist(err) {
+		t.Errorf("Expected rotated files to be removed, got %v", err)
+	}
+	if logs := svc.TailServerLogs("my server", 0); len(logs) != 0 {
+		t.Errorf("Expected no buffered records, got %d", len(logs))
+	}
+}
+
+func TestServerLogsAreReadFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	svc := newTestService()
+	svc.settings = &MCPSettings{LogDir: dir}
+
+	svc.appendStderr("fs", "Permission denied: /etc")
+	svc.appendServerLog("fs", map[string]interface{}{"logger": "watcher", "data": "multi\nline"})
+	for i := 0; i < serverLogCapacity; i++ {
+		svc.appendStderr("fs", "noise")
+	}
+	svc.closeServerLogs()
+
+	// a new service, e.g. after a restart, still finds what left the ring
+	restarted := newTestService()
+	restarted.settings = &MCPSettings{LogDir: dir}
+	if tail := restarted.TailServerLogs("fs", 0); len(tail) != serverLogCapacity+2 {
+		t.Errorf("Expected %d records, got %d", serverLogCapacity+2, len(tail))
+	}
+	matches := restarted.SearchServerLogs("fs", "permission")
+	if len(matches) != 1 || matches[0].Source != LogSourceStderr || matches[0].Server != "fs" {
+		t.Errorf("Expected the evicted stderr line, got %+v", matches)
+	}
+	matches = restarted.SearchServerLogs("fs", "watcher")
+	if len(matches) != 1 || matches[0].Level != "" || matches[0].Message != "multi\nline" {
+		t.Errorf("Expected the multi-line notification, got %+v", matches)
+	}
+}
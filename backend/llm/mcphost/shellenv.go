@@ -0,0 +1,162 @@
+package mcphost
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	loginShellTimeout = 10 * time.Second
+	// envMarker fences the env output from whatever shell profiles print
+	envMarker = "__SPOT_AI_ENV__"
+)
+
+// shellArtifacts are variables describing the probing shell itself
+var shellArtifacts = map[string]bool{"_": true, "PWD": true, "OLDPWD": true, "SHLVL": true}
+
+var envLineRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// LoginShellEnv returns the environment of the user's login shell. Apps
+// started from a desktop launcher miss the PATH entries shell profiles add
+// (nvm, pyenv, uv, ...), so stdio servers start from this environment.
+func LoginShellEnv(ctx context.Context) ([]string, error) {
+	if runtime.GOOS == "windows" {
+		return os.Environ(), nil
+	}
+
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	args := []string{"-l", "-c"}
+	switch filepath.Base(shell) {
+	case "bash", "zsh", "fish":
+		// nvm and friends are usually set up in the interactive rc files
+		args = []string{"-l", "-i", "-c"}
+	}
+	script := fmt.Sprintf("printf '%%s\\n' %s; env; printf '%%s\\n' %s", envMarker, envMarker)
+
+	ctx, cancel := context.WithTimeout(ctx, loginShellTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, shell, append(args, script)...)
+	detachSession(cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read environment of %s: %w", shell, err)
+	}
+	return parseShellEnv(string(out))
+}
+
+// parseShellEnv extracts the variables printed between the markers. Lines
+// that do not start a new variable continue a multi-line value.
+func parseShellEnv(out string) ([]string, error) {
+	start := strings.Index(out, envMarker+"\n")
+	end := strings.LastIndex(out, envMarker)
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("login shell did not print its environment")
+	}
+
+	var env []string
+	for _, line := range strings.Split(out[start+len(envMarker)+1:end], "\n") {
+		switch {
+		case envLineRe.MatchString(line):
+			env = append(env, line)
+		case len(env) > 0:
+			env[len(env)-1] += "\n" + line
+		}
+	}
+
+	kept := env[:0]
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if !shellArtifacts[name] {
+			kept = append(kept, strings.TrimSuffix(kv, "\n"))
+		}
+	}
+	return kept, nil
+}
+
+// envValue returns the last value of a variable in a KEY=value list
+func envValue(env []string, key string) string {
+	value := ""
+	for _, kv := range env {
+		if name, v, ok := strings.Cut(kv, "="); ok && name == key {
+			value = v
+		}
+	}
+	return value
+}
+
+// resolveCommand finds the executable a server command refers to, searching
+// pathEnv instead of our own PATH. Relative paths are taken from dir.
+func resolveCommand(command, pathEnv, dir string) (string, error) {
+	if command == "" {
+		return "", fmt.Errorf("no command configured")
+	}
+
+	if strings.ContainsRune(command, '/') || strings.ContainsRune(command, os.PathSeparator) {
+		path := command
+		if !filepath.IsAbs(path) && dir != "" {
+			path = filepath.Join(dir, path)
+		}
+		if err := checkExecutable(path); err != nil {
+			return "", fmt.Errorf("%s cannot be run: %w", command, err)
+		}
+		return command, nil
+	}
+
+	dirs := filepath.SplitList(pathEnv)
+	for _, d := range dirs {
+		if d == "" {
+			d = "."
+		}
+		for _, candidate := range executableNames(command) {
+			path := filepath.Join(d, candidate)
+			if checkExecutable(path) == nil {
+				return path, nil
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		return "", fmt.Errorf("%s not found; PATH is empty", command)
+	}
+	return "", fmt.Errorf("%s not found; looked in %s", command, strings.Join(dirs, ", "))
+}
+
+// executableNames returns the file names a command may have on disk
+func executableNames(command string) []string {
+	if runtime.GOOS != "windows" || filepath.Ext(command) != "" {
+		return []string{command}
+	}
+	names := []string{}
+	for _, ext := range filepath.SplitList(os.Getenv("PATHEXT")) {
+		names = append(names, command+strings.ToLower(ext))
+	}
+	if len(names) == 0 {
+		names = []string{command + ".exe", command + ".cmd", command + ".bat"}
+	}
+	return names
+}
+
+// checkExecutable reports why path cannot be executed, if it cannot
+func checkExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+		return fmt.Errorf("%s is not executable", path)
+	}
+	return nil
+}
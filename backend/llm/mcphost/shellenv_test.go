@@ -0,0 +1,92 @@
+package mcphost
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseShellEnv(t *testing.T) {
+	out := "Welcome back!\n" + envMarker + "\n" +
+		"PATH=/home/me/.nvm/versions/node/v20/bin:/usr/bin\n" +
+		"MULTI=first\nsecond line\n" +
+		"SHLVL=2\n" +
+		envMarker + "\n"
+
+	env, err := parseShellEnv(out)
+	if err != nil {
+		t.Fatalf("parseShellEnv: %v", err)
+	}
+	expected := []string{"PATH=/home/me/.nvm/versions/node/v20/bin:/usr/bin", "MULTI=first\nsecond line"}
+	if !slices.Equal(env, expected) {
+		t.Errorf("Expected %q, got %q", expected, env)
+	}
+
+	if _, err := parseShellEnv("command not found\n"); err == nil {
+		t.Errorf("Expected an error when the markers are missing")
+	}
+}
+
+func TestResolveCommand(t *testing.T) {
+	dir := t.TempDir()
+	npx := filepath.Join(dir, "npx")
+	if err := os.WriteFile(npx, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write command: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes"), []byte("text"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	path := strings.Join([]string{"/nonexistent", dir}, string(os.PathListSeparator))
+	if got, err := resolveCommand("npx", path, ""); err != nil || got != npx {
+		t.Errorf("Expected %s, got %s (%v)", npx, got, err)
+	}
+
+	_, err := resolveCommand("uvx", path, "")
+	if err == nil || err.Error() != "uvx not found; looked in /nonexistent, "+dir {
+		t.Errorf("Expected a not found diagnostic, got %v", err)
+	}
+
+	if _, err := resolveCommand("./notes", "", dir); err == nil || !strings.Contains(err.Error(), "not executable") {
+		t.Errorf("Expected a not executable diagnostic, got %v", err)
+	}
+}
+
+func TestServerBaseEnvUsesLoginShell(t *testing.T) {
+	login := []string{"PATH=/home/me/.local/bin:/usr/bin", "HOME=/home/me", "NVM_DIR=/home/me/.nvm"}
+
+	if env := serverBaseEnv(login, false); !slices.Equal(env, login) {
+		t.Errorf("Expected the login environment, got %v", env)
+	}
+	if env := serverBaseEnv(login, true); !slices.Equal(env, login[:2]) {
+		t.Errorf("Expected only PATH and HOME after scrubbing, got %v", env)
+	}
+}
+
+func TestMissingCommandIsReported(t *testing.T) {
+	var config MCPConfig
+	data := `{"mcpServers": {"everything": {"command": "npx", "args": ["-y", "server-everything"]}}}`
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	var statuses []ServerStatus
+	clients, err := createMCPClients(&config, &clientHooks{
+		baseEnv: []string{"PATH=/nonexistent"},
+		status:  func(status ServerStatus) { statuses = append(statuses, status) },
+	})
+	if err != nil {
+		t.Fatalf("Expected the server to be skipped, got %v", err)
+	}
+	if len(clients) != 0 {
+		t.Errorf("Expected no clients, got %d", len(clients))
+	}
+
+	expected := []ServerStatus{{Name: "everything", State: ServerFailed, Error: "npx not found; looked in /nonexistent"}}
+	if !slices.Equal(statuses, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, statuses)
+	}
+}
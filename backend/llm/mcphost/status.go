@@ -0,0 +1,65 @@
+package mcphost
+
+import (
+	"errors"
+	"sort"
+)
+
+// Server states reported by ServerStatuses
+const (
+	ServerRunning   = "running"
+	ServerFailed    = "failed"
+	ServerNeedsAuth = "needs_authorization"
+	ServerStopped   = "stopped"
+)
+
+// ServerStatus tells the frontend whether a server came up and, if not, why
+type ServerStatus struct {
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	Error   string `json:"error,omitempty"`
+	Command string `json:"command,omitempty"` // executable a stdio server was resolved to
+}
+
+// setServerStatus records the latest status of a server
+func (s *MCPService) setServerStatus(status ServerStatus) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	if s.serverStatus == nil {
+		s.serverStatus = make(map[string]ServerStatus)
+	}
+	s.serverStatus[status.Name] = status
+}
+
+// ServerStatuses returns the status of every configured server, by name
+func (s *MCPService) ServerStatuses() []ServerStatus {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	statuses := make([]ServerStatus, 0, len(s.serverStatus))
+	for _, status := range s.serverStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// recordServerExit remembers why a server died so failing tool calls can say so
+func (s *MCPService) recordServerExit(name string, err error) {
+	s.statusMu.Lock()
+	status := s.serverStatus[name]
+	s.statusMu.Unlock()
+
+	status.Name, status.State, status.Error = name, ServerStopped, err.Error()
+	s.setServerStatus(status)
+}
+
+// serverExit returns why a server was killed, or nil while it is running
+func (s *MCPService) serverExit(name string) error {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	if status := s.serverStatus[name]; status.State == ServerStopped {
+		return errors.New(status.Error)
+	}
+	return nil
+}
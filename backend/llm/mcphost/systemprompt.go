@@ -0,0 +1,179 @@
+package mcphost
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// DefaultPromptTemplate is the system prompt used when MCPSettings.PromptTemplate is empty
+const DefaultPromptTemplate = `{{if .Persona}}{{.Persona}}
+
+{{end}}Today is {{.Date}} ({{.Timezone}}).
+The user is on {{.OS}}{{if .Locale}} with locale {{.Locale}}{{end}}.
+{{range .Servers}}{{if .Instructions}}
+Instructions from the {{.Name}} server:
+{{.Instructions}}
+{{end}}{{end}}`
+
+// PromptServer is what a prompt template sees of a connected server
+type PromptServer struct {
+	Name         string // name in the MCP config
+	Title        string // name the server reported for itself
+	Version      string
+	Instructions string
+}
+
+// PromptContext is the data a prompt template is executed with
+type PromptContext struct {
+	Persona  string
+	Date     string // e.g. Monday, 2 January 2006
+	Time     string // e.g. 15:04; changes every minute, so a prompt using it is not cached
+	Timezone string // e.g. CET (UTC+01:00)
+	OS       string
+	Locale   string // from LC_ALL, LC_MESSAGES or LANG; empty if unset
+	Servers  []PromptServer
+}
+
+// recordServerInfo keeps what a server reported on initialize for the system prompt
+func (s *MCPService) recordServerInfo(name string, info ServerInfo) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	if s.serverInit == nil {
+		s.serverInit = make(map[string]ServerInfo)
+	}
+	s.serverInit[name] = info
+}
+
+// promptServers lists the connected servers by name
+func (s *MCPService) promptServers() []PromptServer {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	servers := make([]PromptServer, 0, len(s.serverInit))
+	for name, info := range s.serverInit {
+		servers = append(servers, PromptServer{
+			Name:         name,
+			Title:        info.Name,
+			Version:      info.Version,
+			Instructions: strings.TrimSpace(info.Instructions),
+		})
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+	return servers
+}
+
+// promptContext gathers the data for the system prompt at time now
+func (s *MCPService) promptContext(now time.Time) PromptContext {
+	zone, offset := now.Zone()
+	ctx := PromptContext{
+		Date:     now.Format("Monday, 2 January 2006"),
+		Time:     now.Format("15:04"),
+		Timezone: fmt.Sprintf("%s (UTC%s)", zone, formatOffset(offset)),
+		OS:       runtime.GOOS,
+		Servers:  s.promptServers(),
+	}
+
+	persona, _ := s.promptSettings()
+	ctx.Persona = strings.TrimSpace(persona)
+	var env []string
+	if s.settings != nil {
+		env = s.settings.BaseEnv
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	ctx.Locale = userLocale(env)
+	return ctx
+}
+
+// BuildSystemPrompt renders the prompt template for a request made at now
+func (s *MCPService) BuildSystemPrompt(now time.Time) (string, error) {
+	text := DefaultPromptTemplate
+	if _, promptTemplate := s.promptSettings(); promptTemplate != "" {
+		text = promptTemplate
+	}
+
+	tmpl, err := template.New("system prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, s.promptContext(now)); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// PreviewSystemPrompt returns the system prompt the next request would be sent with
+func (s *MCPService) PreviewSystemPrompt() (string, error) {
+	return s.BuildSystemPrompt(time.Now())
+}
+
+// SetSystemPrompt replaces the persona and the prompt template; the next
+// prompt is sent with them
+func (s *MCPService) SetSystemPrompt(persona, promptTemplate string) {
+	s.providerMu.Lock()
+	defer s.providerMu.Unlock()
+	s.settings.SystemPrompt = persona
+	s.settings.PromptTemplate = promptTemplate
+}
+
+func (s *MCPService) promptSettings() (persona, promptTemplate string) {
+	if s.settings == nil {
+		return "", ""
+	}
+	s.providerMu.RLock()
+	defer s.providerMu.RUnlock()
+	return s.settings.SystemPrompt, s.settings.PromptTemplate
+}
+
+// refreshSystemPrompt hands the providers of the current prompt a prompt
+// rendered for now. A broken template falls back to the bare persona so
+// prompts keep working.
+func (s *MCPService) refreshSystemPrompt(now time.Time) {
+	prompt, err := s.BuildSystemPrompt(now)
+	if err != nil {
+		s.logger.Error("failed to build system prompt", "error", err)
+		prompt, _ = s.promptSettings()
+	}
+	for _, link := range s.turnChain() {
+		if setter, ok := link.provider.(models.SystemPromptSetter); ok {
+			setter.SetSystemPrompt(prompt)
+		}
+	}
+}
+
+// userLocale picks the locale the way the C library does, dropping the
+// encoding suffix: en_US.UTF-8 becomes en_US
+func userLocale(env []string) string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := envValue(env, key)
+		if value == "" {
+			continue
+		}
+		if i := strings.IndexAny(value, ".@"); i >= 0 {
+			value = value[:i]
+		}
+		if value == "C" || value == "POSIX" {
+			return ""
+		}
+		return value
+	}
+	return ""
+}
+
+// formatOffset renders a zone offset in seconds as +01:00
+func formatOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	return fmt.Sprintf("%s%02d:%02d", sign, offset/3600, offset%3600/60)
+}
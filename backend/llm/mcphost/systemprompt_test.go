@@ -0,0 +1,105 @@
+package mcphost
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type promptRecorder struct {
+	scriptedProvider
+	prompt string
+}
+
+func (p *promptRecorder) SetSystemPrompt(prompt string) { p.prompt = prompt }
+
+func TestBuildSystemPromptIncludesServerInstructions(t *testing.T) {
+	svc := newTestService()
+	svc.settings = &MCPSettings{
+		SystemPrompt: "You are terse.",
+		BaseEnv:      []string{"LANG=de_DE.UTF-8"},
+	}
+	svc.recordServerInfo("github", ServerInfo{Name: "github-mcp", Instructions: "Prefer search_code over list_files.\n"})
+	svc.recordServerInfo("time", ServerInfo{Name: "time"})
+
+	now := time.Date(2026, 3, 9, 14, 5, 0, 0, time.FixedZone("CET", 3600))
+	prompt, err := svc.BuildSystemPrompt(now)
+	if err != nil {
+		t.Fatalf("BuildSystemPrompt failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"You are terse.",
+		"Monday, 9 March 2026",
+		"(CET (UTC+01:00))",
+		"with locale de_DE",
+		"Instructions from the github server:\nPrefer search_code over list_files.",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "14:05") {
+		t.Errorf("Expected the default prompt to leave out the time of day, got:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "time server") {
+		t.Errorf("Expected servers without instructions to be left out, got:\n%s", prompt)
+	}
+}
+
+func TestBuildSystemPromptUsesCustomTemplate(t *testing.T) {
+	svc := newTestService()
+	svc.settings = &MCPSettings{
+		SystemPrompt:   "Be brief.",
+		PromptTemplate: "{{.Persona}} {{len .Servers}} servers",
+	}
+	svc.recordServerInfo("a", ServerInfo{})
+
+	prompt, err := svc.BuildSystemPrompt(time.Now())
+	if err != nil {
+		t.Fatalf("BuildSystemPrompt failed: %v", err)
+	}
+	if prompt != "Be brief. 1 servers" {
+		t.Errorf("Expected custom template output, got %q", prompt)
+	}
+
+	svc.settings.PromptTemplate = "{{.Nope}}"
+	if _, err := svc.BuildSystemPrompt(time.Now()); err == nil {
+		t.Error("Expected an error for a template using an unknown field")
+	}
+}
+
+func TestRefreshSystemPromptFallsBackToPersona(t *testing.T) {
+	provider := &promptRecorder{}
+	svc := newTestService()
+	svc.provider = provider
+	svc.settings = &MCPSettings{SystemPrompt: "Be brief.", PromptTemplate: "{{"}
+
+	svc.refreshSystemPrompt(time.Now())
+	if provider.prompt != "Be brief." {
+		t.Errorf("Expected the persona when the template is broken, got %q", provider.prompt)
+	}
+
+	svc.settings.PromptTemplate = "{{.Persona}} at {{.Time}}"
+	svc.refreshSystemPrompt(time.Date(2026, 1, 1, 9, 30, 0, 0, time.UTC))
+	if provider.prompt != "Be brief. at 09:30" {
+		t.Errorf("Expected the rendered prompt, got %q", provider.prompt)
+	}
+}
+
+func TestUserLocale(t *testing.T) {
+	tests := []struct {
+		env  []string
+		want string
+	}{
+		{[]string{"LANG=en_US.UTF-8"}, "en_US"},
+		{[]string{"LANG=en_US.UTF-8", "LC_ALL=fr_FR@euro"}, "fr_FR"},
+		{[]string{"LC_MESSAGES=C"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := userLocale(tt.env); got != tt.want {
+			t.Errorf("Expected locale %q for %v, got %q", tt.want, tt.env, got)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package mcphost
+
+import (
+	"context"
+	"testing"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+)
+
+func TestThinkingKeptAheadOfToolUse(t *testing.T) {
+	svc := newTestService()
+	svc.settings = &MCPSettings{}
+	svc.provider = &scriptedProvider{replies: []*history.HistoryMessage{
+		{
+			Role: "assistant",
+			Content: []history.ContentBlock{
+				{Type: history.BlockThinking, Text: "Search first.", Signature: "sig"},
+				{Type: history.BlockRedactedThinking, Data: "opaque"},
+				{Type: "tool_use", ID: "call-1", Name: searchToolsName, Input: []byte(`{"query": "notes"}`)},
+			},
+		},
+		answer("Done."),
+	}}
+
+	var messages []history.HistoryMessage
+	if err := svc.runLLMWithToolCycle(context.Background(), "hello", &messages); err != nil {
+		t.Fatalf("runLLMWithToolCycle: %v", err)
+	}
+
+	turn := messages[0].Content
+	if len(turn) != 3 || turn[0].Type != history.BlockThinking || turn[0].Signature != "sig" ||
+		turn[1].Type != history.BlockRedactedThinking || turn[2].Type != "tool_use" {
+		t.Errorf("Expected thinking stored ahead of the tool call, got %+v", turn)
+	}
+	if thinking := messages[0].GetThinking(); len(thinking) != 2 || thinking[1].Data != "opaque" {
+		t.Errorf("Expected the stored turn to replay its thinking, got %+v", thinking)
+	}
+
+	ev := <-svc.EventChan
+	if ev.Type != EventThinking || ev.Data != "Search first." {
+		t.Errorf("Expected a thinking event with the readable thinking, got %s: %v", ev.Type, ev.Data)
+	}
+	if ev := <-svc.EventChan; ev.Type != EventFinalResult {
+		t.Errorf("Expected %s, got %s: %v", EventFinalResult, ev.Type, ev.Data)
+	}
+}
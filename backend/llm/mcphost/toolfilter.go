@@ -0,0 +1,56 @@
+package mcphost
+
+import (
+	"path"
+)
+
+// ToolFilter limits which of a server's tools are offered to the model.
+// Patterns are globs such as "read_*". When IncludeTools is set only matching
+// tools are kept; ExcludeTools always wins over IncludeTools.
+type ToolFilter struct {
+	IncludeTools []string `json:"includeTools,omitempty"`
+	ExcludeTools []string `json:"excludeTools,omitempty"`
+}
+
+// GetToolFilter returns the tool filter of the server
+func (f ToolFilter) GetToolFilter() ToolFilter {
+	return f
+}
+
+// Allows reports whether a tool passes the filter
+func (f ToolFilter) Allows(tool string) bool {
+	if matchesAny(f.ExcludeTools, tool) {
+		return false
+	}
+	return len(f.IncludeTools) == 0 || matchesAny(f.IncludeTools, tool)
+}
+
+// matchesAny reports whether name matches one of the patterns; malformed
+// patterns never match
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// setServerFilters records the tool filter of every configured server
+func (s *MCPService) setServerFilters(config *MCPConfig) {
+	filters := make(map[string]ToolFilter, len(config.MCPServers))
+	for name, server := range config.MCPServers {
+		filters[name] = server.Config.GetToolFilter()
+	}
+
+	s.toolsMu.Lock()
+	s.serverFilters = filters
+	s.toolsMu.Unlock()
+}
+
+// toolFilter returns the tool filter of a server
+func (s *MCPService) toolFilter(server string) ToolFilter {
+	s.toolsMu.RLock()
+	defer s.toolsMu.RUnlock()
+	return s.serverFilters[server]
+}
@@ -0,0 +1,127 @@
+package mcphost
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+)
+
+func TestToolFilterAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter ToolFilter
+		tool   string
+		want   bool
+	}{
+		{"no filter", ToolFilter{}, "delete_repo", true},
+		{"included", ToolFilter{IncludeTools: []string{"read_*"}}, "read_file", true},
+		{"not included", ToolFilter{IncludeTools: []string{"read_*"}}, "write_file", false},
+		{"excluded", ToolFilter{ExcludeTools: []string{"delete_*"}}, "delete_repo", false},
+		{"exclude wins", ToolFilter{IncludeTools: []string{"*"}, ExcludeTools: []string{"delete_*"}}, "delete_repo", false},
+		{"malformed pattern", ToolFilter{IncludeTools: []string{"[read"}}, "read_file", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Allows(tt.tool); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCatalogAppliesServerFilter(t *testing.T) {
+	var config MCPConfig
+	err := json.Unmarshal([]byte(`{"mcpServers": {"gh": {
+		"command": "gh-mcp",
+		"includeTools": ["list_*", "delete_*"],
+		"excludeTools": ["delete_*"]
+	}}}`), &config)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal config: %v", err)
+	}
+
+	svc := newTestService()
+	svc.setServerFilters(&config)
+	svc.mcpClients = map[string]mcpclient.MCPClient{
+		"gh": &pagedClient{pages: [][]string{{"list_issues", "create_issue", "delete_repo"}}},
+	}
+
+	if err := svc.refreshServerTools("gh"); err != nil {
+		t.Fatalf("refreshServerTools: %v", err)
+	}
+	tools := svc.currentTools()
+	if len(tools) != 1 || tools[0].Name != "gh__list_issues" {
+		t.Errorf("Expected only gh__list_issues, got %v", tools)
+	}
+}
+
+// scriptedProvider replays canned assistant messages
+type scriptedProvider struct {
+	replies []*history.HistoryMessage
+	tools   [][]models.Tool
+}
+
+func (p *scriptedProvider) CreateMessage(ctx context.Context, prompt string, messages []models.Message, tools []models.Tool) (models.Message, error) {
+	p.tools = append(p.tools, tools)
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return reply, nil
+}
+
+func (p *scriptedProvider) CreateToolResponse(toolCallID string, content interface{}) (models.Message, error) {
+	return nil, nil
+}
+
+func (p *scriptedProvider) SupportsTools() bool { return true }
+func (p *scriptedProvider) Name() string        { return "scripted" }
+
+func TestFilteredToolIsRejectedAtCallTime(t *testing.T) {
+	svc := newTestService()
+	svc.settings = &MCPSettings{}
+	// the tool was offered before the filter was tightened
+	svc.toolNames.name("gh", "delete_repo")
+	svc.serverFilters = map[string]ToolFilter{"gh": {ExcludeTools: []string{"delete_*"}}}
+	// calling the client would panic: pagedClient does not implement CallTool
+	svc.mcpClients = map[string]mcpclient.MCPClient{"gh": &pagedClient{}}
+	svc.provider = &scriptedProvider{replies: []*history.HistoryMessage{
+		{
+			Role: "assistant",
+			Content: []history.ContentBlock{
+				{Type: "tool_use", ID: "call-1", Name: "gh__delete_repo", Input: json.RawMessage(`{}`)},
+			},
+		},
+		{
+			Role:    "assistant",
+			Content: []history.ContentBlock{{Type: "text", Text: "I can't delete repositories."}},
+		},
+	}}
+
+	var messages []history.HistoryMessage
+	if err := svc.runLLMWithToolCycle(context.Background(), "delete my repo", &messages); err != nil {
+		t.Fatalf("runLLMWithToolCycle: %v", err)
+	}
+
+	var result *history.ContentBlock
+	for _, msg := range messages {
+		for i, block := range msg.Content {
+			if block.Type == "tool_result" {
+				result = &msg.Content[i]
+			}
+		}
+	}
+	if result == nil || result.ToolUseID != "call-1" || !strings.Contains(result.Text, "not available") {
+		t.Fatalf("Expected a not-available tool result, got %+v", result)
+	}
+
+	ev := <-svc.EventChan
+	if ev.Type != EventFinalResult {
+		t.Errorf("Expected %s, got %s: %v", EventFinalResult, ev.Type, ev.Data)
+	}
+}
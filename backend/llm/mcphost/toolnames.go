@@ -0,0 +1,140 @@
+package mcphost
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxToolNameLength is the function name limit shared by OpenAI,
+	// Anthropic and Gemini
+	maxToolNameLength = 64
+
+	// toolNameHashLength is how many hex digits of the hash disambiguate
+	// names that had to be shortened or sanitized
+	toolNameHashLength = 8
+)
+
+// toolRef identifies an MCP tool on a specific server
+type toolRef struct {
+	Server string
+	Tool   string
+}
+
+// toolNameRegistry maps MCP tools to function names every provider accepts:
+// only ASCII letters, digits, "_" and "-", starting with a letter or "_",
+// at most 64 characters. The same names are used for all providers so a
+// conversation can move between them. Names are stable for the lifetime of
+// the registry. The zero value is ready to use.
+type toolNameRegistry struct {
+	mu     sync.RWMutex
+	byName map[string]toolRef
+	byRef  map[toolRef]string
+}
+
+// name returns the function name of a server's tool, assigning one on first use
+func (r *toolNameRegistry) name(server, tool string) string {
+	ref := toolRef{Server: server, Tool: tool}
+
+	r.mu.RLock()
+	name, ok := r.byRef[ref]
+	r.mu.RUnlock()
+	if ok {
+		return name
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byRef == nil {
+		r.byRef = make(map[toolRef]string)
+		r.byName = make(map[string]toolRef)
+	}
+	if name, ok := r.byRef[ref]; ok {
+		return name
+	}
+
+	name = r.uniqueName(ref)
+	r.byRef[ref] = name
+	r.byName[name] = ref
+	return name
+}
+
+// lookup resolves a function name chosen by the model back to its tool
+func (r *toolNameRegistry) lookup(name string) (toolRef, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ref, ok := r.byName[name]
+	return ref, ok
+}
+
+// uniqueName picks a valid, unused name for ref; callers hold r.mu
+func (r *toolNameRegistry) uniqueName(ref toolRef) string {
+	readable := sanitizeToolName(ref.Server) + "__" + sanitizeToolName(ref.Tool)
+
+	// keep the familiar server__tool form when it is already valid and
+	// unambiguous; anything that had to change gets a hash suffix so two
+	// different tools can never end up with the same name
+	if readable == ref.Server+"__"+ref.Tool && len(readable) <= maxToolNameLength &&
+		validToolNameStart(readable) && readable != searchToolsName && !r.taken(readable) {
+		return readable
+	}
+
+	sum := sha256.Sum256([]byte(ref.Server + "\x00" + ref.Tool))
+	suffix := hex.EncodeToString(sum[:])[:toolNameHashLength]
+
+	base := readable
+	if !validToolNameStart(base) {
+		base = "_" + base
+	}
+	if limit := maxToolNameLength - len(suffix) - 1; len(base) > limit {
+		base = base[:limit]
+	}
+	name := base + "_" + suffix
+
+	// a clash on the shortened name is vanishingly rare, but stay correct
+	for i := 2; r.taken(name); i++ {
+		extra := fmt.Sprintf("_%d", i)
+		trimmed := base
+		if limit := maxToolNameLength - len(suffix) - 1 - len(extra); len(trimmed) > limit {
+			trimmed = trimmed[:limit]
+		}
+		name = trimmed + "_" + suffix + extra
+	}
+	return name
+}
+
+func (r *toolNameRegistry) taken(name string) bool {
+	_, ok := r.byName[name]
+	return ok
+}
+
+// sanitizeToolName replaces every character providers reject with "_"
+func sanitizeToolName(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if isToolNameChar(c) {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func isToolNameChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// validToolNameStart reports whether name starts the way Gemini requires
+func validToolNameStart(name string) bool {
+	if name == "" {
+		return false
+	}
+	c := name[0]
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
@@ -0,0 +1,94 @@
+package mcphost
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// providerNameRules are the function name rules documented by each provider
+var providerNameRules = map[string]*regexp.Regexp{
+	"openai":    regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`),
+	"anthropic": regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`),
+	"google":    regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.:-]{0,63}$`),
+	"ollama":    regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`), // OpenAI compatible
+}
+
+var awkwardTools = []toolRef{
+	{"fs", "read_file"},
+	{"my__server", "tool"},
+	{"my", "_server__tool"},
+	{"server", "tool__with__underscores"},
+	{"db", "query.run"},
+	{"notes", "create note"},
+	{"wiki", "页面搜索"},
+	{"1password", "get_item"},
+	{"", "orphan"},
+	{"a-very-long-server-name-for-an-enterprise-system", "and_an_equally_long_tool_name_for_good_measure"},
+	{"a-very-long-server-name-for-an-enterprise-system", "and_an_equally_long_tool_name_for_good_measures"},
+}
+
+func TestToolNamesSatisfyEveryProvider(t *testing.T) {
+	var r toolNameRegistry
+	for _, ref := range awkwardTools {
+		name := r.name(ref.Server, ref.Tool)
+		for provider, rule := range providerNameRules {
+			if !rule.MatchString(name) {
+				t.Errorf("%s rejects %q (from %s/%s)", provider, name, ref.Server, ref.Tool)
+			}
+		}
+	}
+}
+
+func TestToolNamesAreUniqueAndReversible(t *testing.T) {
+	var r toolNameRegistry
+	seen := make(map[string]toolRef)
+	for _, ref := range awkwardTools {
+		name := r.name(ref.Server, ref.Tool)
+		if prev, ok := seen[name]; ok {
+			t.Errorf("%s/%s and %s/%s share the name %q", prev.Server, prev.Tool, ref.Server, ref.Tool, name)
+		}
+		seen[name] = ref
+
+		got, ok := r.lookup(name)
+		if !ok || got != ref {
+			t.Errorf("Expected %q to resolve to %+v, got %+v", name, ref, got)
+		}
+	}
+}
+
+func TestToolNamesKeepReadableForm(t *testing.T) {
+	var r toolNameRegistry
+	if name := r.name("fs", "read_file"); name != "fs__read_file" {
+		t.Errorf("Expected fs__read_file, got %s", name)
+	}
+
+	// sanitized names keep their words but gain a disambiguating suffix
+	name := r.name("notes", "create note")
+	if !strings.HasPrefix(name, "notes__create_note_") {
+		t.Errorf("Expected a notes__create_note_ prefix, got %s", name)
+	}
+}
+
+func TestToolNamesAreStable(t *testing.T) {
+	var r toolNameRegistry
+	first := r.name("db", "query.run")
+	r.name("db", "other")
+	if again := r.name("db", "query.run"); again != first {
+		t.Errorf("Expected a stable name %q, got %q", first, again)
+	}
+
+	// a fresh registry derives the same shortened name
+	var fresh toolNameRegistry
+	if name := fresh.name("db", "query.run"); name != first {
+		t.Errorf("Expected %q from a new registry, got %q", first, name)
+	}
+}
+
+func TestLookupUnknownName(t *testing.T) {
+	var r toolNameRegistry
+	r.name("fs", "read_file")
+	if _, ok := r.lookup("fs__write_file"); ok {
+		t.Error("Expected an unassigned name not to resolve")
+	}
+}
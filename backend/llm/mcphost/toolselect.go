@@ -0,0 +1,305 @@
+package mcphost
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+const (
+	// searchToolsName is the built-in meta-tool; it has no "__" so it can
+	// never clash with a namespaced server tool
+	searchToolsName = "search_tools"
+
+	// searchToolsLimit is how many matches search_tools returns by default
+	searchToolsLimit = 10
+
+	// queryMessageWindow is how many recent messages feed the ranking query
+	queryMessageWindow = 4
+
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// nameBoost repeats name terms so they outweigh description terms
+	nameBoost = 2
+)
+
+// stopWords are dropped from names, descriptions and queries
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "with": true, "me": true, "my": true, "i": true,
+	"you": true, "your": true, "can": true, "please": true, "what": true,
+}
+
+// tokenize lowercases text and splits it on punctuation, underscores and
+// camelCase boundaries
+func tokenize(text string) []string {
+	var terms []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			term := string(cur)
+			if !stopWords[term] {
+				terms = append(terms, term)
+			}
+			cur = cur[:0]
+		}
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 && unicode.IsLower(runes[i-1]) {
+				flush()
+			}
+			cur = append(cur, unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+// toolIndex is a BM25 index over tool names and descriptions
+type toolIndex struct {
+	tools  []models.Tool
+	terms  []map[string]int // per tool term frequencies
+	length []int
+	avgLen float64
+	df     map[string]int
+}
+
+// newToolIndex indexes a tool catalog
+func newToolIndex(tools []models.Tool) *toolIndex {
+	idx := &toolIndex{
+		tools:  tools,
+		terms:  make([]map[string]int, len(tools)),
+		length: make([]int, len(tools)),
+		df:     make(map[string]int),
+	}
+
+	total := 0
+	for i, tool := range tools {
+		tf := make(map[string]int)
+		for _, term := range tokenize(tool.Name) {
+			tf[term] += nameBoost
+			idx.length[i] += nameBoost
+		}
+		for _, term := range tokenize(tool.Description) {
+			tf[term]++
+			idx.length[i]++
+		}
+		for term := range tf {
+			idx.df[term]++
+		}
+		idx.terms[i] = tf
+		total += idx.length[i]
+	}
+	if len(tools) > 0 {
+		idx.avgLen = float64(total) / float64(len(tools))
+	}
+	return idx
+}
+
+// bm25 scores every tool against the query
+func (idx *toolIndex) bm25(query string) []float64 {
+	scores := make([]float64, len(idx.tools))
+	n := float64(len(idx.tools))
+
+	seen := make(map[string]bool)
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		df := float64(idx.df[term])
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for i, tf := range idx.terms {
+			f := float64(tf[term])
+			if f == 0 {
+				continue
+			}
+			norm := 1 - bm25B + bm25B*float64(idx.length[i])/idx.avgLen
+			scores[i] += idf * f * (bm25K1 + 1) / (f + bm25K1*norm)
+		}
+	}
+	return scores
+}
+
+// rank returns tool positions ordered by relevance to the query
+func (idx *toolIndex) rank(query string) ([]int, []float64) {
+	scores := idx.bm25(query)
+
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+	return order, scores
+}
+
+// searchToolsTool describes the meta-tool the model uses to find more tools
+func searchToolsTool() models.Tool {
+	return models.Tool{
+		Name: searchToolsName,
+		Description: "Search the full catalog of available tools. Only the most relevant " +
+			"tools are offered up front; call this with a short description of what you " +
+			"need when none of them fit. Matching tools become callable afterwards.",
+		InputSchema: models.Schema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "What the tool should do, e.g. \"create a calendar event\"",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("Maximum number of tools to return (default %d)", searchToolsLimit),
+				},
+			},
+			Required: []string{"query"},
+		},
+	}
+}
+
+// conversationQuery joins the text of the latest messages into a ranking query
+func conversationQuery(prompt string, messages []history.HistoryMessage) string {
+	parts := []string{prompt}
+	start := max(0, len(messages)-queryMessageWindow)
+	for _, msg := range messages[start:] {
+		if msg.Role == "user" || msg.Role == "assistant" {
+			parts = append(parts, msg.GetContent())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// selectTools picks the tools sent with the next provider call. Small
+// catalogs are sent whole; larger ones are cut down to the top K matches
+// plus pinned and previously discovered tools, and the search meta-tool.
+// While the provider caches the prompt, the first selection of the
+// conversation is kept and only grows by what search_tools finds, so the
+// cached prefix is not re-ranked away on every call.
+func (s *MCPService) selectTools(query string) []models.Tool {
+	frozen := s.cachesPrompt()
+
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+	all, idx := s.tools, s.toolIndex
+
+	topK := s.settings.ToolTopK
+	if topK <= 0 || len(all) <= topK || idx == nil {
+		return all
+	}
+	if !frozen {
+		s.frozenTools = nil
+	}
+
+	keep := make([]bool, len(all))
+	for i, tool := range all {
+		if matchesAny(s.settings.PinnedTools, tool.Name) || s.foundTools[tool.Name] || s.frozenTools[tool.Name] {
+			keep[i] = true
+		}
+	}
+
+	if s.frozenTools == nil {
+		order, _ := idx.rank(query)
+		for _, i := range order[:topK] {
+			keep[i] = true
+		}
+	}
+
+	// keep catalog order so the tool list stays stable between calls
+	selected := make([]models.Tool, 0, topK+1)
+	for i, tool := range all {
+		if keep[i] {
+			selected = append(selected, tool)
+		}
+	}
+	selected = append(selected, searchToolsTool())
+
+	if frozen {
+		s.frozenTools = make(map[string]bool, len(selected))
+		for _, tool := range selected {
+			s.frozenTools[tool.Name] = true
+		}
+	}
+
+	s.logger.Debug("tools selected", "selected", len(selected)-1, "catalog", len(all), "frozen", frozen)
+	return selected
+}
+
+// cachesPrompt reports whether the provider first asked for the current
+// prompt caches the prompt prefix
+func (s *MCPService) cachesPrompt() bool {
+	chain := s.turnChain()
+	return len(chain) > 0 && chain[0].caching
+}
+
+// searchTools runs the search meta-tool and makes the matches callable for
+// the rest of the prompt
+func (s *MCPService) searchTools(args map[string]any) string {
+	query, _ := args["query"].(string)
+	limit := searchToolsLimit
+	if l, ok := args["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+
+	// the index may be replaced by a tools/list_changed meanwhile
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+	idx := s.toolIndex
+	if idx == nil || len(idx.tools) == 0 {
+		return "No tools are available."
+	}
+
+	order, scores := idx.rank(query)
+	if s.foundTools == nil {
+		s.foundTools = make(map[string]bool)
+	}
+
+	type match struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	var matches []match
+	for _, i := range order {
+		if len(matches) == limit || scores[i] <= 0 {
+			break
+		}
+		tool := idx.tools[i]
+		s.foundTools[tool.Name] = true
+		matches = append(matches, match{Name: tool.Name, Description: tool.Description})
+	}
+	if len(matches) == 0 {
+		return fmt.Sprintf("No tools match %q.", query)
+	}
+
+	out, _ := json.MarshalIndent(matches, "", "  ")
+	return "These tools are now available:\n" + string(out)
+}
+
+// resetFoundTools forgets the tools search_tools found for the last prompt
+func (s *MCPService) resetFoundTools() {
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+	s.foundTools = nil
+}
@@ -0,0 +1,175 @@
+package mcphost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers/anthropic"
+)
+
+func testCatalog() []models.Tool {
+	return []models.Tool{
+		{Name: "calendar__create_event", Description: "Create a calendar event with attendees"},
+		{Name: "calendar__list_events", Description: "List upcoming calendar events"},
+		{Name: "github__create_issue", Description: "Open a new issue in a GitHub repository"},
+		{Name: "github__search_code", Description: "Search code across repositories"},
+		{Name: "fs__read_file", Description: "Read the contents of a file"},
+		{Name: "fs__write_file", Description: "Write contents to a file"},
+		{Name: "slack__post_message", Description: "Post a message to a Slack channel"},
+	}
+}
+
+func toolNames(tools []models.Tool) []string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("github__createIssue: Open the PR-42")
+	want := []string{"github", "create", "issue", "open", "pr", "42"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestBM25RanksRelevantToolsFirst(t *testing.T) {
+	idx := newToolIndex(testCatalog())
+
+	order, _ := idx.rank("schedule an event on my calendar")
+	top := map[string]bool{idx.tools[order[0]].Name: true, idx.tools[order[1]].Name: true}
+	if !top["calendar__create_event"] || !top["calendar__list_events"] {
+		t.Errorf("Expected calendar tools first, got %v", toolNames([]models.Tool{idx.tools[order[0]], idx.tools[order[1]]}))
+	}
+}
+
+func TestSelectToolsKeepsTopKPinnedAndMetaTool(t *testing.T) {
+	svc := newTestService()
+	svc.settings = &MCPSettings{ToolTopK: 2, PinnedTools: []string{"fs__read_*"}}
+	svc.setServerTools("all", testCatalog())
+
+	got := toolNames(svc.selectTools("open a github issue"))
+	want := []string{"github__create_issue", "github__search_code", "fs__read_file", searchToolsName}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// small catalogs are sent as-is
+	svc.settings.ToolTopK = 50
+	if got := svc.selectTools("anything"); len(got) != len(testCatalog()) {
+		t.Errorf("Expected the whole catalog, got %v", toolNames(got))
+	}
+}
+
+func TestSearchToolsMetaToolExpandsSelection(t *testing.T) {
+	svc := newTestService()
+	svc.settings = &MCPSettings{ToolTopK: 1}
+	svc.setServerTools("all", testCatalog())
+
+	provider := &scriptedProvider{replies: []*history.HistoryMessage{
+		{
+			Role: "assistant",
+			Content: []history.ContentBlock{{
+				Type: "tool_use", ID: "call-1", Name: searchToolsName,
+				Input: json.RawMessage(`{"query": "slack message", "limit": 1}`),
+			}},
+		},
+		{
+			Role:    "assistant",
+			Content: []history.ContentBlock{{Type: "text", Text: "Found it."}},
+		},
+	}}
+	svc.provider = provider
+
+	var messages []history.HistoryMessage
+	if err := svc.runLLMWithToolCycle(context.Background(), "read the file notes.txt", &messages); err != nil {
+		t.Fatalf("runLLMWithToolCycle: %v", err)
+	}
+	<-svc.EventChan
+
+	if len(provider.tools) != 2 {
+		t.Fatalf("Expected 2 provider calls, got %d", len(provider.tools))
+	}
+	first, second := toolNames(provider.tools[0]), toolNames(provider.tools[1])
+	if reflect.DeepEqual(first, second) {
+		t.Fatalf("Expected the selection to grow after search_tools, got %v both times", first)
+	}
+	found := false
+	for _, name := range second {
+		found = found || name == "slack__post_message"
+	}
+	if !found {
+		t.Errorf("Expected slack__post_message to be offered after searching, got %v", second)
+	}
+}
+
+func TestCachedPrefixStaysTheSameAcrossTurns(t *testing.T) {
+	var bodies []map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("bad request body: %v", err)
+		}
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"role": "assistant", "content": [{"type": "text", "text": "Done."}],
+			"usage": {"input_tokens": 12, "output_tokens": 3}}`)
+	}))
+	defer server.Close()
+
+	config := LLMProvider{Name: "claude", ProviderName: "anthropic", APIKey: "sk-ant", ModelName: "claude-sonnet-4-5",
+		BaseURL: server.URL, Anthropic: anthropic.Options{PromptCaching: true}}
+	svc := newTestService()
+	svc.settings = &MCPSettings{Provider: config, ToolTopK: 2}
+	chain, err := buildChain(context.Background(), config, "", svc.logger)
+	if err != nil {
+		t.Fatalf("buildChain: %v", err)
+	}
+	svc.provider = chain[0].provider
+	svc.setServerTools("all", testCatalog())
+
+	var messages []history.HistoryMessage
+	turns := []struct {
+		prompt string
+		at     time.Time
+	}{
+		{"open a github issue", time.Date(2026, 3, 9, 14, 5, 0, 0, time.UTC)},
+		{"schedule an event on my calendar", time.Date(2026, 3, 9, 14, 9, 0, 0, time.UTC)},
+	}
+	for _, turn := range turns {
+		svc.refreshSystemPrompt(turn.at)
+		messages = append(messages, history.HistoryMessage{Role: "user",
+			Content: []history.ContentBlock{{Type: "text", Text: turn.prompt}}})
+		if err := svc.runLLMWithToolCycle(context.Background(), turn.prompt, &messages); err != nil {
+			t.Fatalf("runLLMWithToolCycle: %v", err)
+		}
+		if ev := <-svc.EventChan; ev.Type != EventFinalResult {
+			t.Fatalf("Expected %s, got %s: %v", EventFinalResult, ev.Type, ev.Data)
+		}
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("Expected a request per turn, got %d", len(bodies))
+	}
+	for _, field := range []string{"tools", "system"} {
+		if string(bodies[0][field]) != string(bodies[1][field]) {
+			t.Errorf("Expected the same %s in both turns, got\n%s\n%s", field, bodies[0][field], bodies[1][field])
+		}
+	}
+	var first, second []json.RawMessage
+	json.Unmarshal(bodies[0]["messages"], &first)
+	json.Unmarshal(bodies[1]["messages"], &second)
+	if len(second) < 1 || string(first[0]) != string(second[0]) {
+		t.Errorf("Expected the first turn to be sent unchanged, got\n%s\n%s", first[0], second[0])
+	}
+}
@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"smart-spotlight-ai/backend/llm"
+	"smart-spotlight-ai/backend/packages/secrets"
+	"smart-spotlight-ai/backend/settings"
+)
+
+// modelCacheFileName keeps discovered model lists across restarts
+const modelCacheFileName = "models-cache.json"
+
+// GetProviderModels lists the models offered at a saved profile's endpoint,
+// from the cache unless it is stale or refresh is set
+func (a *App) GetProviderModels(profileName string, refresh bool) (llm.ModelList, error) {
+	profile, ok := settings.GetCurrentSettings().Profile(profileName)
+	if !ok {
+		return llm.ModelList{}, fmt.Errorf("profile %s not found", profileName)
+	}
+	return a.discoverModels(profile, refresh)
+}
+
+// DiscoverModels lists the models for a profile being edited, before it is
+// saved. A key sent back still masked uses the stored profile's key.
+func (a *App) DiscoverModels(profile settings.ProviderProfile, refresh bool) (llm.ModelList, error) {
+	if profile.APIKey == secrets.MaskedValue {
+		existing, _ := settings.GetCurrentSettings().Profile(profile.Name)
+		profile.APIKey = existing.APIKey
+	}
+	return a.discoverModels(profile, refresh)
+}
+
+func (a *App) discoverModels(profile settings.ProviderProfile, refresh bool) (llm.ModelList, error) {
+	if a.modelDiscovery == nil {
+		return llm.ModelList{}, fmt.Errorf("model discovery is not initialized")
+	}
+	config, err := a.profileConfig(profile)
+	if err != nil {
+		return llm.ModelList{}, err
+	}
+
+	list, err := a.modelDiscovery.Models(context.Background(), config, refresh)
+	if err != nil && len(list.Models) == 0 {
+		return list, err
+	}
+	if err != nil {
+		log.Printf("Error refreshing models for %s, using the cached list: %v", profile.Name, err)
+	}
+	a.capabilities.SetDiscovered(list.Provider, list.Models)
+	return list, nil
+}
+
+// availableModels returns the models discovered for the default profile, or
+// the built-in list before discovery has run
+func (a *App) availableModels(s *settings.Settings) []string {
+	profile, ok := s.ActiveProfile()
+	if !ok || a.modelDiscovery == nil {
+		return s.AvailableModels
+	}
+	config, err := a.profileConfig(profile)
+	if err != nil {
+		return s.AvailableModels
+	}
+	if list, ok := a.modelDiscovery.Cached(config); ok && len(list.Models) > 0 {
+		return list.IDs()
+	}
+	return s.AvailableModels
+}
+
+// refreshAvailableModels fills the cache for the default profile so the
+// model picker lists what the provider offers today
+func (a *App) refreshAvailableModels() {
+	profile, ok := settings.GetCurrentSettings().ActiveProfile()
+	if !ok {
+		return
+	}
+	if _, err := a.discoverModels(profile, false); err != nil {
+		log.Printf("Error discovering models for %s: %v", profile.Name, err)
+	}
+}
@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"smart-spotlight-ai/backend/packages/llm/providers"
+	"smart-spotlight-ai/backend/packages/llm/providers/ollama"
+	"smart-spotlight-ai/backend/settings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ollamaPullEvent carries ollama.PullProgress to the frontend while a model downloads
+const ollamaPullEvent = "OllamaPullProgress"
+
+// ollamaHost connects to the server of an ollama profile
+func ollamaHost(profileName string) (*ollama.Host, settings.ProviderProfile, error) {
+	profile, ok := settings.GetCurrentSettings().Profile(profileName)
+	if !ok {
+		return nil, profile, fmt.Errorf("profile %s not found", profileName)
+	}
+	if profile.Provider != providers.Ollama {
+		return nil, profile, fmt.Errorf("profile %s does not use Ollama", profileName)
+	}
+	host, err := ollama.NewHost(profile.BaseURL)
+	return host, profile, err
+}
+
+// ListOllamaModels lists the models pulled onto a profile's Ollama server
+func (a *App) ListOllamaModels(profileName string) ([]ollama.LocalModel, error) {
+	host, _, err := ollamaHost(profileName)
+	if err != nil {
+		return nil, err
+	}
+	return host.Models(context.Background())
+}
+
+// GetLoadedOllamaModels lists the models a profile's Ollama server holds in memory
+func (a *App) GetLoadedOllamaModels(profileName string) ([]ollama.LoadedModel, error) {
+	host, _, err := ollamaHost(profileName)
+	if err != nil {
+		return nil, err
+	}
+	return host.Loaded(context.Background())
+}
+
+// PullOllamaModel downloads a model onto a profile's Ollama server, emitting
+// OllamaPullProgress events until it returns
+func (a *App) PullOllamaModel(profileName string, model string) error {
+	host, profile, err := ollamaHost(profileName)
+	if err != nil {
+		return err
+	}
+	err = host.Pull(a.ctx, model, func(progress ollama.PullProgress) {
+		runtime.EventsEmit(a.ctx, ollamaPullEvent, progress)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", model, err)
+	}
+
+	// The picker and the capability registry should see the new model
+	if _, err := a.discoverModels(profile, true); err != nil {
+		log.Printf("Error refreshing models for %s: %v", profile.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// Package capabilities records what each model can do. Built-in defaults
+// are overridden by what model discovery reports, which user overrides
+// beat in turn.
+package capabilities
+
+import (
+	"path"
+	"strings"
+	"sync"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// Capabilities describes a model. Nil flags and zero limits are unknown and
+// leave the value of a lower layer in place.
+type Capabilities struct {
+	Tools           *bool `json:"tools,omitempty"`
+	Vision          *bool `json:"vision,omitempty"`
+	Streaming       *bool `json:"streaming,omitempty"`
+	JSONMode        *bool `json:"jsonMode,omitempty"`
+	Reasoning       *bool `json:"reasoning,omitempty"`
+	ContextWindow   int   `json:"contextWindow,omitempty"`
+	MaxOutputTokens int   `json:"maxOutputTokens,omitempty"`
+}
+
+// merge returns c with every value other knows replaced
+func (c Capabilities) merge(other Capabilities) Capabilities {
+	for _, pair := range []struct{ dst, src **bool }{
+		{&c.Tools, &other.Tools},
+		{&c.Vision, &other.Vision},
+		{&c.Streaming, &other.Streaming},
+		{&c.JSONMode, &other.JSONMode},
+		{&c.Reasoning, &other.Reasoning},
+	} {
+		if *pair.src != nil {
+			*pair.dst = *pair.src
+		}
+	}
+	if other.ContextWindow > 0 {
+		c.ContextWindow = other.ContextWindow
+	}
+	if other.MaxOutputTokens > 0 {
+		c.MaxOutputTokens = other.MaxOutputTokens
+	}
+	return c
+}
+
+// SupportsTools reports whether the model can call tools. Unknown models are
+// assumed to, which is what every provider claimed before the registry.
+func (c Capabilities) SupportsTools() bool {
+	return c.Tools == nil || *c.Tools
+}
+
+// SupportsVision reports whether the model accepts images
+func (c Capabilities) SupportsVision() bool {
+	return c.Vision != nil && *c.Vision
+}
+
+// SupportsStreaming reports whether answers can be streamed
+func (c Capabilities) SupportsStreaming() bool {
+	return c.Streaming == nil || *c.Streaming
+}
+
+// SupportsJSONMode reports whether the model can be held to JSON output
+func (c Capabilities) SupportsJSONMode() bool {
+	return c.JSONMode != nil && *c.JSONMode
+}
+
+// SupportsReasoning reports whether the model thinks before answering
+func (c Capabilities) SupportsReasoning() bool {
+	return c.Reasoning != nil && *c.Reasoning
+}
+
+// FromModelInfo converts what discovery reported
+func FromModelInfo(info models.ModelInfo) Capabilities {
+	return Capabilities{
+		Tools:           info.Tools,
+		Vision:          info.Vision,
+		Streaming:       info.Streaming,
+		ContextWindow:   info.ContextWindow,
+		MaxOutputTokens: info.MaxOutputTokens,
+	}
+}
+
+// Override sets capabilities for the models of a provider whose ID matches
+// Model, a path.Match pattern such as "llama3*"
+type Override struct {
+	Provider     string       `json:"provider"`
+	Model        string       `json:"model"`
+	Capabilities Capabilities `json:"capabilities"`
+}
+
+func (o Override) matches(provider, model string) bool {
+	if o.Provider != "" && o.Provider != provider {
+		return false
+	}
+	ok, err := path.Match(o.Model, model)
+	return err == nil && ok
+}
+
+// Registry resolves the capabilities of a model from the three layers
+type Registry struct {
+	mu         sync.RWMutex
+	builtin    []Override
+	discovered map[string]Capabilities // provider/model -> reported
+	user       []Override
+}
+
+// NewRegistry creates a registry with the built-in defaults
+func NewRegistry() *Registry {
+	return &Registry{
+		builtin:    builtinDefaults,
+		discovered: make(map[string]Capabilities),
+	}
+}
+
+func key(provider, model string) string {
+	return provider + "/" + model
+}
+
+// Lookup returns what is known about a model. Later built-in entries and
+// user overrides are more specific and win over earlier ones.
+func (r *Registry) Lookup(provider, model string) Capabilities {
+	model = strings.TrimPrefix(model, "models/")
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	builtinProvider := provider
+	if alias, ok := builtinAliases[provider]; ok {
+		builtinProvider = alias
+	}
+
+	var caps Capabilities
+	for _, o := range r.builtin {
+		if o.matches(builtinProvider, model) {
+			caps = caps.merge(o.Capabilities)
+		}
+	}
+	caps = caps.merge(r.discovered[key(provider, model)])
+	for _, o := range r.user {
+		if o.matches(provider, model) {
+			caps = caps.merge(o.Capabilities)
+		}
+	}
+	return caps
+}
+
+// Builtin returns what the built-in defaults know about a model, for code
+// that has no registry at hand
+func Builtin(provider, model string) Capabilities {
+	return builtinRegistry.Lookup(provider, model)
+}
+
+var builtinRegistry = NewRegistry()
+
+// SetDiscovered records what a provider reported about its models
+func (r *Registry) SetDiscovered(provider string, infos []models.ModelInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, info := range infos {
+		r.discovered[key(provider, info.ID)] = FromModelInfo(info)
+	}
+}
+
+// Learn records a capability found out by using the model, e.g. a provider
+// rejecting tools. It refines what discovery reported.
+func (r *Registry) Learn(provider, model string, caps Capabilities) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := key(provider, model)
+	r.discovered[k] = r.discovered[k].merge(caps)
+}
+
+// SetUserOverrides replaces the user's overrides
+func (r *Registry) SetUserOverrides(overrides []Override) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.user = append([]Override(nil), overrides...)
+}
+
+// Bool returns a pointer to b for building Capabilities
+func Bool(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,50 @@
+package capabilities
+
+import (
+	"testing"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+func TestLookupLayers(t *testing.T) {
+	r := NewRegistry()
+
+	sonnet := r.Lookup("anthropic", "claude-sonnet-4-5")
+	if !sonnet.SupportsTools() || !sonnet.SupportsReasoning() || sonnet.ContextWindow != 200000 || sonnet.MaxOutputTokens != 64000 {
+		t.Errorf("Expected the family defaults with the more specific entry on top, got %+v", sonnet)
+	}
+	if claude2 := r.Lookup("anthropic", "claude-2.1"); claude2.SupportsTools() {
+		t.Errorf("Expected claude-2 without tools, got %+v", claude2)
+	}
+	if gemini := r.Lookup("google", "models/gemini-2.5-flash"); gemini.MaxOutputTokens != 65536 {
+		t.Errorf("Expected the models/ prefix to be ignored, got %+v", gemini)
+	}
+	if deployment := r.Lookup("azure", "o3-mini"); !deployment.SupportsReasoning() || deployment.ContextWindow != 200000 {
+		t.Errorf("Expected azure deployments to use the OpenAI defaults, got %+v", deployment)
+	}
+	if nova := r.Lookup("bedrock", "us.amazon.nova-micro-v1:0"); !nova.SupportsTools() || nova.SupportsVision() || nova.ContextWindow != 128000 {
+		t.Errorf("Expected the Nova Micro entry over the Nova family, got %+v", nova)
+	}
+
+	unknown := r.Lookup("ollama", "phi3")
+	if !unknown.SupportsTools() || unknown.ContextWindow != 0 {
+		t.Errorf("Expected an unknown model to keep tools and report no context, got %+v", unknown)
+	}
+
+	r.SetDiscovered("ollama", []models.ModelInfo{{ID: "phi3", Tools: Bool(false), ContextWindow: 4096}})
+	phi := r.Lookup("ollama", "phi3")
+	if phi.SupportsTools() || phi.ContextWindow != 4096 || !phi.SupportsJSONMode() {
+		t.Errorf("Expected discovery on top of the defaults, got %+v", phi)
+	}
+
+	r.SetUserOverrides([]Override{{Provider: "ollama", Model: "phi*", Capabilities: Capabilities{Tools: Bool(true)}}})
+	if phi := r.Lookup("ollama", "phi3"); !phi.SupportsTools() || phi.ContextWindow != 4096 {
+		t.Errorf("Expected the user override to win only for what it sets, got %+v", phi)
+	}
+
+	r.SetUserOverrides(nil)
+	r.Learn("ollama", "phi3", Capabilities{Vision: Bool(true)})
+	if phi := r.Lookup("ollama", "phi3"); !phi.SupportsVision() || phi.SupportsTools() {
+		t.Errorf("Expected learned values to refine discovery, got %+v", phi)
+	}
+}
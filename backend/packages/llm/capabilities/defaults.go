@@ -0,0 +1,113 @@
+package capabilities
+
+var (
+	yes = Bool(true)
+	no  = Bool(false)
+)
+
+// builtinDefaults are what the providers document for their model families.
+// Entries are applied in order, so specific patterns follow general ones.
+var builtinDefaults = []Override{
+	// OpenAI
+	{Provider: "openai", Model: "gpt-3.5-turbo*", Capabilities: Capabilities{
+		Tools: yes, Vision: no, Streaming: yes, JSONMode: yes, Reasoning: no,
+		ContextWindow: 16385, MaxOutputTokens: 4096,
+	}},
+	{Provider: "openai", Model: "gpt-4*", Capabilities: Capabilities{
+		Tools: yes, Streaming: yes, JSONMode: yes, Reasoning: no,
+		ContextWindow: 8192, MaxOutputTokens: 8192,
+	}},
+	{Provider: "openai", Model: "gpt-4-turbo*", Capabilities: Capabilities{
+		Vision: yes, ContextWindow: 128000, MaxOutputTokens: 4096,
+	}},
+	{Provider: "openai", Model: "gpt-4o*", Capabilities: Capabilities{
+		Vision: yes, ContextWindow: 128000, MaxOutputTokens: 16384,
+	}},
+	{Provider: "openai", Model: "gpt-4.1*", Capabilities: Capabilities{
+		Vision: yes, ContextWindow: 1047576, MaxOutputTokens: 32768,
+	}},
+	{Provider: "openai", Model: "gpt-5*", Capabilities: Capabilities{
+		Tools: yes, Vision: yes, Streaming: yes, JSONMode: yes, Reasoning: yes,
+		ContextWindow: 400000, MaxOutputTokens: 128000,
+	}},
+	{Provider: "openai", Model: "gpt-5-chat*", Capabilities: Capabilities{
+		Reasoning: no, MaxOutputTokens: 16384, ContextWindow: 128000,
+	}},
+	{Provider: "openai", Model: "o[134]*", Capabilities: Capabilities{
+		Tools: yes, Vision: yes, Streaming: yes, JSONMode: yes, Reasoning: yes,
+		ContextWindow: 200000, MaxOutputTokens: 100000,
+	}},
+
+	// Anthropic
+	{Provider: "anthropic", Model: "claude-*", Capabilities: Capabilities{
+		Tools: yes, Vision: yes, Streaming: yes, JSONMode: no, Reasoning: no,
+		ContextWindow: 200000, MaxOutputTokens: 4096,
+	}},
+	{Provider: "anthropic", Model: "claude-2*", Capabilities: Capabilities{
+		Tools: no, Vision: no, ContextWindow: 100000,
+	}},
+	{Provider: "anthropic", Model: "claude-3-5-*", Capabilities: Capabilities{
+		MaxOutputTokens: 8192,
+	}},
+	{Provider: "anthropic", Model: "claude-3-7-*", Capabilities: Capabilities{
+		Reasoning: yes, MaxOutputTokens: 64000,
+	}},
+	{Provider: "anthropic", Model: "claude-sonnet-4*", Capabilities: Capabilities{
+		Reasoning: yes, MaxOutputTokens: 64000,
+	}},
+	{Provider: "anthropic", Model: "claude-haiku-4*", Capabilities: Capabilities{
+		Reasoning: yes, MaxOutputTokens: 64000,
+	}},
+	{Provider: "anthropic", Model: "claude-opus-4*", Capabilities: Capabilities{
+		Reasoning: yes, MaxOutputTokens: 32000,
+	}},
+
+	// Google
+	{Provider: "google", Model: "gemini-*", Capabilities: Capabilities{
+		Tools: yes, Vision: yes, Streaming: yes, JSONMode: yes, Reasoning: no,
+		ContextWindow: 1048576, MaxOutputTokens: 8192,
+	}},
+	{Provider: "google", Model: "gemini-1.5-pro*", Capabilities: Capabilities{
+		ContextWindow: 2097152,
+	}},
+	{Provider: "google", Model: "gemini-2.5-*", Capabilities: Capabilities{
+		Reasoning: yes, MaxOutputTokens: 65536,
+	}},
+
+	// Bedrock model IDs are prefixed with the vendor, and with a geography
+	// for cross-region inference profiles such as us.anthropic.claude-...
+	{Provider: "bedrock", Model: "*anthropic.claude-*", Capabilities: Capabilities{
+		Tools: yes, Vision: yes, JSONMode: no, Reasoning: no,
+		ContextWindow: 200000, MaxOutputTokens: 4096,
+	}},
+	{Provider: "bedrock", Model: "*anthropic.claude-3-7-*", Capabilities: Capabilities{
+		Reasoning: yes, MaxOutputTokens: 64000,
+	}},
+	{Provider: "bedrock", Model: "*anthropic.claude-sonnet-4*", Capabilities: Capabilities{
+		Reasoning: yes, MaxOutputTokens: 64000,
+	}},
+	{Provider: "bedrock", Model: "*amazon.nova-*", Capabilities: Capabilities{
+		Tools: yes, Vision: yes, Reasoning: no,
+		ContextWindow: 300000, MaxOutputTokens: 5000,
+	}},
+	{Provider: "bedrock", Model: "*amazon.nova-micro*", Capabilities: Capabilities{
+		Vision: no, ContextWindow: 128000,
+	}},
+	{Provider: "bedrock", Model: "*meta.llama3-[13]-*", Capabilities: Capabilities{
+		Tools: yes, Vision: no, Reasoning: no,
+		ContextWindow: 128000, MaxOutputTokens: 2048,
+	}},
+
+	// Ollama reports tool and vision support per model through discovery;
+	// its server streams every model
+	{Provider: "ollama", Model: "*", Capabilities: Capabilities{
+		Streaming: yes, JSONMode: yes,
+	}},
+}
+
+// builtinAliases look up the built-in defaults of providers that serve
+// another provider's models. Azure deployments are usually named after the
+// OpenAI model they run.
+var builtinAliases = map[string]string{
+	"azure": "openai",
+}
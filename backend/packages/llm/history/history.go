@@ -87,7 +87,8 @@ func (t *HistoryToolCall) GetArguments() map[string]interface{} {
 	return args
 }
 
-// ContentBlock represents a block of content in a message
+// ContentBlock represents a block of content in a message. Thinking blocks
+// keep their text in Text; redacted ones only the encrypted Data.
 type ContentBlock struct {
 	Type      string          `json:"type"`
 	Text      string          `json:"text,omitempty"`
@@ -96,4 +97,39 @@ type ContentBlock struct {
 	Name      string          `json:"name,omitempty"`
 	Input     json.RawMessage `json:"input,omitempty"`
 	Content   interface{}     `json:"content,omitempty"`
+	Signature string          `json:"signature,omitempty"`
+	Data      string          `json:"data,omitempty"`
+}
+
+// Block types of thinking a model did before answering
+const (
+	BlockThinking         = "thinking"
+	BlockRedactedThinking = "redacted_thinking"
+)
+
+// GetThinking returns the thinking blocks of the message
+func (m *HistoryMessage) GetThinking() []models.Thinking {
+	var thinking []models.Thinking
+	for _, block := range m.Content {
+		switch block.Type {
+		case BlockThinking:
+			thinking = append(thinking, models.Thinking{Text: block.Text, Signature: block.Signature})
+		case BlockRedactedThinking:
+			thinking = append(thinking, models.Thinking{Data: block.Data})
+		}
+	}
+	return thinking
+}
+
+// ThinkingBlocks converts thinking to the blocks stored in a HistoryMessage
+func ThinkingBlocks(thinking []models.Thinking) []ContentBlock {
+	blocks := make([]ContentBlock, 0, len(thinking))
+	for _, t := range thinking {
+		if t.Data != "" {
+			blocks = append(blocks, ContentBlock{Type: BlockRedactedThinking, Data: t.Data})
+			continue
+		}
+		blocks = append(blocks, ContentBlock{Type: BlockThinking, Text: t.Text, Signature: t.Signature})
+	}
+	return blocks
 }
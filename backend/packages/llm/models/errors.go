@@ -0,0 +1,97 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorKind classifies why a provider request failed
+type ErrorKind string
+
+const (
+	ErrorAuth           ErrorKind = "auth"            // key missing, invalid or not allowed to use the model
+	ErrorRateLimit      ErrorKind = "rate_limit"      // too many requests or quota used up
+	ErrorUnavailable    ErrorKind = "unavailable"     // provider overloaded, down or timing out
+	ErrorNetwork        ErrorKind = "network"         // the provider could not be reached
+	ErrorInvalidRequest ErrorKind = "invalid_request" // the provider rejected what we sent
+	ErrorUnknown        ErrorKind = "unknown"
+)
+
+// ProviderError is returned by providers when a request fails
+type ProviderError struct {
+	Provider   string
+	Kind       ErrorKind
+	StatusCode int // HTTP status, 0 when there was no response
+	Message    string
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	msg := e.Message
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: %s (status %d): %s", e.Provider, e.Kind, e.StatusCode, msg)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Provider, e.Kind, msg)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the same request may succeed later or elsewhere
+func (e *ProviderError) Retryable() bool {
+	switch e.Kind {
+	case ErrorRateLimit, ErrorUnavailable, ErrorNetwork:
+		return true
+	}
+	return false
+}
+
+// NewStatusError builds the error for an HTTP response with a failure status
+func NewStatusError(provider string, status int, message string) *ProviderError {
+	return &ProviderError{
+		Provider:   provider,
+		Kind:       KindForStatus(status),
+		StatusCode: status,
+		Message:    message,
+	}
+}
+
+// NewNetworkError builds the error for a request that got no response
+func NewNetworkError(provider string, err error) *ProviderError {
+	return &ProviderError{Provider: provider, Kind: ErrorNetwork, Err: err}
+}
+
+// KindForStatus maps an HTTP status to an error kind
+func KindForStatus(status int) ErrorKind {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorAuth
+	case status == http.StatusTooManyRequests:
+		return ErrorRateLimit
+	case status == http.StatusRequestTimeout || status >= 500:
+		// includes Anthropic's 529 overloaded
+		return ErrorUnavailable
+	case status >= 400:
+		return ErrorInvalidRequest
+	}
+	return ErrorUnknown
+}
+
+// AsProviderError returns the ProviderError in err's chain, if any
+func AsProviderError(err error) (*ProviderError, bool) {
+	var providerErr *ProviderError
+	ok := errors.As(err, &providerErr)
+	return providerErr, ok
+}
+
+// ShouldFallback reports whether another provider may answer a request that
+// failed with err: the failure was transient, or the key was not accepted
+func ShouldFallback(err error) bool {
+	providerErr, ok := AsProviderError(err)
+	return ok && (providerErr.Retryable() || providerErr.Kind == ErrorAuth)
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestKindForStatus(t *testing.T) {
+	cases := map[int]ErrorKind{
+		401: ErrorAuth,
+		403: ErrorAuth,
+		429: ErrorRateLimit,
+		500: ErrorUnavailable,
+		529: ErrorUnavailable,
+		408: ErrorUnavailable,
+		400: ErrorInvalidRequest,
+		404: ErrorInvalidRequest,
+		200: ErrorUnknown,
+	}
+	for status, want := range cases {
+		if got := KindForStatus(status); got != want {
+			t.Errorf("Expected %s for status %d, got %s", want, status, got)
+		}
+	}
+}
+
+func TestProviderErrorChain(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("sending request: %w", NewNetworkError("openai", cause))
+
+	providerErr, ok := AsProviderError(err)
+	if !ok {
+		t.Fatalf("Expected a provider error in the chain of %v", err)
+	}
+	if !providerErr.Retryable() {
+		t.Errorf("Expected a network error to be retryable")
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("Expected the cause to be unwrapped")
+	}
+	if NewStatusError("openai", 400, "bad model").Retryable() {
+		t.Errorf("Expected an invalid request not to be retryable")
+	}
+}
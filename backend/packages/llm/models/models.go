@@ -68,3 +68,58 @@ type MCPResponse struct {
 	Content string `json:"content"`
 }
 
+// SystemPromptSetter is implemented by providers whose system prompt can
+// change between requests, e.g. to carry the current date or server instructions
+type SystemPromptSetter interface {
+	SetSystemPrompt(prompt string)
+}
+
+// GenerationParams tunes how a model samples its answer; zero values keep
+// the provider's defaults
+type GenerationParams struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"maxTokens,omitempty"`
+}
+
+// GenerationSetter is implemented by providers that accept GenerationParams
+type GenerationSetter interface {
+	SetGenerationParams(params GenerationParams)
+}
+
+// ModelInfo describes a model a provider offers. Limits are 0 and
+// capabilities nil when the provider does not report them.
+type ModelInfo struct {
+	ID              string `json:"id"`
+	DisplayName     string `json:"displayName,omitempty"`
+	ContextWindow   int    `json:"contextWindow,omitempty"`
+	MaxOutputTokens int    `json:"maxOutputTokens,omitempty"`
+	Tools           *bool  `json:"tools,omitempty"`
+	Vision          *bool  `json:"vision,omitempty"`
+	Streaming       *bool  `json:"streaming,omitempty"`
+}
+
+// ModelLister is implemented by providers that can list their models
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// Thinking is a reasoning block a model wrote before answering. Redacted
+// thinking carries only the encrypted Data.
+type Thinking struct {
+	Text      string
+	Signature string
+	Data      string
+}
+
+// ThinkingMessage is implemented by messages that carry thinking blocks,
+// which have to be sent back unchanged along with the tool results that
+// follow them
+type ThinkingMessage interface {
+	GetThinking() []Thinking
+}
+
+// CacheUsage is implemented by messages of providers with prompt caching
+type CacheUsage interface {
+	// GetCacheUsage returns the input tokens read from and written to the cache
+	GetCacheUsage() (read int, write int)
+}
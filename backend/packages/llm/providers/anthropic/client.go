@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
 )
 
 type Client struct {
@@ -45,26 +48,12 @@ func (c *Client) CreateMessage(ctx context.Context, req CreateRequest) (*APIMess
 
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, models.NewNetworkError(providerName, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp struct {
-			Error struct {
-				Type    string `json:"type"`
-				Message string `json:"message"`
-			} `json:"error"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
-			return nil, fmt.Errorf("error response with status %d", resp.StatusCode)
-		}
-
-		if errResp.Error.Type == "overloaded_error" {
-			return nil, fmt.Errorf("overloaded_error: %s", errResp.Error.Message)
-		}
-
-		return nil, fmt.Errorf("%s: %s", errResp.Error.Type, errResp.Error.Message)
+		return nil, statusError(resp)
 	}
 
 	var message APIMessage
@@ -74,3 +63,53 @@ func (c *Client) CreateMessage(ctx context.Context, req CreateRequest) (*APIMess
 
 	return &message, nil
 }
+
+// ListModels returns one page of the models the key can use
+func (c *Client) ListModels(ctx context.Context, afterID string) (*ModelPage, error) {
+	query := url.Values{"limit": {"1000"}}
+	if afterID != "" {
+		query.Set("after_id", afterID)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/models?%s", c.baseURL, query.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	httpReq.Header.Set("X-Api-Key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, models.NewNetworkError(providerName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	var page ModelPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	return &page, nil
+}
+
+// statusError reads the error body of a failed request
+func statusError(resp *http.Response) error {
+	var errResp struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+		return models.NewStatusError(providerName, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	providerErr := models.NewStatusError(providerName, resp.StatusCode,
+		fmt.Sprintf("%s: %s", errResp.Error.Type, errResp.Error.Message))
+	if errResp.Error.Type == "overloaded_error" {
+		providerErr.Kind = models.ErrorUnavailable
+	}
+	return providerErr
+}
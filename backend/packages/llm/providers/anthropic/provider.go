@@ -11,13 +11,40 @@ import (
 	"smart-spotlight-ai/backend/packages/llm/models"
 )
 
+const (
+	providerName     = "anthropic"
+	defaultMaxTokens = 4096
+
+	// minThinkingBudget is the smallest thinking budget the API accepts
+	minThinkingBudget = 1024
+)
+
+// Options are the per-profile Anthropic features a request opts into
+type Options struct {
+	// PromptCaching caches the system prompt, the tools and the conversation
+	// so far, which later turns read back at a fraction of the input price
+	PromptCaching bool `json:"promptCaching,omitempty"`
+	// ThinkingBudget turns on extended thinking with this many tokens, 0 is off
+	ThinkingBudget int `json:"thinkingBudget,omitempty"`
+}
+
+// Validate checks the options can be sent to the API
+func (o Options) Validate() error {
+	if o.ThinkingBudget != 0 && o.ThinkingBudget < minThinkingBudget {
+		return fmt.Errorf("thinking budget must be at least %d tokens", minThinkingBudget)
+	}
+	return nil
+}
+
 type Provider struct {
 	client       *Client
 	model        string
 	systemPrompt string
+	params       models.GenerationParams
+	options      Options
 }
 
-func NewProvider(apiKey, baseURL, model, systemPrompt string) *Provider {
+func NewProvider(apiKey, baseURL, model, systemPrompt string, options Options) *Provider {
 	if model == "" {
 		model = "claude-3-5-sonnet-20240620" // 默认模型
 	}
@@ -25,9 +52,20 @@ func NewProvider(apiKey, baseURL, model, systemPrompt string) *Provider {
 		client:       NewClient(apiKey, baseURL),
 		model:        model,
 		systemPrompt: systemPrompt,
+		options:      options,
 	}
 }
 
+// SetSystemPrompt replaces the system prompt sent with the next requests
+func (p *Provider) SetSystemPrompt(prompt string) {
+	p.systemPrompt = prompt
+}
+
+// SetGenerationParams overrides the default temperature and token limit
+func (p *Provider) SetGenerationParams(params models.GenerationParams) {
+	p.params = params
+}
+
 func (p *Provider) CreateMessage(
 	ctx context.Context,
 	prompt string,
@@ -49,6 +87,12 @@ func (p *Provider) CreateMessage(
 
 		content := []ContentBlock{}
 
+		// Thinking opens the assistant turn it belongs to; a tool call made
+		// after thinking is only accepted with it
+		if thinking, ok := msg.(models.ThinkingMessage); ok && p.options.ThinkingBudget > 0 {
+			content = append(content, thinkingBlocks(thinking.GetThinking())...)
+		}
+
 		// Add regular text content if present
 		if textContent := strings.TrimSpace(msg.GetContent()); textContent != "" {
 			content = append(content, ContentBlock{
@@ -132,13 +176,35 @@ func (p *Provider) CreateMessage(
 		"num_tools", len(tools))
 
 	// Make the API call
-	resp, err := p.client.CreateMessage(ctx, CreateRequest{
-		Model:     p.model,
-		Messages:  anthropicMessages,
-		MaxTokens: 4096,
-		Tools:     anthropicTools,
-		System:    p.systemPrompt,
-	})
+	maxTokens := defaultMaxTokens
+	if p.params.MaxTokens > 0 {
+		maxTokens = p.params.MaxTokens
+	}
+
+	req := CreateRequest{
+		Model:       p.model,
+		Messages:    anthropicMessages,
+		MaxTokens:   maxTokens,
+		Temperature: p.params.Temperature,
+		Tools:       anthropicTools,
+	}
+	if p.systemPrompt != "" {
+		req.System = []ContentBlock{{Type: "text", Text: p.systemPrompt}}
+	}
+	if p.options.ThinkingBudget > 0 {
+		req.Thinking = &ThinkingConfig{Type: "enabled", BudgetTokens: p.options.ThinkingBudget}
+		// the answer has to fit next to the thinking, and thinking only
+		// runs at the default temperature
+		if req.MaxTokens <= p.options.ThinkingBudget {
+			req.MaxTokens = p.options.ThinkingBudget + defaultMaxTokens
+		}
+		req.Temperature = nil
+	}
+	if p.options.PromptCaching {
+		addCacheBreakpoints(&req)
+	}
+
+	resp, err := p.client.CreateMessage(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -146,12 +212,64 @@ func (p *Provider) CreateMessage(
 	return &Message{Msg: *resp}, nil
 }
 
+// thinkingBlocks converts thinking back to the blocks the API returned
+func thinkingBlocks(thinking []models.Thinking) []ContentBlock {
+	blocks := make([]ContentBlock, 0, len(thinking))
+	for _, t := range thinking {
+		if t.Data != "" {
+			blocks = append(blocks, ContentBlock{Type: "redacted_thinking", Data: t.Data})
+			continue
+		}
+		blocks = append(blocks, ContentBlock{Type: "thinking", Thinking: t.Text, Signature: t.Signature})
+	}
+	return blocks
+}
+
+// addCacheBreakpoints marks the prefixes to cache: the tools, the system
+// prompt, and the conversation up to the latest and the previous user turn.
+// The latest is written for the next request, which reads it back; the
+// previous one lets this request read what the last one wrote. That is the
+// API's limit of four breakpoints.
+func addCacheBreakpoints(req *CreateRequest) {
+	if n := len(req.Tools); n > 0 {
+		req.Tools[n-1].CacheControl = ephemeral
+	}
+	if n := len(req.System); n > 0 {
+		req.System[n-1].CacheControl = ephemeral
+	}
+
+	marked := 0
+	for i := len(req.Messages) - 1; i >= 0 && marked < 2; i-- {
+		if req.Messages[i].Role != roleUser {
+			continue
+		}
+		if markLastBlock(req.Messages[i].Content) {
+			marked++
+		}
+	}
+}
+
+// markLastBlock puts a breakpoint on the last block that can carry one
+func markLastBlock(content []ContentBlock) bool {
+	for i := len(content) - 1; i >= 0; i-- {
+		switch {
+		case content[i].Type == "thinking" || content[i].Type == "redacted_thinking":
+			continue
+		case content[i].Type == "text" && content[i].Text == "":
+			continue
+		}
+		content[i].CacheControl = ephemeral
+		return true
+	}
+	return false
+}
+
 func (p *Provider) SupportsTools() bool {
 	return true
 }
 
 func (p *Provider) Name() string {
-	return "anthropic"
+	return providerName
 }
 
 func (p *Provider) CreateToolResponse(
@@ -213,3 +331,23 @@ func mappingRole(role string) string {
 	}
 	return v
 }
+
+// ListModels returns the models the key can use. The models API reports
+// names only, no limits or capabilities.
+func (p *Provider) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	var infos []models.ModelInfo
+	afterID := ""
+	for {
+		page, err := p.client.ListModels(ctx, afterID)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range page.Data {
+			infos = append(infos, models.ModelInfo{ID: entry.ID, DisplayName: entry.DisplayName})
+		}
+		if !page.HasMore || page.LastID == "" {
+			return infos, nil
+		}
+		afterID = page.LastID
+	}
+}
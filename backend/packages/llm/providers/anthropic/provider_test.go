@@ -0,0 +1,135 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// recordingServer answers every request with reply and keeps the last request
+func recordingServer(t *testing.T, reply string, req *CreateRequest) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Errorf("bad request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, reply)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// conversation is a finished tool round trip followed by a new question
+func conversation() []models.Message {
+	return []models.Message{
+		&history.HistoryMessage{Role: "user", Content: []history.ContentBlock{{Type: "text", Text: "List my repos"}}},
+		&history.HistoryMessage{Role: "assistant", Content: []history.ContentBlock{
+			{Type: history.BlockThinking, Text: "The gh tool lists repos.", Signature: "sig-1"},
+			{Type: history.BlockRedactedThinking, Data: "opaque"},
+			{Type: "tool_use", ID: "toolu_1", Name: "gh__list_repos", Input: json.RawMessage(`{}`)},
+		}},
+		&history.HistoryMessage{Role: "tool", Content: []history.ContentBlock{
+			{Type: "tool_result", ToolUseID: "toolu_1", Text: "spotlight, notes", Content: "spotlight, notes"},
+		}},
+		&history.HistoryMessage{Role: "assistant", Content: []history.ContentBlock{{Type: "text", Text: "You have two."}}},
+		&history.HistoryMessage{Role: "user", Content: []history.ContentBlock{{Type: "text", Text: "Which is newer?"}}},
+	}
+}
+
+func TestPromptCachingBreakpoints(t *testing.T) {
+	var req CreateRequest
+	server := recordingServer(t, `{"role": "assistant", "content": [{"type": "text", "text": "notes"}],
+		"usage": {"input_tokens": 12, "output_tokens": 3,
+			"cache_creation_input_tokens": 40, "cache_read_input_tokens": 2100}}`, &req)
+
+	provider := NewProvider("sk-ant", server.URL, "claude-sonnet-4-5", "You are helpful.", Options{PromptCaching: true})
+	tools := []models.Tool{
+		{Name: "gh__list_repos", InputSchema: models.Schema{Type: "object"}},
+		{Name: "gh__get_repo", InputSchema: models.Schema{Type: "object"}},
+	}
+	msg, err := provider.CreateMessage(context.Background(), "", conversation(), tools)
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+
+	if req.Tools[0].CacheControl != nil || req.Tools[1].CacheControl == nil {
+		t.Errorf("Expected a breakpoint on the last tool only")
+	}
+	if len(req.System) != 1 || req.System[0].CacheControl == nil {
+		t.Errorf("Expected the system prompt to be cached, got %+v", req.System)
+	}
+	var marked []int
+	for i, m := range req.Messages {
+		for _, block := range m.Content {
+			if block.CacheControl != nil {
+				marked = append(marked, i)
+			}
+		}
+	}
+	// the tool result turn and the new question, the two latest user turns
+	if fmt.Sprint(marked) != "[2 4]" {
+		t.Errorf("Expected breakpoints on messages [2 4], got %v", marked)
+	}
+	if req.Thinking != nil {
+		t.Errorf("Expected thinking to stay off")
+	}
+	for _, block := range req.Messages[1].Content {
+		if block.Type == "thinking" || block.Type == "redacted_thinking" {
+			t.Errorf("Expected thinking not to be replayed with thinking off")
+		}
+	}
+
+	read, write := msg.(models.CacheUsage).GetCacheUsage()
+	if read != 2100 || write != 40 {
+		t.Errorf("Expected 2100 tokens read and 40 written, got %d and %d", read, write)
+	}
+}
+
+func TestExtendedThinking(t *testing.T) {
+	var req CreateRequest
+	server := recordingServer(t, `{"role": "assistant", "content": [
+		{"type": "thinking", "thinking": "Compare the push dates.", "signature": "sig-2"},
+		{"type": "tool_use", "id": "toolu_2", "name": "gh__get_repo", "input": {"name": "notes"}}],
+		"usage": {"input_tokens": 50, "output_tokens": 30}}`, &req)
+
+	provider := NewProvider("sk-ant", server.URL, "claude-sonnet-4-5", "", Options{ThinkingBudget: 2048})
+	temperature := 0.3
+	provider.SetGenerationParams(models.GenerationParams{Temperature: &temperature, MaxTokens: 1024})
+
+	msg, err := provider.CreateMessage(context.Background(), "", conversation(), nil)
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+
+	if req.Thinking == nil || req.Thinking.BudgetTokens != 2048 {
+		t.Errorf("Expected a 2048 token thinking budget, got %+v", req.Thinking)
+	}
+	if req.MaxTokens <= 2048 || req.Temperature != nil {
+		t.Errorf("Expected max tokens above the budget and no temperature, got %d and %v", req.MaxTokens, req.Temperature)
+	}
+	if req.System != nil {
+		t.Errorf("Expected no system blocks without a system prompt")
+	}
+
+	replayed := req.Messages[1].Content
+	if len(replayed) != 3 || replayed[0].Type != "thinking" || replayed[0].Signature != "sig-1" ||
+		replayed[1].Type != "redacted_thinking" || replayed[1].Data != "opaque" || replayed[2].Type != "tool_use" {
+		t.Errorf("Expected thinking replayed ahead of the tool call, got %+v", replayed)
+	}
+
+	thinking := msg.(models.ThinkingMessage).GetThinking()
+	if len(thinking) != 1 || thinking[0].Text != "Compare the push dates." || thinking[0].Signature != "sig-2" {
+		t.Errorf("Expected the answer's thinking, got %+v", thinking)
+	}
+
+	if err := (Options{ThinkingBudget: 500}).Validate(); err == nil {
+		t.Errorf("Expected a budget under %d tokens to be rejected", minThinkingBudget)
+	}
+}
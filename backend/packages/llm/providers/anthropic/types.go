@@ -10,13 +10,28 @@ import (
 )
 
 type CreateRequest struct {
-	Model     string         `json:"model"`
-	Messages  []MessageParam `json:"messages"`
-	MaxTokens int            `json:"max_tokens"`
-	System    string         `json:"system,omitempty"`
-	Tools     []Tool         `json:"tools,omitempty"`
+	Model       string          `json:"model"`
+	Messages    []MessageParam  `json:"messages"`
+	MaxTokens   int             `json:"max_tokens"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	System      []ContentBlock  `json:"system,omitempty"`
+	Tools       []Tool          `json:"tools,omitempty"`
+	Thinking    *ThinkingConfig `json:"thinking,omitempty"`
 }
 
+// ThinkingConfig turns on extended thinking with a token budget
+type ThinkingConfig struct {
+	Type         string `json:"type"` // "enabled"
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// CacheControl marks the end of a prompt prefix to cache
+type CacheControl struct {
+	Type string `json:"type"` // "ephemeral"
+}
+
+var ephemeral = &CacheControl{Type: "ephemeral"}
+
 type MessageParam struct {
 	Role    string         `json:"role"`
 	Content []ContentBlock `json:"content"`
@@ -30,12 +45,20 @@ type ContentBlock struct {
 	Name      string          `json:"name,omitempty"`
 	Input     json.RawMessage `json:"input,omitempty"`
 	Content   interface{}     `json:"content,omitempty"`
+
+	// thinking and redacted_thinking blocks
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
 }
 
 type Tool struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	InputSchema InputSchema `json:"input_schema"`
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	InputSchema  InputSchema   `json:"input_schema"`
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
 }
 
 type InputSchema struct {
@@ -56,8 +79,10 @@ type APIMessage struct {
 }
 
 type Usage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 }
 
 // Message implements the models.Message interface
@@ -155,6 +180,25 @@ func (m *Message) GetUsage() (input int, output int) {
 	return m.Msg.Usage.InputTokens, m.Msg.Usage.OutputTokens
 }
 
+// GetCacheUsage returns the prompt tokens read from and written to the cache
+func (m *Message) GetCacheUsage() (read int, write int) {
+	return m.Msg.Usage.CacheReadInputTokens, m.Msg.Usage.CacheCreationInputTokens
+}
+
+// GetThinking returns the thinking the model did before answering
+func (m *Message) GetThinking() []models.Thinking {
+	var thinking []models.Thinking
+	for _, block := range m.Msg.Content {
+		switch block.Type {
+		case "thinking":
+			thinking = append(thinking, models.Thinking{Text: block.Thinking, Signature: block.Signature})
+		case "redacted_thinking":
+			thinking = append(thinking, models.Thinking{Data: block.Data})
+		}
+	}
+	return thinking
+}
+
 // ToolCall implements the models.ToolCall interface
 type ToolCall struct {
 	id   string
@@ -177,3 +221,15 @@ func (t *ToolCall) GetArguments() map[string]interface{} {
 func (t *ToolCall) GetID() string {
 	return t.id
 }
+
+// ModelPage is a page of GET /models
+type ModelPage struct {
+	Data    []ModelEntry `json:"data"`
+	HasMore bool         `json:"has_more"`
+	LastID  string       `json:"last_id"`
+}
+
+type ModelEntry struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+}
@@ -0,0 +1,115 @@
+// Package azure talks to Azure OpenAI deployments. They speak the OpenAI
+// API, but under a per-deployment URL with an api-version query parameter,
+// and authenticate with an api-key header instead of a bearer token.
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers/openai"
+)
+
+const (
+	providerName = "azure"
+
+	// DefaultAPIVersion is the GA data plane version used when a profile
+	// sets none. The Responses API needs a preview version.
+	DefaultAPIVersion = "2024-10-21"
+)
+
+var apiVersionRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(-preview)?$`)
+
+// Options are the per-profile settings of the azure provider. The OpenAI
+// options apply as they do for the openai provider.
+type Options struct {
+	APIVersion string `json:"apiVersion,omitempty"` // e.g. 2024-10-21, empty is DefaultAPIVersion
+	openai.Options
+}
+
+// Validate checks the options can be sent to the API
+func (o Options) Validate() error {
+	if o.APIVersion != "" && !apiVersionRe.MatchString(o.APIVersion) {
+		return fmt.Errorf("unsupported api version %q, expected a date such as %s", o.APIVersion, DefaultAPIVersion)
+	}
+	return o.Options.Validate()
+}
+
+// Provider sends requests to one deployment of an Azure OpenAI resource
+type Provider struct {
+	chat *openai.Provider
+}
+
+// NewProvider creates a provider for the deployment of the resource at
+// endpoint, e.g. https://contoso.openai.azure.com
+func NewProvider(apiKey, endpoint, deployment, systemPrompt string, options Options) (*Provider, error) {
+	base, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid Azure OpenAI endpoint %q, expected e.g. https://<resource>.openai.azure.com", endpoint)
+	}
+	if deployment == "" {
+		return nil, fmt.Errorf("an Azure OpenAI deployment name is required")
+	}
+	version := options.APIVersion
+	if version == "" {
+		version = DefaultAPIVersion
+	}
+
+	client := openai.NewEndpointClient(openai.Endpoint{
+		Provider: providerName,
+		URL:      deploymentURL(base.String(), deployment, version),
+		Header:   http.Header{"Api-Key": {apiKey}},
+	})
+	return &Provider{
+		chat: openai.NewProviderWithClient(client, deployment, systemPrompt, options.Options),
+	}, nil
+}
+
+// deploymentURL maps OpenAI API paths onto the resource. Chat completions
+// are served per deployment; the Responses API is served per resource and
+// takes the deployment as the model.
+func deploymentURL(endpoint, deployment, version string) func(string) string {
+	query := "?api-version=" + url.QueryEscape(version)
+	return func(path string) string {
+		if path == "/responses" {
+			return endpoint + "/openai" + path + query
+		}
+		return endpoint + "/openai/deployments/" + url.PathEscape(deployment) + path + query
+	}
+}
+
+// SetSystemPrompt replaces the system prompt sent with the next requests
+func (p *Provider) SetSystemPrompt(prompt string) {
+	p.chat.SetSystemPrompt(prompt)
+}
+
+// SetGenerationParams overrides the default temperature and token limit
+func (p *Provider) SetGenerationParams(params models.GenerationParams) {
+	p.chat.SetGenerationParams(params)
+}
+
+func (p *Provider) CreateMessage(
+	ctx context.Context,
+	prompt string,
+	messages []models.Message,
+	tools []models.Tool,
+) (models.Message, error) {
+	return p.chat.CreateMessage(ctx, prompt, messages, tools)
+}
+
+func (p *Provider) CreateToolResponse(toolCallID string, content interface{}) (models.Message, error) {
+	return p.chat.CreateToolResponse(toolCallID, content)
+}
+
+func (p *Provider) SupportsTools() bool {
+	return true
+}
+
+func (p *Provider) Name() string {
+	return providerName
+}
@@ -0,0 +1,105 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers/openai"
+)
+
+// azureServer stands in for an Azure OpenAI resource, recording the
+// requests it gets
+func azureServer(t *testing.T, requests *[]*http.Request) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/openai/deployments/prod-gpt4o/chat/completions":
+			fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "chat answer"}}]}`)
+		case "/openai/responses":
+			fmt.Fprint(w, `{"output": [{"type": "message", "role": "assistant",
+				"content": [{"type": "output_text", "text": "responses answer"}]}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": {"code": "DeploymentNotFound", "message": "The API deployment for this resource does not exist."}}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDeploymentRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		path    string
+		version string
+		answer  string
+	}{
+		{name: "chat completions", path: "/openai/deployments/prod-gpt4o/chat/completions",
+			version: DefaultAPIVersion, answer: "chat answer"},
+		{name: "responses", options: Options{APIVersion: "2025-03-01-preview", Options: openai.Options{API: openai.APIResponses}},
+			path: "/openai/responses", version: "2025-03-01-preview", answer: "responses answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []*http.Request
+			server := azureServer(t, &requests)
+			provider, err := NewProvider("azure-key", server.URL+"/", "prod-gpt4o", "", tt.options)
+			if err != nil {
+				t.Fatalf("NewProvider: %v", err)
+			}
+
+			msg, err := provider.CreateMessage(context.Background(), "hi", nil, nil)
+			if err != nil {
+				t.Fatalf("CreateMessage: %v", err)
+			}
+			if msg.GetContent() != tt.answer {
+				t.Errorf("Expected %q, got %q", tt.answer, msg.GetContent())
+			}
+
+			r := requests[0]
+			if r.URL.Path != tt.path {
+				t.Errorf("Expected a request to %s, got %s", tt.path, r.URL.Path)
+			}
+			if got := r.URL.Query().Get("api-version"); got != tt.version {
+				t.Errorf("Expected api-version %s, got %q", tt.version, got)
+			}
+			if r.Header.Get("api-key") != "azure-key" || r.Header.Get("Authorization") != "" {
+				t.Errorf("Expected the key in the api-key header only, got api-key %q and Authorization %q",
+					r.Header.Get("api-key"), r.Header.Get("Authorization"))
+			}
+		})
+	}
+}
+
+func TestDeploymentErrors(t *testing.T) {
+	var requests []*http.Request
+	server := azureServer(t, &requests)
+	provider, err := NewProvider("azure-key", server.URL, "missing", "", Options{})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+
+	_, err = provider.CreateMessage(context.Background(), "hi", nil, nil)
+	providerErr, ok := models.AsProviderError(err)
+	if !ok || providerErr.Provider != "azure" || providerErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 from azure, got %v", err)
+	}
+
+	if _, err := NewProvider("azure-key", "", "prod-gpt4o", "", Options{}); err == nil {
+		t.Errorf("Expected a missing endpoint to be rejected")
+	}
+	if err := (Options{APIVersion: "latest"}).Validate(); err == nil {
+		t.Errorf("Expected a malformed api version to be rejected")
+	}
+	if err := (Options{Options: openai.Options{ReasoningEffort: "max"}}).Validate(); err == nil {
+		t.Errorf("Expected the OpenAI options to be validated too")
+	}
+}
@@ -0,0 +1,86 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+type Client struct {
+	baseURL     string
+	region      string
+	credentials Credentials
+	client      *http.Client
+	now         func() time.Time
+}
+
+// NewClient creates a client for the Bedrock runtime of region. baseURL
+// replaces the regional endpoint, e.g. for a VPC endpoint.
+func NewClient(credentials Credentials, region, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region)
+	}
+	return &Client{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		region:      region,
+		credentials: credentials,
+		client:      &http.Client{},
+		now:         time.Now,
+	}
+}
+
+func (c *Client) Converse(ctx context.Context, modelID string, req ConverseRequest) (*ConverseResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	// model IDs carry colons and ARNs slashes, so the path is escaped by
+	// hand rather than left to net/url
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/model/"+escape(modelID)+"/converse", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	signRequest(httpReq, body, c.credentials, c.region, signingService, c.now())
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, models.NewNetworkError(providerName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	var response ConverseResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	return &response, nil
+}
+
+// statusError reads the error body of a failed request. The exception name
+// comes in a header, e.g. "ValidationException:http://internal.amazon.com/...".
+func statusError(resp *http.Response) error {
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Message == "" {
+		return models.NewStatusError(providerName, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	message := errResp.Message
+	if kind, _, _ := strings.Cut(resp.Header.Get("X-Amzn-Errortype"), ":"); kind != "" {
+		message = kind + ": " + message
+	}
+	return models.NewStatusError(providerName, resp.StatusCode, message)
+}
@@ -0,0 +1,368 @@
+// Package bedrock talks to models on Amazon Bedrock through the Converse
+// API, which offers every model family one request shape with tool use.
+// Requests are signed with AWS Signature Version 4.
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+const (
+	providerName     = "bedrock"
+	defaultMaxTokens = 4096
+
+	roleUser      = "user"
+	roleAssistant = "assistant"
+)
+
+var regionRe = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// Options are the per-profile settings of the bedrock provider. The
+// profile's API key is the secret access key that pairs with AccessKeyID.
+type Options struct {
+	Region      string `json:"region,omitempty"`      // e.g. us-east-1, empty reads AWS_REGION
+	AccessKeyID string `json:"accessKeyId,omitempty"` // empty reads the AWS_* credential variables
+}
+
+// Validate checks the options can be used to sign requests
+func (o Options) Validate() error {
+	if o.Region != "" && !regionRe.MatchString(o.Region) {
+		return fmt.Errorf("invalid AWS region %q, expected e.g. us-east-1", o.Region)
+	}
+	return nil
+}
+
+// resolveCredentials pairs the options with the secret key, falling back to the
+// standard AWS environment variables when no access key is configured
+func resolveCredentials(secretKey string, options Options) (Credentials, error) {
+	creds := Credentials{AccessKeyID: options.AccessKeyID, SecretAccessKey: secretKey}
+	if creds.AccessKeyID == "" {
+		creds = Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return Credentials{}, &models.ProviderError{
+			Provider: providerName,
+			Kind:     models.ErrorAuth,
+			Message:  "AWS credentials not provided: set an access key ID and use the secret key as API key, or set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
+		}
+	}
+	return creds, nil
+}
+
+// resolveRegion returns the configured region or the one of the environment
+func resolveRegion(options Options) (string, error) {
+	for _, r := range []string{options.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")} {
+		if r != "" {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("an AWS region is required: set it in the profile or AWS_REGION")
+}
+
+type Provider struct {
+	client       *Client
+	model        string
+	systemPrompt string
+	params       models.GenerationParams
+}
+
+// NewProvider creates a provider for a Bedrock model ID or inference
+// profile, e.g. anthropic.claude-3-5-sonnet-20240620-v1:0
+func NewProvider(secretKey, baseURL, model, systemPrompt string, options Options) (*Provider, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	creds, err := resolveCredentials(secretKey, options)
+	if err != nil {
+		return nil, err
+	}
+	region, err := resolveRegion(options)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{
+		client:       NewClient(creds, region, baseURL),
+		model:        model,
+		systemPrompt: systemPrompt,
+	}, nil
+}
+
+// SetSystemPrompt replaces the system prompt sent with the next requests
+func (p *Provider) SetSystemPrompt(prompt string) {
+	p.systemPrompt = prompt
+}
+
+// SetGenerationParams overrides the default temperature and token limit
+func (p *Provider) SetGenerationParams(params models.GenerationParams) {
+	p.params = params
+}
+
+func (p *Provider) CreateMessage(
+	ctx context.Context,
+	prompt string,
+	messages []models.Message,
+	tools []models.Tool,
+) (models.Message, error) {
+	slog.Debug("creating message",
+		"prompt", prompt,
+		"num_messages", len(messages),
+		"num_tools", len(tools))
+
+	req := ConverseRequest{
+		Messages: toMessages(messages, prompt, len(tools) > 0),
+		InferenceConfig: &InferenceConfig{
+			MaxTokens:   defaultMaxTokens,
+			Temperature: p.params.Temperature,
+		},
+	}
+	if p.params.MaxTokens > 0 {
+		req.InferenceConfig.MaxTokens = p.params.MaxTokens
+	}
+	if p.systemPrompt != "" {
+		req.System = []SystemBlock{{Text: p.systemPrompt}}
+	}
+	if len(tools) > 0 {
+		req.ToolConfig = &ToolConfig{}
+		for _, tool := range tools {
+			req.ToolConfig.Tools = append(req.ToolConfig.Tools, Tool{ToolSpec: ToolSpec{
+				Name:        tool.Name,
+				Description: tool.Description,
+				InputSchema: InputSchema{JSON: inputSchema(tool.InputSchema)},
+			}})
+		}
+	}
+
+	resp, err := p.client.Converse(ctx, p.model, req)
+	if err != nil {
+		return nil, err
+	}
+	return &ConverseMessage{Resp: *resp}, nil
+}
+
+// inputSchema returns the schema as a JSON object; Converse rejects a
+// missing properties object
+func inputSchema(schema models.Schema) map[string]any {
+	properties := schema.Properties
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	converted := map[string]any{"type": "object", "properties": properties}
+	if len(schema.Required) > 0 {
+		converted["required"] = schema.Required
+	}
+	return converted
+}
+
+// toMessages converts the conversation to Converse turns. Converse wants
+// alternating turns opening with the user, and every toolUse answered by a
+// toolResult in the next turn; without tools in the request it accepts
+// neither, so calls and results are then written out as text.
+func toMessages(messages []models.Message, prompt string, withTools bool) []Message {
+	var out []Message
+	add := func(role string, blocks ...ContentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Content = append(out[n-1].Content, blocks...)
+			return
+		}
+		out = append(out, Message{Role: role, Content: blocks})
+	}
+
+	for _, msg := range messages {
+		if msg.IsToolResponse() {
+			add(roleUser, toolResults(msg)...)
+			continue
+		}
+
+		role := roleUser
+		if msg.GetRole() == roleAssistant {
+			role = roleAssistant
+		}
+		var blocks []ContentBlock
+		if text := strings.TrimSpace(msg.GetContent()); text != "" {
+			blocks = append(blocks, ContentBlock{Text: text})
+		}
+		for _, call := range msg.GetToolCalls() {
+			blocks = append(blocks, ContentBlock{ToolUse: &ToolUse{
+				ToolUseID: call.GetID(),
+				Name:      call.GetName(),
+				Input:     call.GetArguments(),
+			}})
+		}
+		add(role, blocks...)
+	}
+	if prompt != "" {
+		add(roleUser, ContentBlock{Text: prompt})
+	}
+
+	for len(out) > 0 && out[0].Role != roleUser {
+		out = out[1:]
+	}
+	pairToolBlocks(out)
+	if !withTools {
+		for i := range out {
+			for j, block := range out[i].Content {
+				out[i].Content[j] = asText(block)
+			}
+		}
+	}
+	return out
+}
+
+// toolResults returns a tool message's results
+func toolResults(msg models.Message) []ContentBlock {
+	historyMsg, ok := msg.(*history.HistoryMessage)
+	if !ok {
+		return []ContentBlock{resultBlock(msg.GetToolResponseID(), msg.GetContent())}
+	}
+	var blocks []ContentBlock
+	for _, block := range historyMsg.Content {
+		if block.Type == "tool_result" {
+			blocks = append(blocks, resultBlock(block.ToolUseID, resultText(block)))
+		}
+	}
+	return blocks
+}
+
+func resultBlock(id, text string) ContentBlock {
+	if text == "" {
+		text = "No content returned from function"
+	}
+	return ContentBlock{ToolResult: &ToolResult{
+		ToolUseID: id,
+		Content:   []ToolResultContent{{Text: text}},
+		Status:    "success",
+	}}
+}
+
+// resultText is the text of a recorded tool result
+func resultText(block history.ContentBlock) string {
+	if block.Text != "" {
+		return block.Text
+	}
+	switch content := block.Content.(type) {
+	case nil:
+		return ""
+	case string:
+		return content
+	case []any:
+		var texts []string
+		for _, item := range content {
+			if m, ok := item.(map[string]any); ok && m["text"] != nil {
+				texts = append(texts, fmt.Sprint(m["text"]))
+			}
+		}
+		if len(texts) > 0 {
+			return strings.Join(texts, "\n")
+		}
+	}
+	raw, _ := json.Marshal(block.Content)
+	return string(raw)
+}
+
+// pairToolBlocks makes the turn after each toolUse answer it: calls that
+// never completed get an error result, and results no call asked for
+// become text.
+func pairToolBlocks(out []Message) {
+	calls := map[string]bool{}
+	for i := range out {
+		if out[i].Role == roleAssistant {
+			calls = map[string]bool{}
+			var pending []string
+			for _, block := range out[i].Content {
+				if block.ToolUse != nil {
+					calls[block.ToolUse.ToolUseID] = true
+					pending = append(pending, block.ToolUse.ToolUseID)
+				}
+			}
+			if len(pending) > 0 && i+1 < len(out) {
+				answered := map[string]bool{}
+				for _, block := range out[i+1].Content {
+					if block.ToolResult != nil {
+						answered[block.ToolResult.ToolUseID] = true
+					}
+				}
+				var missing []ContentBlock
+				for _, id := range pending {
+					if !answered[id] {
+						missing = append(missing, ContentBlock{ToolResult: &ToolResult{
+							ToolUseID: id,
+							Content:   []ToolResultContent{{Text: "the call did not complete"}},
+							Status:    "error",
+						}})
+					}
+				}
+				out[i+1].Content = append(missing, out[i+1].Content...)
+			}
+			continue
+		}
+
+		for j, block := range out[i].Content {
+			if block.ToolResult != nil && !calls[block.ToolResult.ToolUseID] {
+				out[i].Content[j] = asText(block)
+			}
+		}
+		calls = map[string]bool{}
+	}
+}
+
+// asText writes a tool block out as text
+func asText(block ContentBlock) ContentBlock {
+	switch {
+	case block.ToolUse != nil:
+		args, _ := json.Marshal(block.ToolUse.Input)
+		return ContentBlock{Text: fmt.Sprintf("Called tool %s with %s", block.ToolUse.Name, args)}
+	case block.ToolResult != nil:
+		var texts []string
+		for _, content := range block.ToolResult.Content {
+			texts = append(texts, content.Text)
+		}
+		return ContentBlock{Text: fmt.Sprintf("Result of tool call %s: %s", block.ToolResult.ToolUseID, strings.Join(texts, "\n"))}
+	}
+	return block
+}
+
+// CreateToolResponse wraps a tool's output as a tool result; the next
+// request turns it into the toolResult of the matching call
+func (p *Provider) CreateToolResponse(toolCallID string, content interface{}) (models.Message, error) {
+	text, ok := content.(string)
+	if !ok {
+		raw, err := json.Marshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling tool response: %w", err)
+		}
+		text = string(raw)
+	}
+	return &history.HistoryMessage{
+		Role: "tool",
+		Content: []history.ContentBlock{{
+			Type:      "tool_result",
+			ToolUseID: toolCallID,
+			Content:   content,
+			Text:      text,
+		}},
+	}, nil
+}
+
+func (p *Provider) SupportsTools() bool {
+	return true
+}
+
+func (p *Provider) Name() string {
+	return providerName
+}
@@ -0,0 +1,176 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+var testCredentials = Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+// TestSignRequest checks the signer against the get-vanilla case of the
+// AWS Signature Version 4 test suite
+func TestSignRequest(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	signRequest(req, nil, testCredentials, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Expected\n  %s\ngot\n  %s", want, got)
+	}
+}
+
+// bedrockServer stands in for the Bedrock runtime. It re-signs what it
+// receives and fails requests whose signature does not match.
+func bedrockServer(t *testing.T, reply string, got *ConverseRequest) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if want := "/model/us.anthropic.claude-sonnet-4-20250514-v1%3A0/converse"; r.URL.EscapedPath() != want {
+			t.Errorf("Expected a request to %s, got %s", want, r.URL.EscapedPath())
+		}
+
+		signed, _ := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+		check := r.Clone(context.Background())
+		check.Header.Del("Authorization")
+		check.Header.Del("Accept-Encoding") // added by the transport after signing
+		check.Header.Del("Content-Length")
+		check.Header.Del("User-Agent")
+		signRequest(check, body, testCredentials, "us-west-2", signingService, signed)
+		if r.Header.Get("Authorization") != check.Header.Get("Authorization") {
+			w.Header().Set("X-Amzn-Errortype", "InvalidSignatureException:http://internal.amazon.com/coral/com.amazon.coral.service/")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "The request signature we calculated does not match the signature you provided."}`)
+			return
+		}
+
+		if err := json.Unmarshal(body, got); err != nil {
+			t.Errorf("bad request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, reply)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestProvider(t *testing.T, baseURL string) *Provider {
+	t.Helper()
+	provider, err := NewProvider(testCredentials.SecretAccessKey, baseURL, "us.anthropic.claude-sonnet-4-20250514-v1:0",
+		"You are helpful.", Options{Region: "us-west-2", AccessKeyID: testCredentials.AccessKeyID})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	return provider
+}
+
+func TestConverseToolUse(t *testing.T) {
+	var req ConverseRequest
+	server := bedrockServer(t, `{"output": {"message": {"role": "assistant", "content": [
+		{"text": "Let me check."},
+		{"toolUse": {"toolUseId": "tooluse_2", "name": "gh__get_repo", "input": {"name": "notes"}}}]}},
+		"stopReason": "tool_use", "usage": {"inputTokens": 80, "outputTokens": 20}}`, &req)
+	provider := newTestProvider(t, server.URL)
+
+	conversation := []models.Message{
+		&history.HistoryMessage{Role: "user", Content: []history.ContentBlock{{Type: "text", Text: "List my repos"}}},
+		&history.HistoryMessage{Role: "assistant", Content: []history.ContentBlock{
+			{Type: "tool_use", ID: "tooluse_1", Name: "gh__list_repos", Input: json.RawMessage(`{"owner":"me"}`)},
+		}},
+		&history.HistoryMessage{Role: "tool", Content: []history.ContentBlock{
+			{Type: "tool_result", ToolUseID: "tooluse_1", Content: []any{map[string]any{"type": "text", "text": "spotlight, notes"}}},
+		}},
+	}
+	tools := []models.Tool{{Name: "gh__get_repo", Description: "Get a repo", InputSchema: models.Schema{Type: "object"}}}
+
+	msg, err := provider.CreateMessage(context.Background(), "Which is newer?", conversation, tools)
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+
+	turns, _ := json.Marshal(req.Messages)
+	want := `[{"role":"user","content":[{"text":"List my repos"}]},` +
+		`{"role":"assistant","content":[{"toolUse":{"toolUseId":"tooluse_1","name":"gh__list_repos","input":{"owner":"me"}}}]},` +
+		`{"role":"user","content":[{"toolResult":{"toolUseId":"tooluse_1","content":[{"text":"spotlight, notes"}],"status":"success"}},` +
+		`{"text":"Which is newer?"}]}]`
+	if string(turns) != want {
+		t.Errorf("Expected messages\n  %s\ngot\n  %s", want, turns)
+	}
+	if len(req.System) != 1 || req.System[0].Text != "You are helpful." {
+		t.Errorf("Expected the system prompt, got %+v", req.System)
+	}
+	if req.ToolConfig == nil || req.ToolConfig.Tools[0].ToolSpec.InputSchema.JSON["properties"] == nil {
+		t.Errorf("Expected the tool with a properties object, got %+v", req.ToolConfig)
+	}
+
+	calls := msg.GetToolCalls()
+	if msg.GetContent() != "Let me check." || len(calls) != 1 || calls[0].GetID() != "tooluse_2" ||
+		calls[0].GetArguments()["name"] != "notes" {
+		t.Errorf("Expected the text and the tool call, got %q and %+v", msg.GetContent(), calls)
+	}
+	if in, out := msg.GetUsage(); in != 80 || out != 20 {
+		t.Errorf("Expected usage 80/20, got %d/%d", in, out)
+	}
+}
+
+func TestConverseErrors(t *testing.T) {
+	var req ConverseRequest
+	server := bedrockServer(t, `{}`, &req)
+	provider := newTestProvider(t, server.URL)
+	provider.client.credentials.SecretAccessKey = "wrong"
+
+	_, err := provider.CreateMessage(context.Background(), "hi", nil, nil)
+	providerErr, ok := models.AsProviderError(err)
+	if !ok || providerErr.Kind != models.ErrorAuth || !strings.Contains(providerErr.Message, "InvalidSignatureException") {
+		t.Errorf("Expected a signature error, got %v", err)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	_, err = NewProvider("", "", "amazon.nova-pro-v1:0", "", Options{Region: "us-east-1"})
+	if providerErr, ok := models.AsProviderError(err); !ok || providerErr.Kind != models.ErrorAuth {
+		t.Errorf("Expected missing credentials to be an auth error, got %v", err)
+	}
+	if err := (Options{Region: "US East"}).Validate(); err == nil {
+		t.Errorf("Expected a malformed region to be rejected")
+	}
+}
+
+func TestToMessagesPairsToolBlocks(t *testing.T) {
+	var recorded []history.HistoryMessage
+	json.Unmarshal([]byte(`[
+		{"role": "tool", "content": [{"type": "tool_result", "tool_use_id": "lost", "text": "from a trimmed turn"}]},
+		{"role": "user", "content": [{"type": "text", "text": "Delete the branch"}]},
+		{"role": "assistant", "content": [{"type": "tool_use", "id": "call_d", "name": "git__delete_branch", "input": {"name": "old"}}]},
+		{"role": "user", "content": [{"type": "text", "text": "Never mind"}]}
+	]`), &recorded)
+	messages := make([]models.Message, len(recorded))
+	for i := range recorded {
+		messages[i] = &recorded[i]
+	}
+
+	withTools, _ := json.Marshal(toMessages(messages, "", true))
+	want := `[{"role":"user","content":[{"text":"Result of tool call lost: from a trimmed turn"},{"text":"Delete the branch"}]},` +
+		`{"role":"assistant","content":[{"toolUse":{"toolUseId":"call_d","name":"git__delete_branch","input":{"name":"old"}}}]},` +
+		`{"role":"user","content":[{"toolResult":{"toolUseId":"call_d","content":[{"text":"the call did not complete"}],"status":"error"}},` +
+		`{"text":"Never mind"}]}]`
+	if string(withTools) != want {
+		t.Errorf("Expected\n  %s\ngot\n  %s", want, withTools)
+	}
+
+	withoutTools, _ := json.Marshal(toMessages(messages, "", false))
+	if strings.Contains(string(withoutTools), "toolUse") || strings.Contains(string(withoutTools), "toolResult") ||
+		!strings.Contains(string(withoutTools), `Called tool git__delete_branch with {\"name\":\"old\"}`) {
+		t.Errorf("Expected tool blocks written out as text without tools, got %s", withoutTools)
+	}
+}
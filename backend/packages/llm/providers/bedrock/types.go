@@ -0,0 +1,146 @@
+package bedrock
+
+import (
+	"strings"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// ConverseRequest is the body of POST /model/{modelId}/converse
+type ConverseRequest struct {
+	Messages        []Message        `json:"messages"`
+	System          []SystemBlock    `json:"system,omitempty"`
+	InferenceConfig *InferenceConfig `json:"inferenceConfig,omitempty"`
+	ToolConfig      *ToolConfig      `json:"toolConfig,omitempty"`
+}
+
+type Message struct {
+	Role    string         `json:"role"` // "user" or "assistant"
+	Content []ContentBlock `json:"content"`
+}
+
+// ContentBlock holds exactly one of its fields
+type ContentBlock struct {
+	Text       string      `json:"text,omitempty"`
+	ToolUse    *ToolUse    `json:"toolUse,omitempty"`
+	ToolResult *ToolResult `json:"toolResult,omitempty"`
+}
+
+type ToolUse struct {
+	ToolUseID string         `json:"toolUseId"`
+	Name      string         `json:"name"`
+	Input     map[string]any `json:"input"`
+}
+
+type ToolResult struct {
+	ToolUseID string              `json:"toolUseId"`
+	Content   []ToolResultContent `json:"content"`
+	Status    string              `json:"status,omitempty"` // "success" or "error"
+}
+
+// ToolResultContent holds either text or a JSON document
+type ToolResultContent struct {
+	Text string `json:"text,omitempty"`
+	JSON any    `json:"json,omitempty"`
+}
+
+type SystemBlock struct {
+	Text string `json:"text"`
+}
+
+type InferenceConfig struct {
+	MaxTokens   int      `json:"maxTokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+type ToolConfig struct {
+	Tools []Tool `json:"tools"`
+}
+
+type Tool struct {
+	ToolSpec ToolSpec `json:"toolSpec"`
+}
+
+type ToolSpec struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema InputSchema `json:"inputSchema"`
+}
+
+// InputSchema wraps the JSON schema of a tool's arguments
+type InputSchema struct {
+	JSON map[string]any `json:"json"`
+}
+
+// ConverseResponse is the answer of POST /model/{modelId}/converse
+type ConverseResponse struct {
+	Output struct {
+		Message Message `json:"message"`
+	} `json:"output"`
+	StopReason string `json:"stopReason"`
+	Usage      struct {
+		InputTokens  int `json:"inputTokens"`
+		OutputTokens int `json:"outputTokens"`
+	} `json:"usage"`
+}
+
+// ConverseMessage implements models.Message for a Converse answer
+type ConverseMessage struct {
+	Resp ConverseResponse
+}
+
+func (m *ConverseMessage) GetRole() string {
+	return m.Resp.Output.Message.Role
+}
+
+func (m *ConverseMessage) GetContent() string {
+	var texts []string
+	for _, block := range m.Resp.Output.Message.Content {
+		if block.Text != "" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func (m *ConverseMessage) GetToolCalls() []models.ToolCall {
+	var calls []models.ToolCall
+	for _, block := range m.Resp.Output.Message.Content {
+		if block.ToolUse != nil {
+			calls = append(calls, &ToolCall{*block.ToolUse})
+		}
+	}
+	return calls
+}
+
+func (m *ConverseMessage) IsToolResponse() bool {
+	return false
+}
+
+func (m *ConverseMessage) GetToolResponseID() string {
+	return ""
+}
+
+func (m *ConverseMessage) GetUsage() (int, int) {
+	return m.Resp.Usage.InputTokens, m.Resp.Usage.OutputTokens
+}
+
+// ToolCall implements models.ToolCall for a toolUse block
+type ToolCall struct {
+	Use ToolUse
+}
+
+func (t *ToolCall) GetName() string {
+	return t.Use.Name
+}
+
+func (t *ToolCall) GetArguments() map[string]interface{} {
+	if t.Use.Input == nil {
+		return map[string]interface{}{}
+	}
+	return t.Use.Input
+}
+
+func (t *ToolCall) GetID() string {
+	return t.Use.ToolUseID
+}
@@ -0,0 +1,377 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// pendingCall is a function call of the latest model turn awaiting its result
+type pendingCall struct {
+	id       string
+	name     string
+	answered bool
+}
+
+// toolResult is one result of a tool message, before it is matched to its call
+type toolResult struct {
+	id   string
+	text string
+}
+
+// historyConverter turns the conversation into Gemini contents. Gemini has
+// no call IDs: the function responses that follow a model turn must name
+// its function calls, one each and in the same order.
+type historyConverter struct {
+	contents  []*genai.Content
+	calls     []pendingCall
+	responses map[int]genai.FunctionResponse // call index -> response
+	unmatched []genai.Part                   // results no call of the turn asked for
+}
+
+// toContents converts messages to Gemini contents
+func toContents(messages []models.Message) []*genai.Content {
+	c := &historyConverter{}
+	for _, msg := range messages {
+		if msg.IsToolResponse() {
+			for _, result := range toolResults(msg) {
+				c.addResult(result)
+			}
+			continue
+		}
+		c.flushResponses()
+
+		role := mappingRole(msg.GetRole())
+		var parts []genai.Part
+		if text := strings.TrimSpace(msg.GetContent()); text != "" {
+			parts = append(parts, genai.Text(text))
+		}
+		if role == roleModel {
+			for _, call := range msg.GetToolCalls() {
+				parts = append(parts, genai.FunctionCall{Name: call.GetName(), Args: call.GetArguments()})
+				c.calls = append(c.calls, pendingCall{id: call.GetID(), name: call.GetName()})
+			}
+		}
+		c.append(role, parts...)
+	}
+	c.flushResponses()
+	return c.contents
+}
+
+// toolResults returns the results a tool message carries
+func toolResults(msg models.Message) []toolResult {
+	historyMsg, ok := msg.(*history.HistoryMessage)
+	if !ok {
+		return []toolResult{{id: msg.GetToolResponseID(), text: msg.GetContent()}}
+	}
+	var results []toolResult
+	for _, block := range historyMsg.Content {
+		if block.Type != "tool_result" {
+			continue
+		}
+		text := block.Text
+		if text == "" && block.Content != nil {
+			raw, _ := json.Marshal(block.Content)
+			text = string(raw)
+		}
+		results = append(results, toolResult{id: block.ToolUseID, text: text})
+	}
+	return results
+}
+
+// addResult matches a result to its call by ID, or to the first call still
+// waiting when the ID is unknown, e.g. one recorded by another provider
+func (c *historyConverter) addResult(result toolResult) {
+	match := -1
+	for i, call := range c.calls {
+		if !call.answered && call.id == result.id {
+			match = i
+			break
+		}
+	}
+	if match < 0 {
+		for i, call := range c.calls {
+			if !call.answered {
+				match = i
+				break
+			}
+		}
+	}
+	if match < 0 {
+		// Gemini rejects a response no call asked for; keep what it said
+		c.unmatched = append(c.unmatched, genai.Text(fmt.Sprintf("Result of tool call %s: %s", result.id, result.text)))
+		return
+	}
+
+	c.calls[match].answered = true
+	if c.responses == nil {
+		c.responses = make(map[int]genai.FunctionResponse)
+	}
+	c.responses[match] = genai.FunctionResponse{
+		Name:     c.calls[match].name,
+		Response: responsePayload(result.text),
+	}
+}
+
+// flushResponses closes the model turn: every call gets its response, in
+// call order, with calls that never completed answered as such
+func (c *historyConverter) flushResponses() {
+	if len(c.calls) == 0 && len(c.unmatched) == 0 {
+		return
+	}
+	parts := make([]genai.Part, 0, len(c.calls)+len(c.unmatched))
+	for i, call := range c.calls {
+		response, ok := c.responses[i]
+		if !ok {
+			response = genai.FunctionResponse{
+				Name:     call.name,
+				Response: map[string]any{"error": "the call did not complete"},
+			}
+		}
+		parts = append(parts, response)
+	}
+	parts = append(parts, c.unmatched...)
+	c.append(roleUser, parts...)
+
+	c.calls, c.responses, c.unmatched = nil, nil, nil
+}
+
+// append adds parts as a turn of role, joining the previous turn if it has
+// the same role so user and model keep alternating
+func (c *historyConverter) append(role string, parts ...genai.Part) {
+	if len(parts) == 0 {
+		return
+	}
+	if n := len(c.contents); n > 0 && c.contents[n-1].Role == role {
+		c.contents[n-1].Parts = append(c.contents[n-1].Parts, parts...)
+		return
+	}
+	c.contents = append(c.contents, &genai.Content{Role: role, Parts: parts})
+}
+
+// responsePayload wraps a tool's output in the object Gemini expects. A
+// result that is a JSON object already is passed as is.
+func responsePayload(text string) map[string]any {
+	var object map[string]any
+	if err := json.Unmarshal([]byte(text), &object); err == nil && object != nil {
+		return object
+	}
+	return map[string]any{"content": text}
+}
+
+// translateToGoogleSchema converts a tool's input schema. Gemini supports an
+// OpenAPI subset, so what it cannot express is approximated.
+func translateToGoogleSchema(schema models.Schema) *genai.Schema {
+	s := &genai.Schema{
+		Type:       genai.TypeObject,
+		Properties: make(map[string]*genai.Schema),
+	}
+	for name, prop := range schema.Properties {
+		m, ok := prop.(map[string]any)
+		if !ok {
+			continue
+		}
+		s.Properties[name] = propertyToGoogleSchema(m)
+	}
+	s.Required = knownRequired(schema.Required, s.Properties)
+
+	if len(s.Properties) == 0 {
+		// Functions that don't take any arguments have an object-type schema with 0 properties.
+		// Google/Gemini does not like that: Error 400: * GenerateContentRequest properties: should be non-empty for OBJECT type.
+		// To work around this issue, we'll just inject some unused, nullable property with a primitive type.
+		s.Nullable = true
+		s.Properties["unused"] = &genai.Schema{
+			Type:     genai.TypeInteger,
+			Nullable: true,
+		}
+	}
+	return s
+}
+
+// propertyToGoogleSchema converts a JSON Schema property
+func propertyToGoogleSchema(property map[string]any) *genai.Schema {
+	// anyOf / oneOf: Gemini takes one type, use the first that is not null
+	for _, key := range []string{"anyOf", "oneOf"} {
+		alternatives, ok := property[key].([]any)
+		if !ok || property["type"] != nil {
+			continue
+		}
+		var chosen *genai.Schema
+		nullable := false
+		for _, alternative := range alternatives {
+			m, ok := alternative.(map[string]any)
+			if !ok {
+				continue
+			}
+			if m["type"] == "null" {
+				nullable = true
+				continue
+			}
+			if chosen == nil {
+				chosen = propertyToGoogleSchema(m)
+			}
+		}
+		if chosen == nil {
+			chosen = &genai.Schema{Type: genai.TypeString}
+		}
+		chosen.Nullable = chosen.Nullable || nullable
+		if desc, ok := property["description"].(string); ok {
+			chosen.Description = desc
+		}
+		return chosen
+	}
+
+	typ, nullable := schemaType(property)
+	s := &genai.Schema{Type: toType(typ), Nullable: nullable}
+	if n, ok := property["nullable"].(bool); ok && n {
+		s.Nullable = true
+	}
+	if desc, ok := property["description"].(string); ok {
+		s.Description = desc
+	}
+	if format, ok := property["format"].(string); ok && supportedFormat(s.Type, format) {
+		s.Format = format
+	}
+
+	if enum := enumValues(property["enum"]); len(enum) > 0 {
+		if s.Type == genai.TypeString {
+			s.Format = "enum"
+			s.Enum = enum
+		} else {
+			// Gemini only restricts strings; tell the model instead
+			s.Description = strings.TrimSpace(s.Description + " One of: " + strings.Join(enum, ", ") + ".")
+		}
+	}
+
+	// Objects and arrays need to have their properties recursively mapped.
+	switch s.Type {
+	case genai.TypeObject:
+		s.Properties = make(map[string]*genai.Schema)
+		if properties, ok := property["properties"].(map[string]any); ok {
+			for name, prop := range properties {
+				if m, ok := prop.(map[string]any); ok {
+					s.Properties[name] = propertyToGoogleSchema(m)
+				}
+			}
+		}
+		s.Required = knownRequired(stringList(property["required"]), s.Properties)
+		if len(s.Properties) == 0 {
+			// a free-form object; Gemini needs a property, same as at the top level
+			s.Properties["unused"] = &genai.Schema{Type: genai.TypeInteger, Nullable: true}
+		}
+	case genai.TypeArray:
+		if items, ok := property["items"].(map[string]any); ok {
+			s.Items = propertyToGoogleSchema(items)
+		} else {
+			s.Items = &genai.Schema{Type: genai.TypeString}
+		}
+	}
+	return s
+}
+
+// schemaType returns a property's type, inferring it when the schema leaves
+// it out, and whether null is allowed as in "type": ["string", "null"]
+func schemaType(property map[string]any) (string, bool) {
+	switch typ := property["type"].(type) {
+	case string:
+		return typ, false
+	case []any:
+		chosen, nullable := "", false
+		for _, t := range typ {
+			if t == "null" {
+				nullable = true
+			} else if s, ok := t.(string); ok && chosen == "" {
+				chosen = s
+			}
+		}
+		if chosen != "" {
+			return chosen, nullable
+		}
+		return "string", nullable
+	}
+	switch {
+	case property["properties"] != nil:
+		return "object", false
+	case property["items"] != nil:
+		return "array", false
+	}
+	return "string", false
+}
+
+// supportedFormat reports whether Gemini accepts format for the type
+func supportedFormat(typ genai.Type, format string) bool {
+	switch typ {
+	case genai.TypeString:
+		return format == "date-time"
+	case genai.TypeInteger:
+		return format == "int32" || format == "int64"
+	case genai.TypeNumber:
+		return format == "float" || format == "double"
+	}
+	return false
+}
+
+// knownRequired drops required names without a property, which Gemini rejects
+func knownRequired(required []string, properties map[string]*genai.Schema) []string {
+	var known []string
+	for _, name := range required {
+		if _, ok := properties[name]; ok {
+			known = append(known, name)
+		}
+	}
+	return known
+}
+
+// enumValues returns the allowed values of an enum as strings
+func enumValues(v any) []string {
+	if values, ok := v.([]any); ok {
+		enum := make([]string, 0, len(values))
+		for _, value := range values {
+			if value != nil {
+				enum = append(enum, fmt.Sprint(value))
+			}
+		}
+		return enum
+	}
+	return stringList(v)
+}
+
+func stringList(v any) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []any:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func toType(typ string) genai.Type {
+	switch typ {
+	case "string":
+		return genai.TypeString
+	case "number":
+		return genai.TypeNumber
+	case "integer":
+		return genai.TypeInteger
+	case "boolean":
+		return genai.TypeBoolean
+	case "object":
+		return genai.TypeObject
+	case "array":
+		return genai.TypeArray
+	default:
+		return genai.TypeString
+	}
+}
@@ -0,0 +1,252 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// describe renders contents one turn per line, e.g.
+// `model: call weather {"city":"Paris"} | text "done"`
+func describe(contents []*genai.Content) []string {
+	var turns []string
+	for _, content := range contents {
+		var parts []string
+		for _, part := range content.Parts {
+			switch p := part.(type) {
+			case genai.Text:
+				parts = append(parts, fmt.Sprintf("text %q", string(p)))
+			case genai.FunctionCall:
+				args, _ := json.Marshal(p.Args)
+				parts = append(parts, fmt.Sprintf("call %s %s", p.Name, args))
+			case genai.FunctionResponse:
+				response, _ := json.Marshal(p.Response)
+				parts = append(parts, fmt.Sprintf("response %s %s", p.Name, response))
+			default:
+				parts = append(parts, fmt.Sprintf("%T", p))
+			}
+		}
+		turns = append(turns, content.Role+": "+strings.Join(parts, " | "))
+	}
+	return turns
+}
+
+func TestToContents(t *testing.T) {
+	tests := []struct {
+		name       string
+		transcript string // history as the MCP service records it
+		want       []string
+	}{
+		{
+			name: "single call",
+			transcript: `[
+				{"role": "user", "content": [{"type": "text", "text": "What's the weather in Paris?"}]},
+				{"role": "assistant", "content": [
+					{"type": "tool_use", "id": "call_a", "name": "weather__current", "input": {"city": "Paris"}}]},
+				{"role": "tool", "content": [{"type": "tool_result", "tool_use_id": "call_a", "text": "18°C, cloudy"}]}
+			]`,
+			want: []string{
+				`user: text "What's the weather in Paris?"`,
+				`model: call weather__current {"city":"Paris"}`,
+				`user: response weather__current {"content":"18°C, cloudy"}`,
+			},
+		},
+		{
+			name: "parallel calls answered out of order",
+			transcript: `[
+				{"role": "user", "content": [{"type": "text", "text": "Compare Paris and Rome"}]},
+				{"role": "assistant", "content": [
+					{"type": "text", "text": "Checking both."},
+					{"type": "tool_use", "id": "call_p", "name": "weather__current", "input": {"city": "Paris"}},
+					{"type": "tool_use", "id": "call_r", "name": "weather__forecast", "input": {"city": "Rome"}}]},
+				{"role": "tool", "content": [{"type": "tool_result", "tool_use_id": "call_r", "text": "{\"high\": 24}"}]},
+				{"role": "tool", "content": [{"type": "tool_result", "tool_use_id": "call_p", "text": "18°C"}]}
+			]`,
+			want: []string{
+				`user: text "Compare Paris and Rome"`,
+				`model: text "Checking both." | call weather__current {"city":"Paris"} | call weather__forecast {"city":"Rome"}`,
+				`user: response weather__current {"content":"18°C"} | response weather__forecast {"high":24}`,
+			},
+		},
+		{
+			name: "IDs reused across turns",
+			transcript: `[
+				{"role": "user", "content": [{"type": "text", "text": "List files, then read one"}]},
+				{"role": "assistant", "content": [{"type": "tool_use", "id": "Tool<0>", "name": "fs__list", "input": {}}]},
+				{"role": "tool", "content": [{"type": "tool_result", "tool_use_id": "Tool<0>",
+					"content": [{"type": "text", "text": "a.txt"}]}]},
+				{"role": "assistant", "content": [{"type": "tool_use", "id": "Tool<0>", "name": "fs__read", "input": {"path": "a.txt"}}]},
+				{"role": "tool", "content": [{"type": "tool_result", "tool_use_id": "Tool<0>", "text": "hello"}]}
+			]`,
+			want: []string{
+				`user: text "List files, then read one"`,
+				`model: call fs__list {}`,
+				`user: response fs__list {"content":"[{\"text\":\"a.txt\",\"type\":\"text\"}]"}`,
+				`model: call fs__read {"path":"a.txt"}`,
+				`user: response fs__read {"content":"hello"}`,
+			},
+		},
+		{
+			name: "call aborted before it ran",
+			transcript: `[
+				{"role": "user", "content": [{"type": "text", "text": "Delete the branch"}]},
+				{"role": "assistant", "content": [{"type": "tool_use", "id": "call_d", "name": "git__delete_branch", "input": {"name": "old"}}]},
+				{"role": "user", "content": [{"type": "text", "text": "Never mind, what branches exist?"}]}
+			]`,
+			want: []string{
+				`user: text "Delete the branch"`,
+				`model: call git__delete_branch {"name":"old"}`,
+				`user: response git__delete_branch {"error":"the call did not complete"} | text "Never mind, what branches exist?"`,
+			},
+		},
+		{
+			name: "result without a call",
+			transcript: `[
+				{"role": "user", "content": [{"type": "text", "text": "hi"}]},
+				{"role": "assistant", "content": [{"type": "text", "text": "Hello!"}]},
+				{"role": "tool", "content": [{"type": "tool_result", "tool_use_id": "orphan", "text": "stale"}]},
+				{"role": "user", "content": [{"type": "text", "text": "thanks"}]}
+			]`,
+			want: []string{
+				`user: text "hi"`,
+				`model: text "Hello!"`,
+				`user: text "Result of tool call orphan: stale" | text "thanks"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recorded []history.HistoryMessage
+			if err := json.Unmarshal([]byte(tt.transcript), &recorded); err != nil {
+				t.Fatalf("bad transcript: %v", err)
+			}
+			messages := make([]models.Message, len(recorded))
+			for i := range recorded {
+				messages[i] = &recorded[i]
+			}
+
+			got := describe(toContents(messages))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected\n  %s\ngot\n  %s", strings.Join(tt.want, "\n  "), strings.Join(got, "\n  "))
+			}
+		})
+	}
+}
+
+func TestMessageKeepsCallIDs(t *testing.T) {
+	msg := newMessage(&genai.Candidate{Content: &genai.Content{Role: roleModel, Parts: []genai.Part{
+		genai.FunctionCall{Name: "a", Args: map[string]any{}},
+		genai.FunctionCall{Name: "b", Args: map[string]any{}},
+	}}})
+
+	first, second := msg.GetToolCalls(), msg.GetToolCalls()
+	if len(first) != 2 || first[0].GetID() != second[0].GetID() || first[0].GetID() == first[1].GetID() {
+		t.Errorf("Expected stable, distinct call IDs")
+	}
+	if msg.GetRole() != "assistant" {
+		t.Errorf("Expected model turns to be stored as assistant, got %q", msg.GetRole())
+	}
+
+	resp, err := (&Provider{}).CreateToolResponse(first[1].GetID(), map[string]any{"ok": true})
+	if err != nil {
+		t.Fatalf("CreateToolResponse: %v", err)
+	}
+	if !resp.IsToolResponse() || resp.GetToolResponseID() != first[1].GetID() {
+		t.Errorf("Expected a tool result for the second call, got %+v", resp)
+	}
+}
+
+func TestTranslateToGoogleSchema(t *testing.T) {
+	nullableInt := &genai.Schema{Type: genai.TypeInteger, Nullable: true}
+	tests := []struct {
+		name     string
+		property string
+		want     *genai.Schema
+	}{
+		{
+			name:     "string enum",
+			property: `{"type": "string", "enum": ["asc", "desc"], "description": "Sort order"}`,
+			want:     &genai.Schema{Type: genai.TypeString, Format: "enum", Enum: []string{"asc", "desc"}, Description: "Sort order"},
+		},
+		{
+			name:     "integer enum",
+			property: `{"type": "integer", "enum": [1, 2, 3]}`,
+			want:     &genai.Schema{Type: genai.TypeInteger, Description: "One of: 1, 2, 3."},
+		},
+		{
+			name:     "nullable type list",
+			property: `{"type": ["string", "null"], "format": "date-time"}`,
+			want:     &genai.Schema{Type: genai.TypeString, Nullable: true, Format: "date-time"},
+		},
+		{
+			name:     "unsupported format dropped",
+			property: `{"type": "string", "format": "uri"}`,
+			want:     &genai.Schema{Type: genai.TypeString},
+		},
+		{
+			name:     "anyOf with null",
+			property: `{"anyOf": [{"type": "null"}, {"type": "number", "format": "double"}], "description": "Limit"}`,
+			want:     &genai.Schema{Type: genai.TypeNumber, Format: "double", Nullable: true, Description: "Limit"},
+		},
+		{
+			name: "array of objects",
+			property: `{"type": "array", "items": {"type": "object",
+				"properties": {"path": {"type": "string"}, "tags": {"type": "array"}},
+				"required": ["path", "missing"]}}`,
+			want: &genai.Schema{Type: genai.TypeArray, Items: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"path": {Type: genai.TypeString},
+					"tags": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+				},
+				Required: []string{"path"},
+			}},
+		},
+		{
+			name:     "free-form object",
+			property: `{"type": "object", "additionalProperties": true}`,
+			want:     &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{"unused": nullableInt}},
+		},
+		{
+			name:     "type inferred",
+			property: `{"properties": {"q": {"description": "Query"}}}`,
+			want: &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{
+				"q": {Type: genai.TypeString, Description: "Query"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var property map[string]any
+			if err := json.Unmarshal([]byte(tt.property), &property); err != nil {
+				t.Fatalf("bad property: %v", err)
+			}
+			schema := translateToGoogleSchema(models.Schema{
+				Type:       "object",
+				Properties: map[string]any{"arg": property},
+				Required:   []string{"arg"},
+			})
+			if !reflect.DeepEqual(schema.Required, []string{"arg"}) {
+				t.Errorf("Expected arg to stay required, got %v", schema.Required)
+			}
+			if got := schema.Properties["arg"]; !reflect.DeepEqual(got, tt.want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(tt.want)
+				t.Errorf("Expected %s, got %s", wantJSON, gotJSON)
+			}
+		})
+	}
+
+	if empty := translateToGoogleSchema(models.Schema{Type: "object"}); empty.Properties["unused"] == nil {
+		t.Errorf("Expected a schema without arguments to get a placeholder property")
+	}
+}
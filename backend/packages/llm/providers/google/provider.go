@@ -2,10 +2,18 @@ package google
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"smart-spotlight-ai/backend/packages/llm/history"
 	"smart-spotlight-ai/backend/packages/llm/models"
@@ -13,12 +21,11 @@ import (
 	"google.golang.org/api/option"
 )
 
+const providerName = "google"
+
 type Provider struct {
 	client *genai.Client
 	model  *genai.GenerativeModel
-	chat   *genai.ChatSession
-
-	toolCallID int
 }
 
 func NewProvider(ctx context.Context, apiKey, model, systemPrompt string) (*Provider, error) {
@@ -34,84 +41,143 @@ func NewProvider(ctx context.Context, apiKey, model, systemPrompt string) (*Prov
 	return &Provider{
 		client: client,
 		model:  m,
-		chat:   m.StartChat(),
 	}, nil
 }
 
-func (p *Provider) CreateMessage(ctx context.Context, prompt string, messages []models.Message, tools []models.Tool) (models.Message, error) {
-	var hist []*genai.Content
-	for _, msg := range messages {
-		for _, call := range msg.GetToolCalls() {
-			hist = append(hist, &genai.Content{
-				Role: mappingRole(msg.GetRole()),
-				Parts: []genai.Part{
-					genai.FunctionCall{
-						Name: call.GetName(),
-						Args: call.GetArguments(),
-					},
-				},
-			})
-		}
+// SetSystemPrompt replaces the system instruction sent with the next requests
+func (p *Provider) SetSystemPrompt(prompt string) {
+	if prompt == "" {
+		p.model.SystemInstruction = nil
+		return
+	}
+	p.model.SystemInstruction = genai.NewUserContent(genai.Text(prompt))
+}
 
-		if msg.IsToolResponse() {
-			if historyMsg, ok := msg.(*history.HistoryMessage); ok {
-				for _, block := range historyMsg.Content {
-					if block.Type == "tool_result" {
-						hist = append(hist, &genai.Content{
-							Role:  mappingRole(msg.GetRole()),
-							Parts: []genai.Part{genai.Text(block.Text)},
-						})
-					}
-				}
-			}
+// SetGenerationParams overrides the model's default temperature and token limit
+func (p *Provider) SetGenerationParams(params models.GenerationParams) {
+	p.model.Temperature = nil
+	p.model.MaxOutputTokens = nil
+	if params.Temperature != nil {
+		p.model.SetTemperature(float32(*params.Temperature))
+	}
+	if params.MaxTokens > 0 {
+		p.model.SetMaxOutputTokens(int32(params.MaxTokens))
+	}
+}
+
+// grpcStatus maps the gRPC codes Gemini answers with to HTTP statuses
+var grpcStatus = map[codes.Code]int{
+	codes.Unauthenticated:   http.StatusUnauthorized,
+	codes.PermissionDenied:  http.StatusForbidden,
+	codes.ResourceExhausted: http.StatusTooManyRequests,
+	codes.Unavailable:       http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:  http.StatusGatewayTimeout,
+	codes.Internal:          http.StatusInternalServerError,
+	codes.InvalidArgument:   http.StatusBadRequest,
+	codes.NotFound:          http.StatusNotFound,
+}
+
+// providerError classifies an error from the Gemini client
+func providerError(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return models.NewStatusError(providerName, apiErr.Code, apiErr.Message)
+	}
+	if st, ok := status.FromError(err); ok {
+		if code, known := grpcStatus[st.Code()]; known {
+			return models.NewStatusError(providerName, code, st.Message())
 		}
+	}
+	return err
+}
 
-		if text := strings.TrimSpace(msg.GetContent()); text != "" {
-			hist = append(hist, &genai.Content{
-				Role:  mappingRole(msg.GetRole()),
-				Parts: []genai.Part{genai.Text(text)},
-			})
+// ListModels returns the models that can generate content, with their token
+// limits and whether they stream
+func (p *Provider) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	var infos []models.ModelInfo
+	it := p.client.ListModels(ctx)
+	for {
+		model, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return infos, nil
+		}
+		if err != nil {
+			return nil, providerError(err)
+		}
+		if !slices.Contains(model.SupportedGenerationMethods, "generateContent") {
+			continue // embedding and other non-chat models
 		}
+		streaming := slices.Contains(model.SupportedGenerationMethods, "streamGenerateContent")
+		infos = append(infos, models.ModelInfo{
+			ID:              strings.TrimPrefix(model.Name, "models/"),
+			DisplayName:     model.DisplayName,
+			ContextWindow:   int(model.InputTokenLimit),
+			MaxOutputTokens: int(model.OutputTokenLimit),
+			Streaming:       &streaming,
+		})
+	}
+}
+
+func (p *Provider) CreateMessage(ctx context.Context, prompt string, messages []models.Message, tools []models.Tool) (models.Message, error) {
+	contents := toContents(messages)
+	if prompt = strings.TrimSpace(prompt); prompt != "" {
+		contents = append(contents, genai.NewUserContent(genai.Text(prompt)))
+	}
+	if len(contents) == 0 || contents[len(contents)-1].Role != roleUser {
+		return nil, fmt.Errorf("conversation must end with a user turn")
 	}
 
 	p.model.Tools = nil
-	for _, tool := range tools {
-		p.model.Tools = append(p.model.Tools, &genai.Tool{
-			FunctionDeclarations: []*genai.FunctionDeclaration{
-				{
-					Name:        tool.Name,
-					Description: tool.Description,
-					Parameters:  translateToGoogleSchema(tool.InputSchema),
-				},
-			},
-		})
+	if len(tools) > 0 {
+		declarations := make([]*genai.FunctionDeclaration, 0, len(tools))
+		for _, tool := range tools {
+			declarations = append(declarations, &genai.FunctionDeclaration{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  translateToGoogleSchema(tool.InputSchema),
+			})
+		}
+		p.model.Tools = []*genai.Tool{{FunctionDeclarations: declarations}}
 	}
 
-	p.chat.History = hist
-	// The provided messages slice (and thus history) already includes the new prompt,
-	// so we just call SendMessage with an empty string that will be trimmed by the server.
-	resp, err := p.chat.SendMessage(ctx, genai.Text(""))
+	// The chat sends its history followed by the last turn, which holds the
+	// new prompt or the function responses the model is waiting for
+	last := contents[len(contents)-1]
+	chat := p.model.StartChat()
+	chat.History = contents[:len(contents)-1]
+	resp, err := chat.SendMessage(ctx, last.Parts...)
 	if err != nil {
-		return nil, err
+		return nil, providerError(err)
 	}
 
-	if len(resp.Candidates) == 0 {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
 		return nil, fmt.Errorf("no response from model")
 	}
 
 	// The library enforces a generation config with 1 candidate.
-	m := &Message{
-		Candidate:  resp.Candidates[0],
-		toolCallID: p.toolCallID,
-	}
-
-	p.toolCallID += len(m.Candidate.FunctionCalls())
-	return m, nil
+	return newMessage(resp.Candidates[0]), nil
 }
 
+// CreateToolResponse wraps a tool's output as a tool result; the next
+// request turns it into the FunctionResponse of the matching call
 func (p *Provider) CreateToolResponse(toolCallID string, content any) (models.Message, error) {
-	// UNUSED: Nothing in root.go calls this.
-	return nil, nil
+	text, ok := content.(string)
+	if !ok {
+		raw, err := json.Marshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling tool response: %w", err)
+		}
+		text = string(raw)
+	}
+	return &history.HistoryMessage{
+		Role: "tool",
+		Content: []history.ContentBlock{{
+			Type:      "tool_result",
+			ToolUseID: toolCallID,
+			Content:   content,
+			Text:      text,
+		}},
+	}, nil
 }
 
 func (p *Provider) SupportsTools() bool {
@@ -120,79 +186,7 @@ func (p *Provider) SupportsTools() bool {
 }
 
 func (p *Provider) Name() string {
-	return "Google"
-}
-
-func translateToGoogleSchema(schema models.Schema) *genai.Schema {
-	s := &genai.Schema{
-		Type:       toType(schema.Type),
-		Required:   schema.Required,
-		Properties: make(map[string]*genai.Schema),
-	}
-
-	for name, prop := range schema.Properties {
-		m, ok := prop.(map[string]any)
-		if !ok || len(m) == 0 {
-			continue
-		}
-		s.Properties[name] = propertyToGoogleSchema(m)
-	}
-
-	if len(s.Properties) == 0 {
-		// Functions that don't take any arguments have an object-type schema with 0 properties.
-		// Google/Gemini does not like that: Error 400: * GenerateContentRequest properties: should be non-empty for OBJECT type.
-		// To work around this issue, we'll just inject some unused, nullable property with a primitive type.
-		s.Nullable = true
-		s.Properties["unused"] = &genai.Schema{
-			Type:     genai.TypeInteger,
-			Nullable: true,
-		}
-	}
-	return s
-}
-
-func propertyToGoogleSchema(properties map[string]any) *genai.Schema {
-	typ, ok := properties["type"].(string)
-	if !ok {
-		return nil
-	}
-	s := &genai.Schema{Type: toType(typ)}
-	if desc, ok := properties["description"].(string); ok {
-		s.Description = desc
-	}
-
-	// Objects and arrays need to have their properties recursively mapped.
-	if s.Type == genai.TypeObject {
-		objectProperties := properties["properties"].(map[string]any)
-		s.Properties = make(map[string]*genai.Schema)
-		for name, prop := range objectProperties {
-			s.Properties[name] = propertyToGoogleSchema(prop.(map[string]any))
-		}
-	} else if s.Type == genai.TypeArray {
-		itemProperties := properties["items"].(map[string]any)
-		s.Items = propertyToGoogleSchema(itemProperties)
-	}
-
-	return s
-}
-
-func toType(typ string) genai.Type {
-	switch typ {
-	case "string":
-		return genai.TypeString
-	case "number":
-		return genai.TypeNumber
-	case "integer":
-		return genai.TypeInteger
-	case "boolean":
-		return genai.TypeBoolean
-	case "object":
-		return genai.TypeObject
-	case "array":
-		return genai.TypeArray
-	default:
-		return genai.TypeUnspecified
-	}
+	return providerName
 }
 
 const (
@@ -201,8 +195,9 @@ const (
 )
 
 var roleMap = map[string]string{
-	roleUser:  roleUser,
-	roleModel: roleModel,
+	roleUser:    roleUser,
+	roleModel:   roleModel,
+	"assistant": roleModel, // turns another provider answered
 }
 
 func mappingRole(role string) string {
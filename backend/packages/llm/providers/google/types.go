@@ -1,18 +1,18 @@
 package google
 
 import (
-	"fmt"
 	"strings"
 
 	"smart-spotlight-ai/backend/packages/llm/models"
 
 	"github.com/google/generative-ai-go/genai"
+	"github.com/google/uuid"
 )
 
 type ToolCall struct {
 	genai.FunctionCall
 
-	toolCallID int
+	id string
 }
 
 func (t *ToolCall) GetName() string {
@@ -23,17 +23,34 @@ func (t *ToolCall) GetArguments() map[string]any {
 	return t.Args
 }
 
+// GetID returns the ID the call was given when it arrived. Gemini has no
+// call IDs, so results are matched back to calls by their order.
 func (t *ToolCall) GetID() string {
-	return fmt.Sprintf("Tool<%d>", t.toolCallID)
+	return t.id
 }
 
 type Message struct {
 	*genai.Candidate
 
-	toolCallID int
+	callIDs []string
 }
 
+// newMessage wraps a candidate, giving each function call an ID that stays
+// the same however often the calls are read
+func newMessage(candidate *genai.Candidate) *Message {
+	m := &Message{Candidate: candidate}
+	for range candidate.FunctionCalls() {
+		m.callIDs = append(m.callIDs, "call_"+uuid.NewString())
+	}
+	return m
+}
+
+// GetRole reports model turns as "assistant" so the stored conversation
+// reads the same to every provider
 func (m *Message) GetRole() string {
+	if m.Candidate.Content.Role == roleModel {
+		return "assistant"
+	}
 	return m.Candidate.Content.Role
 }
 
@@ -50,22 +67,23 @@ func (m *Message) GetContent() string {
 func (m *Message) GetToolCalls() []models.ToolCall {
 	var calls []models.ToolCall
 	for i, call := range m.Candidate.FunctionCalls() {
-		calls = append(calls, &ToolCall{call, m.toolCallID + i})
+		calls = append(calls, &ToolCall{call, m.callIDs[i]})
 	}
 	return calls
 }
 
 func (m *Message) IsToolResponse() bool {
 	for _, part := range m.Candidate.Content.Parts {
-		if _, ok := part.(*genai.FunctionResponse); ok {
+		if _, ok := part.(genai.FunctionResponse); ok {
 			return true
 		}
 	}
 	return false
 }
 
+// GetToolResponseID returns "": responses the model writes name no call
 func (m *Message) GetToolResponseID() string {
-	return fmt.Sprintf("Tool<%d>", m.toolCallID)
+	return ""
 }
 
 func (m *Message) GetUsage() (input int, output int) {
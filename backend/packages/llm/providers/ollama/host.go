@@ -0,0 +1,172 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	api "github.com/ollama/ollama/api"
+)
+
+// Options are the per-profile settings Ollama takes beyond the generation
+// parameters every provider shares
+type Options struct {
+	NumCtx    int    `json:"numCtx,omitempty"`    // context window to load the model with, 0 keeps the server default
+	KeepAlive string `json:"keepAlive,omitempty"` // how long the model stays loaded, e.g. "10m", "-1" for ever
+}
+
+// Validate checks the options can be sent to the server
+func (o Options) Validate() error {
+	if o.NumCtx < 0 {
+		return fmt.Errorf("num_ctx cannot be negative")
+	}
+	if _, err := parseKeepAlive(o.KeepAlive); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseKeepAlive accepts what the Ollama server does: a duration, or a
+// number of seconds where a negative one keeps the model loaded for ever
+func parseKeepAlive(value string) (*api.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return &api.Duration{Duration: time.Duration(seconds) * time.Second}, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keep_alive %q, expected a duration such as 5m or seconds", value)
+	}
+	return &api.Duration{Duration: d}, nil
+}
+
+// keepAlive returns the validated keep_alive, nil for the server default
+func (o Options) keepAlive() *api.Duration {
+	d, _ := parseKeepAlive(o.KeepAlive)
+	return d
+}
+
+// newClient connects to the server at baseURL, or the one OLLAMA_HOST names
+// when baseURL is empty
+func newClient(baseURL string) (*api.Client, error) {
+	if baseURL == "" {
+		return api.ClientFromEnvironment()
+	}
+	base, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid Ollama endpoint %q", baseURL)
+	}
+	return api.NewClient(base, http.DefaultClient), nil
+}
+
+// LocalModel is a model pulled onto the server
+type LocalModel struct {
+	Name              string    `json:"name"`
+	Size              int64     `json:"size"`
+	ModifiedAt        time.Time `json:"modifiedAt"`
+	Family            string    `json:"family,omitempty"`
+	ParameterSize     string    `json:"parameterSize,omitempty"`
+	QuantizationLevel string    `json:"quantizationLevel,omitempty"`
+}
+
+// LoadedModel is a model the server holds in memory
+type LoadedModel struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	SizeVRAM  int64     `json:"sizeVram"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// PullProgress reports a step of pulling a model. Total and Completed are
+// bytes of the layer named by Digest while it downloads.
+type PullProgress struct {
+	Model     string `json:"model"`
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+// Host manages the models of an Ollama server
+type Host struct {
+	client *api.Client
+}
+
+// NewHost connects to the server at baseURL, or the one OLLAMA_HOST names
+// when baseURL is empty
+func NewHost(baseURL string) (*Host, error) {
+	client, err := newClient(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Host{client: client}, nil
+}
+
+// Models lists the models pulled onto the server
+func (h *Host) Models(ctx context.Context) ([]LocalModel, error) {
+	list, err := h.client.List(ctx)
+	if err != nil {
+		return nil, providerError(err)
+	}
+	local := make([]LocalModel, 0, len(list.Models))
+	for _, model := range list.Models {
+		local = append(local, LocalModel{
+			Name:              model.Name,
+			Size:              model.Size,
+			ModifiedAt:        model.ModifiedAt,
+			Family:            model.Details.Family,
+			ParameterSize:     model.Details.ParameterSize,
+			QuantizationLevel: model.Details.QuantizationLevel,
+		})
+	}
+	return local, nil
+}
+
+// Loaded lists the models the server holds in memory
+func (h *Host) Loaded(ctx context.Context) ([]LoadedModel, error) {
+	running, err := h.client.ListRunning(ctx)
+	if err != nil {
+		return nil, providerError(err)
+	}
+	loaded := make([]LoadedModel, 0, len(running.Models))
+	for _, model := range running.Models {
+		loaded = append(loaded, LoadedModel{
+			Name:      model.Name,
+			Size:      model.Size,
+			SizeVRAM:  model.SizeVRAM,
+			ExpiresAt: model.ExpiresAt,
+		})
+	}
+	return loaded, nil
+}
+
+// Pull downloads model onto the server, calling progress for every status
+// the server reports. It returns once the model is ready or ctx is done.
+func (h *Host) Pull(ctx context.Context, model string, progress func(PullProgress)) error {
+	if strings.TrimSpace(model) == "" {
+		return fmt.Errorf("model name is required")
+	}
+	err := h.client.Pull(ctx, &api.PullRequest{Model: model}, func(resp api.ProgressResponse) error {
+		if progress != nil {
+			progress(PullProgress{
+				Model:     model,
+				Status:    resp.Status,
+				Digest:    resp.Digest,
+				Total:     resp.Total,
+				Completed: resp.Completed,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return providerError(err)
+	}
+	return nil
+}
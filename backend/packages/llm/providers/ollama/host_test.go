@@ -0,0 +1,140 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// fakeOllama speaks the parts of the Ollama API the provider and Host use
+func fakeOllama(t *testing.T, chat func(body map[string]any)) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models": [{"name": "llama3.2:latest", "size": 2019393189,
+			"modified_at": "2026-09-01T10:00:00Z",
+			"details": {"family": "llama", "parameter_size": "3.2B", "quantization_level": "Q4_K_M"}}]}`)
+	})
+	mux.HandleFunc("/api/ps", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models": [{"name": "llama3.2:latest", "size": 3500000000,
+			"size_vram": 3500000000, "expires_at": "2026-10-18T17:00:00Z"}]}`)
+	})
+	mux.HandleFunc("/api/pull", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model string }
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "missing" {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error": "pull model manifest: file does not exist"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"status": "pulling manifest"}`)
+		fmt.Fprintln(w, `{"status": "pulling dde5aa3fc5ff", "digest": "sha256:dde5", "total": 100, "completed": 40}`)
+		fmt.Fprintln(w, `{"status": "pulling dde5aa3fc5ff", "digest": "sha256:dde5", "total": 100, "completed": 100}`)
+		fmt.Fprintln(w, `{"status": "success"}`)
+	})
+	mux.HandleFunc("/api/chat", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		chat(body)
+		fmt.Fprint(w, `{"model": "llama3.2", "message": {"role": "assistant", "content": "Hi there."}, "done": true}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProviderUsesEndpointAndOptions(t *testing.T) {
+	var body map[string]any
+	server := fakeOllama(t, func(b map[string]any) { body = b })
+
+	provider, err := NewProvider(server.URL, "llama3.2", "", Options{NumCtx: 8192, KeepAlive: "30m"})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	temperature := 0.2
+	provider.SetGenerationParams(models.GenerationParams{Temperature: &temperature})
+
+	msg, err := provider.CreateMessage(context.Background(), "", []models.Message{&history.HistoryMessage{
+		Role:    "user",
+		Content: []history.ContentBlock{{Type: "text", Text: "hello"}},
+	}}, nil)
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if msg.GetContent() != "Hi there." {
+		t.Errorf("Expected the stand-in server's answer, got %q", msg.GetContent())
+	}
+
+	options, _ := body["options"].(map[string]any)
+	if options["num_ctx"] != float64(8192) || options["temperature"] != 0.2 {
+		t.Errorf("Expected num_ctx and temperature in the options, got %v", options)
+	}
+	if body["keep_alive"] != "30m0s" {
+		t.Errorf("Expected keep_alive 30m0s, got %v", body["keep_alive"])
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	for _, valid := range []Options{{}, {KeepAlive: "-1"}, {KeepAlive: "300"}, {NumCtx: 4096, KeepAlive: "1h"}} {
+		if err := valid.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", valid, err)
+		}
+	}
+	for _, invalid := range []Options{{NumCtx: -1}, {KeepAlive: "soon"}} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", invalid)
+		}
+	}
+	if _, err := NewProvider("localhost:11434", "llama3.2", "", Options{}); err == nil {
+		t.Errorf("Expected an endpoint without a scheme to be rejected")
+	}
+}
+
+func TestHostManagesModels(t *testing.T) {
+	server := fakeOllama(t, nil)
+	host, err := NewHost(server.URL + "/")
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	ctx := context.Background()
+
+	local, err := host.Models(ctx)
+	if err != nil {
+		t.Fatalf("Models: %v", err)
+	}
+	if len(local) != 1 || local[0].Name != "llama3.2:latest" || local[0].ParameterSize != "3.2B" {
+		t.Errorf("Expected llama3.2 with its details, got %+v", local)
+	}
+
+	loaded, err := host.Loaded(ctx)
+	if err != nil {
+		t.Fatalf("Loaded: %v", err)
+	}
+	expires := time.Date(2026, 10, 18, 17, 0, 0, 0, time.UTC)
+	if len(loaded) != 1 || loaded[0].SizeVRAM != 3500000000 || !loaded[0].ExpiresAt.Equal(expires) {
+		t.Errorf("Expected llama3.2 loaded until %v, got %+v", expires, loaded)
+	}
+
+	var progress []PullProgress
+	if err := host.Pull(ctx, "llama3.2", func(p PullProgress) { progress = append(progress, p) }); err != nil {
+		t.Fatalf("Pull: %v", err)
+	}
+	if len(progress) != 4 || progress[2].Completed != 100 || progress[3].Status != "success" {
+		t.Errorf("Expected every progress step, got %+v", progress)
+	}
+	if progress[0].Model != "llama3.2" {
+		t.Errorf("Expected progress to name the model, got %+v", progress[0])
+	}
+
+	if err := host.Pull(ctx, "missing", nil); err == nil {
+		t.Errorf("Expected a failed pull to return the server's error")
+	}
+}
@@ -3,7 +3,9 @@ package ollama
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"log/slog"
@@ -17,16 +19,24 @@ func boolPtr(b bool) *bool {
 	return &b
 }
 
+const providerName = "ollama"
+
 // Provider implements the Provider interface for Ollama
 type Provider struct {
 	client       *api.Client
 	model        string
 	systemPrompt string
+	params       models.GenerationParams
+	options      Options
 }
 
-// NewProvider creates a new Ollama provider
-func NewProvider(model string, systemPrompt string) (*Provider, error) {
-	client, err := api.ClientFromEnvironment()
+// NewProvider creates a new Ollama provider for the server at baseURL, or
+// the one OLLAMA_HOST names when baseURL is empty
+func NewProvider(baseURL string, model string, systemPrompt string, options Options) (*Provider, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := newClient(baseURL)
 	if err != nil {
 		return nil, err
 	}
@@ -34,9 +44,83 @@ func NewProvider(model string, systemPrompt string) (*Provider, error) {
 		client:       client,
 		model:        model,
 		systemPrompt: systemPrompt,
+		options:      options,
 	}, nil
 }
 
+// SetSystemPrompt replaces the system prompt sent with the next requests
+func (p *Provider) SetSystemPrompt(prompt string) {
+	p.systemPrompt = prompt
+}
+
+// SetGenerationParams overrides the model's default temperature and token limit
+func (p *Provider) SetGenerationParams(params models.GenerationParams) {
+	p.params = params
+}
+
+// requestOptions returns the generation parameters in Ollama's option names
+func (p *Provider) requestOptions() map[string]interface{} {
+	options := map[string]interface{}{}
+	if p.params.Temperature != nil {
+		options["temperature"] = *p.params.Temperature
+	}
+	if p.params.MaxTokens > 0 {
+		options["num_predict"] = p.params.MaxTokens
+	}
+	if p.options.NumCtx > 0 {
+		options["num_ctx"] = p.options.NumCtx
+	}
+	return options
+}
+
+// providerError classifies an error from the Ollama client
+func providerError(err error) error {
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) {
+		message := statusErr.ErrorMessage
+		if message == "" {
+			message = statusErr.Status
+		}
+		return models.NewStatusError(providerName, statusErr.StatusCode, message)
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return models.NewNetworkError(providerName, err)
+	}
+	return err
+}
+
+// ListModels returns the locally pulled models. Capabilities are read from
+// each model: the context length from its metadata, vision from an image
+// projector, tool support from a chat template that renders tools.
+func (p *Provider) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	list, err := p.client.List(ctx)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	infos := make([]models.ModelInfo, 0, len(list.Models))
+	for _, model := range list.Models {
+		info := models.ModelInfo{ID: model.Name, Streaming: boolPtr(true)}
+
+		show, err := p.client.Show(ctx, &api.ShowRequest{Model: model.Name})
+		if err != nil {
+			slog.Warn("failed to read model details", "model", model.Name, "error", err)
+			infos = append(infos, info)
+			continue
+		}
+		if arch, ok := show.ModelInfo["general.architecture"].(string); ok {
+			if length, ok := show.ModelInfo[arch+".context_length"].(float64); ok {
+				info.ContextWindow = int(length)
+			}
+		}
+		info.Vision = boolPtr(len(show.ProjectorInfo) > 0)
+		info.Tools = boolPtr(strings.Contains(show.Template, ".Tools"))
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 func (p *Provider) CreateMessage(
 	ctx context.Context,
 	prompt string,
@@ -167,10 +251,12 @@ func (p *Provider) CreateMessage(
 		"num_tools", len(tools))
 
 	err := p.client.Chat(ctx, &api.ChatRequest{
-		Model:    p.model,
-		Messages: ollamaMessages,
-		Tools:    ollamaTools,
-		Stream:   boolPtr(false),
+		Model:     p.model,
+		Messages:  ollamaMessages,
+		Tools:     ollamaTools,
+		Stream:    boolPtr(false),
+		Options:   p.requestOptions(),
+		KeepAlive: p.options.keepAlive(),
 	}, func(r api.ChatResponse) error {
 		if r.Done {
 			response = r.Message
@@ -179,7 +265,7 @@ func (p *Provider) CreateMessage(
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, providerError(err)
 	}
 
 	return &OllamaMessage{Message: response}, nil
@@ -197,7 +283,7 @@ func (p *Provider) SupportsTools() bool {
 }
 
 func (p *Provider) Name() string {
-	return "ollama"
+	return providerName
 }
 
 func (p *Provider) CreateToolResponse(
@@ -5,63 +5,78 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
 )
 
 type Client struct {
-	apiKey  string
-	baseURL string
-	client  *http.Client
+	endpoint Endpoint
+	client   *http.Client
+}
+
+// Endpoint says where a client sends its requests and how it authenticates,
+// for services that speak the OpenAI API under their own URL scheme
+type Endpoint struct {
+	Provider string                   // name reported in errors
+	URL      func(path string) string // full URL of an API path such as "/chat/completions"
+	Header   http.Header              // authentication headers sent with every request
 }
 
 func NewClient(apiKey string, baseURL string) *Client {
 	if baseURL == "" {
 		baseURL = "https://api.openai.com/v1"
 	}
+	return NewEndpointClient(Endpoint{
+		Provider: providerName,
+		URL:      func(path string) string { return baseURL + path },
+		Header:   http.Header{"Authorization": {"Bearer " + apiKey}},
+	})
+}
+
+// NewEndpointClient creates a client for an OpenAI compatible service
+func NewEndpointClient(endpoint Endpoint) *Client {
 	return &Client{
-		apiKey:  apiKey,
-		baseURL: baseURL,
-		client:  &http.Client{},
+		endpoint: endpoint,
+		client:   &http.Client{},
 	}
 }
 
+// newRequest creates an authenticated request to an API path
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.endpoint.URL(path), body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	for key, values := range c.endpoint.Header {
+		httpReq.Header[key] = values
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	return httpReq, nil
+}
+
 func (c *Client) CreateChatCompletion(ctx context.Context, req CreateRequest) (*APIResponse, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(
-		ctx,
-		"POST",
-		fmt.Sprintf("%s/chat/completions", c.baseURL),
-		bytes.NewReader(body),
-	)
+	httpReq, err := c.newRequest(ctx, "POST", "/chat/completions", bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, models.NewNetworkError(c.endpoint.Provider, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp struct {
-			Error struct {
-				Message string `json:"message"`
-				Type    string `json:"type"`
-				Code    string `json:"code"`
-			} `json:"error"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
-			return nil, fmt.Errorf("error response with status %d", resp.StatusCode)
-		}
-		return nil, fmt.Errorf("%s: %s", errResp.Error.Type, errResp.Error.Message)
+		return nil, c.statusError(resp)
 	}
 
 	var response APIResponse
@@ -71,3 +86,43 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req CreateRequest) (*
 
 	return &response, nil
 }
+
+// ListModels returns the models the endpoint serves
+func (c *Client) ListModels(ctx context.Context) (*ModelList, error) {
+	httpReq, err := c.newRequest(ctx, "GET", "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, models.NewNetworkError(c.endpoint.Provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.statusError(resp)
+	}
+
+	var list ModelList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	return &list, nil
+}
+
+// statusError reads the error body of a failed request
+func (c *Client) statusError(resp *http.Response) error {
+	var errResp struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+		return models.NewStatusError(c.endpoint.Provider, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return models.NewStatusError(c.endpoint.Provider, resp.StatusCode,
+		fmt.Sprintf("%s: %s", errResp.Error.Type, errResp.Error.Message))
+}
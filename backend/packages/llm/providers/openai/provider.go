@@ -5,15 +5,59 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"slices"
+	"smart-spotlight-ai/backend/packages/llm/capabilities"
 	"smart-spotlight-ai/backend/packages/llm/history"
 	"smart-spotlight-ai/backend/packages/llm/models"
 	"strings"
 )
 
+const (
+	providerName       = "openai"
+	defaultMaxTokens   = 4096
+	defaultTemperature = 0.7
+
+	// reasoning tokens count against the answer's limit, so reasoning
+	// models get the room OpenAI recommends reserving
+	defaultReasoningMaxTokens = 25000
+)
+
+// APIs a profile can send its requests to
+const (
+	APIChat      = "chat"      // /chat/completions, what compatible servers speak
+	APIResponses = "responses" // /responses
+)
+
+// ReasoningEfforts are the accepted values of Options.ReasoningEffort
+var ReasoningEfforts = []string{"minimal", "low", "medium", "high"}
+
+// Options are the per-profile settings of the openai provider
+type Options struct {
+	API             string `json:"api,omitempty"`             // APIChat or APIResponses, empty is APIChat
+	ReasoningEffort string `json:"reasoningEffort,omitempty"` // for reasoning models, empty keeps the model default
+	// Reasoning marks the model as a reasoning model, for names the
+	// built-in table does not know; nil goes by the model name
+	Reasoning *bool `json:"reasoning,omitempty"`
+}
+
+// Validate checks the options can be sent to the API
+func (o Options) Validate() error {
+	if o.API != "" && o.API != APIChat && o.API != APIResponses {
+		return fmt.Errorf("unsupported API %q, expected %s or %s", o.API, APIChat, APIResponses)
+	}
+	if o.ReasoningEffort != "" && !slices.Contains(ReasoningEfforts, o.ReasoningEffort) {
+		return fmt.Errorf("unsupported reasoning effort %q, expected one of %s",
+			o.ReasoningEffort, strings.Join(ReasoningEfforts, ", "))
+	}
+	return nil
+}
+
 type Provider struct {
 	client       *Client
 	model        string
 	systemPrompt string
+	params       models.GenerationParams
+	options      Options
 }
 
 func convertSchema(schema models.Schema) map[string]interface{} {
@@ -30,12 +74,61 @@ func convertSchema(schema models.Schema) map[string]interface{} {
 	}
 }
 
-func NewProvider(apiKey, baseURL, model, systemPrompt string) *Provider {
+func NewProvider(apiKey, baseURL, model, systemPrompt string, options Options) *Provider {
+	return NewProviderWithClient(NewClient(apiKey, baseURL), model, systemPrompt, options)
+}
+
+// NewProviderWithClient creates a provider that talks through client, e.g.
+// one made by NewEndpointClient
+func NewProviderWithClient(client *Client, model, systemPrompt string, options Options) *Provider {
 	return &Provider{
-		client:       NewClient(apiKey, baseURL),
+		client:       client,
 		model:        model,
 		systemPrompt: systemPrompt,
+		options:      options,
+	}
+}
+
+// reasoning reports whether the model is a reasoning model, which takes a
+// completion token limit and an effort but rejects temperature
+func (p *Provider) reasoning() bool {
+	if p.options.Reasoning != nil {
+		return *p.options.Reasoning
 	}
+	return capabilities.Builtin(providerName, p.model).SupportsReasoning()
+}
+
+// generation returns the sampling parameters shaped for the model: a token
+// limit, and a temperature unless it is a reasoning model
+func (p *Provider) generation() (maxTokens int, temperature *float64) {
+	maxTokens = defaultMaxTokens
+	if p.reasoning() {
+		maxTokens = defaultReasoningMaxTokens
+	}
+	if p.params.MaxTokens > 0 {
+		maxTokens = p.params.MaxTokens
+	}
+	if p.reasoning() {
+		if p.params.Temperature != nil {
+			slog.Debug("reasoning model, not sending temperature", "model", p.model)
+		}
+		return maxTokens, nil
+	}
+	t := defaultTemperature
+	if p.params.Temperature != nil {
+		t = *p.params.Temperature
+	}
+	return maxTokens, &t
+}
+
+// SetSystemPrompt replaces the system prompt sent with the next requests
+func (p *Provider) SetSystemPrompt(prompt string) {
+	p.systemPrompt = prompt
+}
+
+// SetGenerationParams overrides the default temperature and token limit
+func (p *Provider) SetGenerationParams(params models.GenerationParams) {
+	p.params = params
 }
 
 func (p *Provider) CreateMessage(
@@ -49,12 +142,20 @@ func (p *Provider) CreateMessage(
 		"num_messages", len(messages),
 		"num_tools", len(tools))
 
+	if p.options.API == APIResponses {
+		return p.createResponse(ctx, prompt, messages, tools)
+	}
+
 	openaiMessages := make([]MessageParam, 0, len(messages))
 
-	// Add system prompt if provided
+	// Add system prompt if provided; reasoning models take it as developer message
 	if p.systemPrompt != "" {
+		role := "system"
+		if p.reasoning() {
+			role = "developer"
+		}
 		openaiMessages = append(openaiMessages, MessageParam{
-			Role:    "system",
+			Role:    role,
 			Content: &p.systemPrompt,
 		})
 	}
@@ -108,37 +209,7 @@ func (p *Provider) CreateMessage(
 				"tool_call_id", msg.GetToolResponseID(),
 				"raw_message", msg)
 
-			// Extract content from tool response
-			var contentStr string
-			if content := msg.GetContent(); content != "" {
-				contentStr = content
-			} else {
-				// Try to extract text from history message content blocks
-				if historyMsg, ok := msg.(*history.HistoryMessage); ok {
-					var texts []string
-					for _, block := range historyMsg.Content {
-						if block.Type == "tool_result" {
-							if block.Text != "" {
-								texts = append(texts, block.Text)
-							} else if contentArray, ok := block.Content.([]interface{}); ok {
-								for _, item := range contentArray {
-									if contentMap, ok := item.(map[string]interface{}); ok {
-										if text, ok := contentMap["text"]; ok {
-											texts = append(texts, fmt.Sprint(text))
-										}
-									}
-								}
-							}
-						}
-					}
-					contentStr = strings.Join(texts, "\n")
-				}
-			}
-
-			if contentStr == "" {
-				contentStr = "No content returned from function"
-			}
-
+			contentStr := toolOutput(msg)
 			param.Content = &contentStr
 			param.Role = "tool" // Use tool role instead of function
 			param.ToolCallID = msg.GetToolResponseID()
@@ -176,13 +247,21 @@ func (p *Provider) CreateMessage(
 	}
 
 	// Make the API call
-	resp, err := p.client.CreateChatCompletion(ctx, CreateRequest{
-		Model:       p.model,
-		Messages:    openaiMessages,
-		Tools:       openaiTools,
-		MaxTokens:   4096,
-		Temperature: 0.7,
-	})
+	req := CreateRequest{
+		Model:    p.model,
+		Messages: openaiMessages,
+		Tools:    openaiTools,
+	}
+	maxTokens, temperature := p.generation()
+	if p.reasoning() {
+		req.MaxCompletionTokens = maxTokens
+		req.ReasoningEffort = p.options.ReasoningEffort
+	} else {
+		req.MaxTokens = maxTokens
+		req.Temperature = temperature
+	}
+
+	resp, err := p.client.CreateChatCompletion(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -194,6 +273,37 @@ func (p *Provider) CreateMessage(
 	return &Message{Resp: resp, Choice: &resp.Choices[0]}, nil
 }
 
+// toolOutput returns the text a tool response carries
+func toolOutput(msg models.Message) string {
+	if content := msg.GetContent(); content != "" {
+		return content
+	}
+
+	// Try to extract text from history message content blocks
+	var texts []string
+	if historyMsg, ok := msg.(*history.HistoryMessage); ok {
+		for _, block := range historyMsg.Content {
+			if block.Type == "tool_result" {
+				if block.Text != "" {
+					texts = append(texts, block.Text)
+				} else if contentArray, ok := block.Content.([]interface{}); ok {
+					for _, item := range contentArray {
+						if contentMap, ok := item.(map[string]interface{}); ok {
+							if text, ok := contentMap["text"]; ok {
+								texts = append(texts, fmt.Sprint(text))
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	if len(texts) == 0 {
+		return "No content returned from function"
+	}
+	return strings.Join(texts, "\n")
+}
+
 func (p *Provider) SupportsTools() bool {
 	return true
 }
@@ -347,3 +457,39 @@ func (t *ToolCallWrapper) GetArguments() map[string]interface{} {
 	}
 	return args
 }
+
+// ListModels returns the models the endpoint serves with whatever limits and
+// capabilities it reports
+func (p *Provider) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	list, err := p.client.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]models.ModelInfo, 0, len(list.Data))
+	for _, entry := range list.Data {
+		info := models.ModelInfo{
+			ID:            entry.ID,
+			DisplayName:   entry.Name,
+			ContextWindow: entry.ContextLength,
+		}
+		if info.ContextWindow == 0 {
+			info.ContextWindow = entry.MaxModelLen
+		}
+		if entry.TopProvider != nil {
+			info.MaxOutputTokens = entry.TopProvider.MaxCompletionTokens
+		}
+		if entry.Architecture != nil {
+			info.Vision = boolPtr(slices.Contains(entry.Architecture.InputModalities, "image"))
+		}
+		if entry.SupportedParameters != nil {
+			info.Tools = boolPtr(slices.Contains(entry.SupportedParameters, "tools"))
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,178 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smart-spotlight-ai/backend/packages/llm/history"
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// stubServer answers chat completions and responses, recording each body
+func stubServer(t *testing.T, bodies map[string]map[string]any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies[r.URL.Path] = body
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/chat/completions":
+			fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "chat answer"}}]}`)
+		case "/responses":
+			fmt.Fprint(w, `{"id": "resp_1", "status": "completed", "output": [
+				{"type": "reasoning", "summary": []},
+				{"type": "message", "role": "assistant", "content": [{"type": "output_text", "text": "Checking."}]},
+				{"type": "function_call", "call_id": "call_9", "name": "gh__get_repo", "arguments": "{\"name\":\"notes\"}"}],
+				"usage": {"input_tokens": 120, "output_tokens": 40}}`)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParameterShaping(t *testing.T) {
+	temperature := 0.4
+	tests := []struct {
+		name    string
+		model   string
+		options Options
+		path    string
+		want    map[string]any // expected fields, nil for absent
+	}{
+		{
+			name: "classic chat", model: "gpt-4o", path: "/chat/completions",
+			want: map[string]any{"max_tokens": 1000.0, "temperature": 0.4, "max_completion_tokens": nil, "reasoning_effort": nil},
+		},
+		{
+			name: "reasoning chat", model: "o3-mini", options: Options{ReasoningEffort: "high"}, path: "/chat/completions",
+			want: map[string]any{"max_completion_tokens": 1000.0, "reasoning_effort": "high", "max_tokens": nil, "temperature": nil},
+		},
+		{
+			name: "chat variant of a reasoning family", model: "gpt-5-chat-latest", path: "/chat/completions",
+			want: map[string]any{"max_tokens": 1000.0, "temperature": 0.4},
+		},
+		{
+			name: "reasoning forced for an unknown name", model: "my-deployment", path: "/chat/completions",
+			options: Options{Reasoning: boolPtr(true)},
+			want:    map[string]any{"max_completion_tokens": 1000.0, "temperature": nil},
+		},
+		{
+			name: "classic responses", model: "gpt-4.1", options: Options{API: APIResponses}, path: "/responses",
+			want: map[string]any{"max_output_tokens": 1000.0, "temperature": 0.4, "reasoning": nil, "instructions": "Be brief."},
+		},
+		{
+			name: "reasoning responses", model: "gpt-5", options: Options{API: APIResponses, ReasoningEffort: "low"}, path: "/responses",
+			want: map[string]any{"max_output_tokens": 1000.0, "temperature": nil, "reasoning": map[string]any{"effort": "low"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies := map[string]map[string]any{}
+			server := stubServer(t, bodies)
+			provider := NewProvider("sk-test", server.URL, tt.model, "Be brief.", tt.options)
+			provider.SetGenerationParams(models.GenerationParams{Temperature: &temperature, MaxTokens: 1000})
+
+			if _, err := provider.CreateMessage(context.Background(), "hi", nil, nil); err != nil {
+				t.Fatalf("CreateMessage: %v", err)
+			}
+			body, ok := bodies[tt.path]
+			if !ok {
+				t.Fatalf("Expected a request to %s, got %v", tt.path, bodies)
+			}
+			for field, want := range tt.want {
+				got, present := body[field]
+				if want == nil && present {
+					t.Errorf("Expected no %s, got %v", field, got)
+				}
+				if want != nil && fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("Expected %s %v, got %v", field, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReasoningChatUsesDeveloperRole(t *testing.T) {
+	bodies := map[string]map[string]any{}
+	server := stubServer(t, bodies)
+	provider := NewProvider("sk-test", server.URL, "o4-mini", "Be brief.", Options{})
+	if _, err := provider.CreateMessage(context.Background(), "hi", nil, nil); err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	messages := bodies["/chat/completions"]["messages"].([]any)
+	if role := messages[0].(map[string]any)["role"]; role != "developer" {
+		t.Errorf("Expected the system prompt as a developer message, got %v", role)
+	}
+}
+
+func TestResponsesToolRoundTrip(t *testing.T) {
+	bodies := map[string]map[string]any{}
+	server := stubServer(t, bodies)
+	provider := NewProvider("sk-test", server.URL, "gpt-5", "", Options{API: APIResponses})
+
+	conversation := []models.Message{
+		&history.HistoryMessage{Role: "user", Content: []history.ContentBlock{{Type: "text", Text: "List my repos"}}},
+		&history.HistoryMessage{Role: "assistant", Content: []history.ContentBlock{
+			{Type: history.BlockThinking, Text: "not for OpenAI"},
+			{Type: "text", Text: "Let me look."},
+			{Type: "tool_use", ID: "call_1", Name: "gh__list_repos", Input: json.RawMessage(`{"owner":"me"}`)},
+		}},
+		&history.HistoryMessage{Role: "tool", Content: []history.ContentBlock{
+			{Type: "tool_result", ToolUseID: "call_1", Content: []any{map[string]any{"type": "text", "text": "spotlight, notes"}}},
+		}},
+	}
+	tools := []models.Tool{{Name: "gh__get_repo", Description: "Get a repo", InputSchema: models.Schema{
+		Type: "object", Properties: map[string]any{"name": map[string]any{"type": "string"}},
+	}}}
+
+	msg, err := provider.CreateMessage(context.Background(), "", conversation, tools)
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+
+	body := bodies["/responses"]
+	input, _ := json.Marshal(body["input"])
+	want := `[{"content":"List my repos","role":"user","type":"message"},` +
+		`{"content":"Let me look.","role":"assistant","type":"message"},` +
+		`{"arguments":"{\"owner\":\"me\"}","call_id":"call_1","name":"gh__list_repos","type":"function_call"},` +
+		`{"call_id":"call_1","output":"spotlight, notes","type":"function_call_output"}]`
+	if string(input) != want {
+		t.Errorf("Expected input\n  %s\ngot\n  %s", want, input)
+	}
+	tool := body["tools"].([]any)[0].(map[string]any)
+	if tool["type"] != "function" || tool["name"] != "gh__get_repo" || tool["parameters"] == nil {
+		t.Errorf("Expected a flat function tool, got %v", tool)
+	}
+
+	if msg.GetRole() != "assistant" || msg.GetContent() != "Checking." {
+		t.Errorf("Expected the assistant's text, got %s %q", msg.GetRole(), msg.GetContent())
+	}
+	calls := msg.GetToolCalls()
+	if len(calls) != 1 || calls[0].GetID() != "call_9" || calls[0].GetArguments()["name"] != "notes" {
+		t.Errorf("Expected the function call, got %+v", calls)
+	}
+	if in, out := msg.GetUsage(); in != 120 || out != 40 {
+		t.Errorf("Expected usage 120/40, got %d/%d", in, out)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	if err := (Options{API: "completions"}).Validate(); err == nil {
+		t.Errorf("Expected an unknown API to be rejected")
+	}
+	if err := (Options{ReasoningEffort: "max"}).Validate(); err == nil {
+		t.Errorf("Expected an unknown reasoning effort to be rejected")
+	}
+	if err := (Options{API: APIResponses, ReasoningEffort: "minimal"}).Validate(); err != nil {
+		t.Errorf("Expected valid options, got %v", err)
+	}
+}
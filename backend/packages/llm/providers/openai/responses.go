@@ -0,0 +1,229 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
+)
+
+// ResponsesRequest is the body of POST /responses
+type ResponsesRequest struct {
+	Model           string          `json:"model"`
+	Instructions    string          `json:"instructions,omitempty"`
+	Input           []InputItem     `json:"input"`
+	Tools           []ResponsesTool `json:"tools,omitempty"`
+	MaxOutputTokens int             `json:"max_output_tokens,omitempty"`
+	Temperature     *float64        `json:"temperature,omitempty"`
+	Reasoning       *Reasoning      `json:"reasoning,omitempty"`
+}
+
+// InputItem is a message, a function call the model made, or its output
+type InputItem struct {
+	Type    string `json:"type"` // "message", "function_call" or "function_call_output"
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    string `json:"output,omitempty"`
+}
+
+// ResponsesTool declares a function; unlike chat tools it is not nested
+type ResponsesTool struct {
+	Type        string      `json:"type"` // "function"
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters"`
+}
+
+type Reasoning struct {
+	Effort string `json:"effort,omitempty"`
+}
+
+// ResponsesResponse is the answer of POST /responses
+type ResponsesResponse struct {
+	ID     string       `json:"id"`
+	Model  string       `json:"model"`
+	Status string       `json:"status"`
+	Output []OutputItem `json:"output"`
+	Usage  struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// OutputItem is a message or a function call of the answer. Reasoning
+// items are skipped.
+type OutputItem struct {
+	Type    string `json:"type"`
+	Role    string `json:"role,omitempty"`
+	Content []struct {
+		Type string `json:"type"` // "output_text" or "refusal"
+		Text string `json:"text,omitempty"`
+	} `json:"content,omitempty"`
+
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+func (c *Client) CreateResponse(ctx context.Context, req ResponsesRequest) (*ResponsesResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	httpReq, err := c.newRequest(ctx, "POST", "/responses", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, models.NewNetworkError(c.endpoint.Provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.statusError(resp)
+	}
+
+	var response ResponsesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	return &response, nil
+}
+
+// createResponse sends the conversation to the Responses API
+func (p *Provider) createResponse(
+	ctx context.Context,
+	prompt string,
+	messages []models.Message,
+	tools []models.Tool,
+) (models.Message, error) {
+	input, err := responsesInput(messages)
+	if err != nil {
+		return nil, err
+	}
+	if prompt != "" {
+		input = append(input, InputItem{Type: "message", Role: "user", Content: prompt})
+	}
+
+	req := ResponsesRequest{
+		Model:        p.model,
+		Instructions: p.systemPrompt,
+		Input:        input,
+	}
+	for _, tool := range tools {
+		req.Tools = append(req.Tools, ResponsesTool{
+			Type:        "function",
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  convertSchema(tool.InputSchema),
+		})
+	}
+	req.MaxOutputTokens, req.Temperature = p.generation()
+	if p.reasoning() && p.options.ReasoningEffort != "" {
+		req.Reasoning = &Reasoning{Effort: p.options.ReasoningEffort}
+	}
+
+	resp, err := p.client.CreateResponse(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseMessage{Resp: resp}, nil
+}
+
+// responsesInput maps the conversation onto input items: text turns become
+// messages, tool calls function_call items and tool results their outputs
+func responsesInput(messages []models.Message) ([]InputItem, error) {
+	input := make([]InputItem, 0, len(messages))
+	for _, msg := range messages {
+		if msg.IsToolResponse() {
+			input = append(input, InputItem{
+				Type:   "function_call_output",
+				CallID: msg.GetToolResponseID(),
+				Output: toolOutput(msg),
+			})
+			continue
+		}
+
+		role := msg.GetRole()
+		if role != "assistant" && role != "system" && role != "developer" {
+			role = "user"
+		}
+		if text := strings.TrimSpace(msg.GetContent()); text != "" {
+			input = append(input, InputItem{Type: "message", Role: role, Content: text})
+		}
+		for _, call := range msg.GetToolCalls() {
+			args, err := json.Marshal(call.GetArguments())
+			if err != nil {
+				return nil, fmt.Errorf("error marshaling function arguments: %w", err)
+			}
+			input = append(input, InputItem{
+				Type:      "function_call",
+				CallID:    call.GetID(),
+				Name:      call.GetName(),
+				Arguments: string(args),
+			})
+		}
+	}
+	return input, nil
+}
+
+// ResponseMessage implements models.Message for a Responses API answer
+type ResponseMessage struct {
+	Resp *ResponsesResponse
+}
+
+func (m *ResponseMessage) GetRole() string {
+	return "assistant"
+}
+
+func (m *ResponseMessage) GetContent() string {
+	var texts []string
+	for _, item := range m.Resp.Output {
+		if item.Type != "message" {
+			continue
+		}
+		for _, content := range item.Content {
+			if content.Text != "" {
+				texts = append(texts, content.Text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func (m *ResponseMessage) GetToolCalls() []models.ToolCall {
+	var calls []models.ToolCall
+	for _, item := range m.Resp.Output {
+		if item.Type == "function_call" {
+			calls = append(calls, &ToolCallWrapper{ToolCall{
+				ID:       item.CallID,
+				Type:     "function",
+				Function: FunctionCall{Name: item.Name, Arguments: item.Arguments},
+			}})
+		}
+	}
+	return calls
+}
+
+func (m *ResponseMessage) IsToolResponse() bool {
+	return false
+}
+
+func (m *ResponseMessage) GetToolResponseID() string {
+	return ""
+}
+
+func (m *ResponseMessage) GetUsage() (int, int) {
+	return m.Resp.Usage.InputTokens, m.Resp.Usage.OutputTokens
+}
@@ -5,7 +5,11 @@ type CreateRequest struct {
 	Messages    []MessageParam `json:"messages"`
 	Tools       []Tool         `json:"tools,omitempty"`
 	MaxTokens   int            `json:"max_tokens,omitempty"`
-	Temperature float32        `json:"temperature,omitempty"`
+	Temperature *float64       `json:"temperature,omitempty"`
+
+	// reasoning models take these instead of max_tokens and temperature
+	MaxCompletionTokens int    `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string `json:"reasoning_effort,omitempty"`
 }
 
 type MessageParam struct {
@@ -60,3 +64,23 @@ type Usage struct {
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
 }
+
+// ModelList is the response of GET /models. OpenAI only reports IDs;
+// compatible servers such as OpenRouter and vLLM add limits and capabilities.
+type ModelList struct {
+	Data []ModelEntry `json:"data"`
+}
+
+type ModelEntry struct {
+	ID            string `json:"id"`
+	Name          string `json:"name,omitempty"`           // OpenRouter
+	ContextLength int    `json:"context_length,omitempty"` // OpenRouter
+	MaxModelLen   int    `json:"max_model_len,omitempty"`  // vLLM
+	TopProvider   *struct {
+		MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+	} `json:"top_provider,omitempty"` // OpenRouter
+	Architecture *struct {
+		InputModalities []string `json:"input_modalities,omitempty"`
+	} `json:"architecture,omitempty"` // OpenRouter
+	SupportedParameters []string `json:"supported_parameters,omitempty"` // OpenRouter
+}
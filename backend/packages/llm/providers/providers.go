@@ -0,0 +1,111 @@
+// Package providers creates the LLM provider named in a configuration
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers/anthropic"
+	"smart-spotlight-ai/backend/packages/llm/providers/azure"
+	"smart-spotlight-ai/backend/packages/llm/providers/bedrock"
+	"smart-spotlight-ai/backend/packages/llm/providers/google"
+	"smart-spotlight-ai/backend/packages/llm/providers/ollama"
+	"smart-spotlight-ai/backend/packages/llm/providers/openai"
+)
+
+// Provider types
+const (
+	OpenAI    = "openai"
+	Anthropic = "anthropic"
+	Ollama    = "ollama"
+	Google    = "google"
+	Azure     = "azure"
+	Bedrock   = "bedrock"
+)
+
+// Config selects and configures a provider. Both the plain search mode and
+// the MCP mode build their provider from it.
+type Config struct {
+	Name         string // profile the configuration came from, reported to the user
+	Provider     string
+	BaseURL      string
+	APIKey       string
+	Model        string
+	SystemPrompt string
+	Params       models.GenerationParams
+	Ollama       ollama.Options    // used by the ollama provider only
+	Anthropic    anthropic.Options // used by the anthropic provider only
+	OpenAI       openai.Options    // used by the openai provider only
+	Azure        azure.Options     // used by the azure provider only
+	Bedrock      bedrock.Options   // used by the bedrock provider only
+}
+
+// NeedsAPIKey reports whether the provider type authenticates with a key.
+// Bedrock can take its credentials from the AWS environment instead.
+func NeedsAPIKey(provider string) bool {
+	return provider != Ollama && provider != Bedrock
+}
+
+// New creates the provider described by config
+func New(ctx context.Context, config Config) (models.Provider, error) {
+	if config.APIKey == "" && NeedsAPIKey(config.Provider) {
+		return nil, &models.ProviderError{
+			Provider: config.Provider,
+			Kind:     models.ErrorAuth,
+			Message:  "API key not provided",
+		}
+	}
+
+	var provider models.Provider
+	switch config.Provider {
+	case OpenAI:
+		slog.Info("Creating OpenAI provider")
+		provider = openai.NewProvider(config.APIKey, config.BaseURL, config.Model, config.SystemPrompt, config.OpenAI)
+
+	case Anthropic:
+		slog.Info("Creating Anthropic provider")
+		provider = anthropic.NewProvider(config.APIKey, config.BaseURL, config.Model, config.SystemPrompt, config.Anthropic)
+
+	case Ollama:
+		slog.Info("Creating Ollama provider")
+		p, err := ollama.NewProvider(config.BaseURL, config.Model, config.SystemPrompt, config.Ollama)
+		if err != nil {
+			return nil, err
+		}
+		provider = p
+
+	case Google:
+		slog.Info("Creating Google provider")
+		p, err := google.NewProvider(ctx, config.APIKey, config.Model, config.SystemPrompt)
+		if err != nil {
+			return nil, err
+		}
+		provider = p
+
+	case Azure:
+		slog.Info("Creating Azure OpenAI provider")
+		p, err := azure.NewProvider(config.APIKey, config.BaseURL, config.Model, config.SystemPrompt, config.Azure)
+		if err != nil {
+			return nil, err
+		}
+		provider = p
+
+	case Bedrock:
+		slog.Info("Creating Bedrock provider")
+		p, err := bedrock.NewProvider(config.APIKey, config.BaseURL, config.Model, config.SystemPrompt, config.Bedrock)
+		if err != nil {
+			return nil, err
+		}
+		provider = p
+
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
+	}
+
+	if setter, ok := provider.(models.GenerationSetter); ok {
+		setter.SetGenerationParams(config.Params)
+	}
+	return provider, nil
+}
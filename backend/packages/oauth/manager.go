@@ -0,0 +1,201 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const callbackPath = "/callback"
+
+// Manager runs authorization flows and keeps tokens fresh for MCP servers
+type Manager struct {
+	store      *Store
+	httpClient *http.Client
+	clientName string
+}
+
+// NewManager creates a manager that stores credentials in store
+func NewManager(store *Store, clientName string) *Manager {
+	return &Manager{
+		store:      store,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		clientName: clientName,
+	}
+}
+
+// Token returns a usable access token for a server, refreshing it when it
+// has expired. It returns ErrNoToken when the user has to authorize again.
+func (m *Manager) Token(ctx context.Context, server, serverURL string) (*Token, error) {
+	creds, ok := m.store.Get(server)
+	if !ok || creds.Token == nil || creds.ServerURL != serverURL {
+		return nil, ErrNoToken
+	}
+	if !creds.Token.Expired() {
+		return creds.Token, nil
+	}
+	if creds.Token.RefreshToken == "" || creds.Metadata == nil || creds.Client == nil {
+		return nil, ErrNoToken
+	}
+
+	token, err := refreshToken(ctx, m.httpClient, creds.Metadata, creds.Client, creds.Token, serverURL)
+	if err != nil {
+		// a rejected refresh token means starting over
+		creds.Token = nil
+		if putErr := m.store.Put(server, creds); putErr != nil {
+			return nil, putErr
+		}
+		return nil, fmt.Errorf("%w: refresh failed: %v", ErrNoToken, err)
+	}
+
+	creds.Token = token
+	if err := m.store.Put(server, creds); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Authorize runs the interactive authorization code flow for a server.
+// openURL is called with the URL the user must visit; Authorize then waits
+// on a loopback listener for the redirect until ctx is done.
+func (m *Manager) Authorize(ctx context.Context, server, serverURL string, openURL func(authURL string)) (*Token, error) {
+	meta, err := Discover(ctx, m.httpClient, serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("authorization discovery failed: %w", err)
+	}
+
+	// a stored registration is reused by listening on the port it was
+	// registered with; only when that port is taken do we register again
+	creds, _ := m.store.Get(server)
+	client := creds.Client
+	if creds.ServerURL != serverURL {
+		client = nil
+	}
+	var listener net.Listener
+	var redirectURI string
+	if client != nil {
+		listener, redirectURI = listenRedirect(client.RedirectURIs)
+	}
+	if listener == nil {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("failed to start redirect listener: %w", err)
+		}
+		redirectURI = fmt.Sprintf("http://%s%s", listener.Addr().String(), callbackPath)
+		client, err = Register(ctx, m.httpClient, meta, m.clientName, redirectURI)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	verifier, challenge, err := newPKCE()
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	authURL, err := authorizationURL(meta, client, redirectURI, challenge, state, serverURL)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	results := make(chan callbackResult, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var res callbackResult
+		switch {
+		case q.Get("state") != state:
+			res.err = fmt.Errorf("authorization callback state mismatch")
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization denied: %s %s", q.Get("error"), q.Get("error_description"))
+		case q.Get("code") == "":
+			res.err = fmt.Errorf("authorization callback has no code")
+		default:
+			res.code = q.Get("code")
+		}
+
+		if res.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "<html><body><p>Authorization failed: %s</p></body></html>", html.EscapeString(res.err.Error()))
+		} else {
+			fmt.Fprint(w, "<html><body><p>Authorization complete. You can close this window.</p></body></html>")
+		}
+
+		select {
+		case results <- res:
+		default:
+		}
+	})
+
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	openURL(authURL)
+
+	var res callbackResult
+	select {
+	case res = <-results:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("authorization not completed: %w", ctx.Err())
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	token, err := exchangeCode(ctx, m.httpClient, meta, client, res.code, verifier, redirectURI, serverURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.store.Put(server, Credentials{
+		ServerURL: serverURL,
+		Metadata:  meta,
+		Client:    client,
+		Token:     token,
+	}); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Forget removes everything stored for a server
+func (m *Manager) Forget(server string) error {
+	return m.store.Delete(server)
+}
+
+// SetVault persists credentials to vault from now on, see Store.SetVault
+func (m *Manager) SetVault(vault Vault) error {
+	return m.store.SetVault(vault)
+}
+
+// listenRedirect listens on the first loopback redirect URI of a stored
+// registration whose port is free, returning nil when none is usable
+func listenRedirect(redirectURIs []string) (net.Listener, string) {
+	for _, uri := range redirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme != "http" || u.Hostname() != "127.0.0.1" || u.Port() == "" || u.Path != callbackPath {
+			continue
+		}
+		if listener, err := net.Listen("tcp", u.Host); err == nil {
+			return listener, uri
+		}
+	}
+	return nil, ""
+}
@@ -0,0 +1,337 @@
+// Package oauth implements the client side of the MCP authorization spec:
+// metadata discovery, dynamic client registration, PKCE and token refresh.
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ErrNoToken is returned when no usable token is stored for a server
+var ErrNoToken = errors.New("no token stored")
+
+// Token is an OAuth access token with its optional refresh token
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the token is expired or about to expire
+func (t *Token) Expired() bool {
+	if t.ExpiresAt.IsZero() {
+		return false
+	}
+	return time.Now().Add(30 * time.Second).After(t.ExpiresAt)
+}
+
+// ServerMetadata holds the authorization server endpoints (RFC 8414)
+type ServerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint,omitempty"`
+	ScopesSupported               []string `json:"scopes_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// ClientInfo is the result of dynamic client registration (RFC 7591)
+type ClientInfo struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	RedirectURIs []string `json:"redirect_uris,omitempty"`
+}
+
+// protectedResourceMetadata is the subset of RFC 9728 metadata we need
+type protectedResourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"`
+}
+
+// errorResponse is the standard OAuth error body
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// tokenResponse is the token endpoint's success body
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope"`
+}
+
+// Discover finds the authorization server for an MCP server URL. It first
+// looks for protected resource metadata, then for authorization server
+// metadata, and falls back to the default /authorize, /token and /register
+// endpoints on the issuer when neither document exists.
+func Discover(ctx context.Context, httpClient *http.Client, serverURL string) (*ServerMetadata, error) {
+	base, err := baseURL(serverURL)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := base
+	var resource protectedResourceMetadata
+	found, err := getJSON(ctx, httpClient, base+"/.well-known/oauth-protected-resource", &resource)
+	if err != nil {
+		return nil, err
+	}
+	if found && len(resource.AuthorizationServers) > 0 {
+		issuer = strings.TrimSuffix(resource.AuthorizationServers[0], "/")
+	}
+
+	issuerURL, err := url.Parse(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuer %q: %w", issuer, err)
+	}
+	wellKnown := fmt.Sprintf("%s://%s/.well-known/oauth-authorization-server%s",
+		issuerURL.Scheme, issuerURL.Host, strings.TrimSuffix(issuerURL.Path, "/"))
+
+	var meta ServerMetadata
+	found, err = getJSON(ctx, httpClient, wellKnown, &meta)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return &ServerMetadata{
+			Issuer:                issuer,
+			AuthorizationEndpoint: issuer + "/authorize",
+			TokenEndpoint:         issuer + "/token",
+			RegistrationEndpoint:  issuer + "/register",
+		}, nil
+	}
+
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" {
+		return nil, fmt.Errorf("authorization server metadata at %s is incomplete", wellKnown)
+	}
+	return &meta, nil
+}
+
+// Register performs dynamic client registration for a public client
+func Register(ctx context.Context, httpClient *http.Client, meta *ServerMetadata, clientName, redirectURI string) (*ClientInfo, error) {
+	if meta.RegistrationEndpoint == "" {
+		return nil, fmt.Errorf("authorization server does not support dynamic client registration")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"client_name":                clientName,
+		"redirect_uris":              []string{redirectURI},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"response_types":             []string{"code"},
+		"token_endpoint_auth_method": "none",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling registration request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", meta.RegistrationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating registration request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error registering client: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, responseError("client registration", resp)
+	}
+
+	var info ClientInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("error decoding registration response: %w", err)
+	}
+	if info.ClientID == "" {
+		return nil, fmt.Errorf("registration response has no client_id")
+	}
+	if len(info.RedirectURIs) == 0 {
+		info.RedirectURIs = []string{redirectURI}
+	}
+	return &info, nil
+}
+
+// newPKCE returns a code verifier and its S256 challenge
+func newPKCE() (verifier, challenge string, err error) {
+	verifier, err = randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// authorizationURL builds the URL the user opens to grant access
+func authorizationURL(meta *ServerMetadata, client *ClientInfo, redirectURI, challenge, state, resource string) (string, error) {
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", client.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	q.Set("state", state)
+	q.Set("resource", resource)
+	if len(meta.ScopesSupported) > 0 {
+		q.Set("scope", strings.Join(meta.ScopesSupported, " "))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// exchangeCode trades an authorization code for a token
+func exchangeCode(ctx context.Context, httpClient *http.Client, meta *ServerMetadata, client *ClientInfo, code, verifier, redirectURI, resource string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("code_verifier", verifier)
+	form.Set("resource", resource)
+	return requestToken(ctx, httpClient, meta, client, form)
+}
+
+// refreshToken trades a refresh token for a new token
+func refreshToken(ctx context.Context, httpClient *http.Client, meta *ServerMetadata, client *ClientInfo, token *Token, resource string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", token.RefreshToken)
+	form.Set("resource", resource)
+
+	refreshed, err := requestToken(ctx, httpClient, meta, client, form)
+	if err != nil {
+		return nil, err
+	}
+	// servers may keep the old refresh token instead of rotating it
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	return refreshed, nil
+}
+
+func requestToken(ctx context.Context, httpClient *http.Client, meta *ServerMetadata, client *ClientInfo, form url.Values) (*Token, error) {
+	form.Set("client_id", client.ClientID)
+	if client.ClientSecret != "" {
+		form.Set("client_secret", client.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("token request", resp)
+	}
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("error decoding token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+
+	token := &Token{
+		AccessToken:  tr.AccessToken,
+		TokenType:    tr.TokenType,
+		RefreshToken: tr.RefreshToken,
+		Scope:        tr.Scope,
+	}
+	if tr.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// getJSON fetches a metadata document; found is false on 404
+func getJSON(ctx context.Context, httpClient *http.Client, rawURL string, v interface{}) (found bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("MCP-Protocol-Version", mcp.LATEST_PROTOCOL_VERSION)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("error fetching %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, responseError("metadata request", resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("error decoding %s: %w", rawURL, err)
+	}
+	return true, nil
+}
+
+func responseError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var errResp errorResponse
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+		if errResp.ErrorDescription != "" {
+			return fmt.Errorf("%s failed: %s: %s", op, errResp.Error, errResp.ErrorDescription)
+		}
+		return fmt.Errorf("%s failed: %s", op, errResp.Error)
+	}
+	return fmt.Errorf("%s failed with status %d", op, resp.StatusCode)
+}
+
+// baseURL strips the path from a server URL, as the spec defines the
+// authorization base URL
+func baseURL(serverURL string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %q: %w", serverURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid server URL %q", serverURL)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
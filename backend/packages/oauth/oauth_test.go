@@ -0,0 +1,322 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// standInServer is a minimal authorization server that approves every request
+type standInServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	clients    map[string]string // client_id -> redirect_uri
+	codes      map[string]string // code -> code_challenge
+	refreshes  map[string]bool
+	issued     int
+	expiresIn  int
+	registered int
+}
+
+func newStandInServer(t *testing.T) *standInServer {
+	s := &standInServer{
+		clients:   map[string]string{},
+		codes:     map[string]string{},
+		refreshes: map[string]bool{},
+		expiresIn: 3600,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"resource":              s.URL + "/mcp",
+			"authorization_servers": []string{s.URL + "/auth"},
+		})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server/auth", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 s.URL + "/auth",
+			"authorization_endpoint": s.URL + "/auth/authorize",
+			"token_endpoint":         s.URL + "/auth/token",
+			"registration_endpoint":  s.URL + "/auth/register",
+		})
+	})
+	mux.HandleFunc("/auth/register", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RedirectURIs []string `json:"redirect_uris"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		s.mu.Lock()
+		s.registered++
+		id := fmt.Sprintf("client-%d", s.registered)
+		s.clients[id] = req.RedirectURIs[0]
+		s.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"client_id": id, "redirect_uris": req.RedirectURIs})
+	})
+	mux.HandleFunc("/auth/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		s.mu.Lock()
+		redirect, ok := s.clients[q.Get("client_id")]
+		s.mu.Unlock()
+		if !ok || redirect != q.Get("redirect_uri") || q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "bad authorization request", http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		code := fmt.Sprintf("code-%d", len(s.codes)+1)
+		s.codes[code] = q.Get("code_challenge")
+		s.mu.Unlock()
+
+		http.Redirect(w, r, redirect+"?code="+code+"&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/auth/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			challenge, ok := s.codes[r.Form.Get("code")]
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			delete(s.codes, r.Form.Get("code"))
+		case "refresh_token":
+			if !s.refreshes[r.Form.Get("refresh_token")] {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unsupported_grant_type"})
+			return
+		}
+
+		s.issued++
+		refresh := fmt.Sprintf("refresh-%d", s.issued)
+		s.refreshes[refresh] = true
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  fmt.Sprintf("access-%d", s.issued),
+			"token_type":    "Bearer",
+			"refresh_token": refresh,
+			"expires_in":    s.expiresIn,
+		})
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// visit follows the authorization URL like a browser would
+func visit(t *testing.T) func(string) {
+	return func(authURL string) {
+		go func() {
+			resp, err := http.Get(authURL)
+			if err != nil {
+				t.Errorf("visiting authorization URL: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+}
+
+func TestDiscoverUsesProtectedResourceMetadata(t *testing.T) {
+	srv := newStandInServer(t)
+
+	meta, err := Discover(context.Background(), http.DefaultClient, srv.URL+"/mcp/sse")
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if meta.TokenEndpoint != srv.URL+"/auth/token" {
+		t.Errorf("Expected token endpoint from metadata, got %s", meta.TokenEndpoint)
+	}
+}
+
+func TestDiscoverFallsBackToDefaultEndpoints(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	meta, err := Discover(context.Background(), http.DefaultClient, srv.URL+"/sse")
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if meta.AuthorizationEndpoint != srv.URL+"/authorize" || meta.RegistrationEndpoint != srv.URL+"/register" {
+		t.Errorf("Unexpected fallback metadata: %+v", meta)
+	}
+}
+
+// mapVault stands in for the encrypted secret store
+type mapVault map[string]string
+
+func (v mapVault) Get(name string) (string, bool) {
+	value, ok := v[name]
+	return value, ok
+}
+
+func (v mapVault) Set(name, value string) error {
+	v[name] = value
+	return nil
+}
+
+func TestAuthorizeAndRefresh(t *testing.T) {
+	srv := newStandInServer(t)
+	storePath := filepath.Join(t.TempDir(), "tokens.json")
+	store, err := NewStore(storePath)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	vault := mapVault{}
+	if err := store.SetVault(vault); err != nil {
+		t.Fatalf("SetVault: %v", err)
+	}
+	mgr := NewManager(store, "test")
+	serverURL := srv.URL + "/mcp/sse"
+
+	if _, err := mgr.Token(context.Background(), "remote", serverURL); err != ErrNoToken {
+		t.Fatalf("Expected ErrNoToken before authorizing, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token, err := mgr.Authorize(ctx, "remote", serverURL, visit(t))
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if token.AccessToken != "access-1" {
+		t.Errorf("Expected access-1, got %s", token.AccessToken)
+	}
+
+	// credentials survive a restart in the vault, never in a plaintext file
+	if _, err := os.Stat(storePath); !os.IsNotExist(err) {
+		t.Errorf("Expected no plaintext token file, got %v", err)
+	}
+	reloaded, err := NewStore(storePath)
+	if err != nil {
+		t.Fatalf("reloading store: %v", err)
+	}
+	if err := reloaded.SetVault(vault); err != nil {
+		t.Fatalf("SetVault: %v", err)
+	}
+	mgr = NewManager(reloaded, "test")
+
+	token, err = mgr.Token(context.Background(), "remote", serverURL)
+	if err != nil || token.AccessToken != "access-1" {
+		t.Fatalf("Expected stored token, got %v, %v", token, err)
+	}
+
+	// an expired token is refreshed transparently
+	creds, _ := reloaded.Get("remote")
+	creds.Token.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := reloaded.Put("remote", creds); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	token, err = mgr.Token(context.Background(), "remote", serverURL)
+	if err != nil {
+		t.Fatalf("Token after expiry: %v", err)
+	}
+	if token.AccessToken != "access-2" || token.RefreshToken != "refresh-2" {
+		t.Errorf("Expected refreshed token, got %+v", token)
+	}
+}
+
+func TestReauthorizeReusesRegistration(t *testing.T) {
+	srv := newStandInServer(t)
+	store, _ := NewStore(filepath.Join(t.TempDir(), "tokens.json"))
+	mgr := NewManager(store, "test")
+	serverURL := srv.URL + "/mcp/sse"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		if _, err := mgr.Authorize(ctx, "remote", serverURL, visit(t)); err != nil {
+			t.Fatalf("Authorize: %v", err)
+		}
+	}
+	if srv.registered != 1 {
+		t.Errorf("Expected one client registration, got %d", srv.registered)
+	}
+
+	// a taken port falls back to a new registration
+	creds, _ := store.Get("remote")
+	u, _ := url.Parse(creds.Client.RedirectURIs[0])
+	busy, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer busy.Close()
+	if _, err := mgr.Authorize(ctx, "remote", serverURL, visit(t)); err != nil {
+		t.Fatalf("Authorize with a busy port: %v", err)
+	}
+	if srv.registered != 2 {
+		t.Errorf("Expected a second registration, got %d", srv.registered)
+	}
+}
+
+func TestStoreMovesPlaintextFileIntoVault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	legacy := `{"remote": {"serverUrl": "https://example.com/sse", "token": {"access_token": "old"}}}`
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+
+	vault := mapVault{}
+	if err := store.SetVault(vault); err != nil {
+		t.Fatalf("SetVault: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the plaintext file to be removed, got %v", err)
+	}
+	if !strings.Contains(vault[vaultKey], `"old"`) {
+		t.Errorf("Expected the token in the vault, got %q", vault[vaultKey])
+	}
+}
+
+func TestAuthorizeRejectsStateMismatch(t *testing.T) {
+	srv := newStandInServer(t)
+	store, _ := NewStore(filepath.Join(t.TempDir(), "tokens.json"))
+	mgr := NewManager(store, "test")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := mgr.Authorize(ctx, "remote", srv.URL+"/mcp/sse", func(authURL string) {
+		u, _ := url.Parse(authURL)
+		redirect := u.Query().Get("redirect_uri")
+		go http.Get(redirect + "?code=forged&state=wrong")
+	})
+	if err == nil {
+		t.Fatal("Expected an error for a forged callback")
+	}
+	if _, ok := store.Get("remote"); ok {
+		t.Error("Expected nothing stored after a failed flow")
+	}
+}
@@ -0,0 +1,126 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// vaultKey is the secret the credentials of every server are kept under
+const vaultKey = "mcp-oauth-credentials"
+
+// Credentials is everything stored for one server
+type Credentials struct {
+	ServerURL string          `json:"serverUrl"`
+	Metadata  *ServerMetadata `json:"metadata,omitempty"`
+	Client    *ClientInfo     `json:"client,omitempty"`
+	Token     *Token          `json:"token,omitempty"`
+}
+
+// Vault persists credentials; the encrypted secret store satisfies it
+type Vault interface {
+	Get(name string) (string, bool)
+	Set(name, value string) error
+}
+
+// Store keeps credentials per server name. They are only written to an
+// attached vault and live in memory until one is attached, so tokens never
+// reach disk in plain text.
+type Store struct {
+	legacyPath string
+	mutex      sync.Mutex
+	creds      map[string]Credentials
+	vault      Vault
+}
+
+// NewStore creates an in-memory store. legacyPath is the plaintext file
+// earlier versions wrote; its credentials are loaded and the file is
+// removed once a vault holds them.
+func NewStore(legacyPath string) (*Store, error) {
+	s := &Store{
+		legacyPath: legacyPath,
+		creds:      make(map[string]Credentials),
+	}
+
+	data, err := os.ReadFile(legacyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read token store: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.creds); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token store: %w", err)
+	}
+	return s, nil
+}
+
+// SetVault attaches the vault credentials are persisted to, loading what it
+// already holds. Credentials obtained before, e.g. while the vault was
+// locked, take precedence and are saved to it.
+func (s *Store) SetVault(vault Vault) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored := make(map[string]Credentials)
+	if data, ok := vault.Get(vaultKey); ok {
+		if err := json.Unmarshal([]byte(data), &stored); err != nil {
+			return fmt.Errorf("failed to unmarshal stored credentials: %w", err)
+		}
+	}
+	for server, creds := range s.creds {
+		stored[server] = creds
+	}
+	s.creds = stored
+	s.vault = vault
+	return s.save()
+}
+
+// Get returns the credentials stored for a server
+func (s *Store) Get(server string) (Credentials, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c, ok := s.creds[server]
+	return c, ok
+}
+
+// Put replaces the credentials of a server and saves the store
+func (s *Store) Put(server string, creds Credentials) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.creds[server] = creds
+	return s.save()
+}
+
+// Delete forgets a server's credentials and saves the store
+func (s *Store) Delete(server string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.creds, server)
+	return s.save()
+}
+
+// save writes the credentials to the vault, if one is attached, and then
+// removes the legacy plaintext file
+func (s *Store) save() error {
+	if s.vault == nil {
+		return nil
+	}
+	data, err := json.Marshal(s.creds)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token store: %w", err)
+	}
+	if err := s.vault.Set(vaultKey, string(data)); err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	if s.legacyPath != "" {
+		if err := os.Remove(s.legacyPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove plaintext token store: %w", err)
+		}
+	}
+	return nil
+}
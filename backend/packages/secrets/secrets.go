@@ -0,0 +1,77 @@
+// Package secrets resolves ${env:VAR} and ${secret:name} references in
+// server configs and keeps secret values in an encrypted store.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MaskedValue replaces secret values shown to the user
+const MaskedValue = "********"
+
+// ErrUnresolved is returned when a reference cannot be resolved
+var ErrUnresolved = errors.New("unresolved reference")
+
+// Lookup finds secret values by name
+type Lookup interface {
+	Get(name string) (string, bool)
+}
+
+var referenceRe = regexp.MustCompile(`\$\{(env|secret):([^}]+)\}`)
+
+// HasReference reports whether value contains a ${env:...} or ${secret:...} reference
+func HasReference(value string) bool {
+	return referenceRe.MatchString(value)
+}
+
+// Expand replaces every reference in value. Secrets come from lookup, which
+// may be nil while the store is locked.
+func Expand(value string, lookup Lookup) (string, error) {
+	var firstErr error
+	expanded := referenceRe.ReplaceAllStringFunc(value, func(ref string) string {
+		m := referenceRe.FindStringSubmatch(ref)
+		kind, name := m[1], strings.TrimSpace(m[2])
+
+		switch kind {
+		case "env":
+			if v, ok := os.LookupEnv(name); ok {
+				return v
+			}
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%w: environment variable %s is not set", ErrUnresolved, name)
+			}
+		case "secret":
+			if lookup == nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%w: secret store is locked, cannot read %s", ErrUnresolved, name)
+				}
+				return ref
+			}
+			if v, ok := lookup.Get(name); ok {
+				return v
+			}
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%w: secret %s does not exist", ErrUnresolved, name)
+			}
+		}
+		return ref
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return expanded, nil
+}
+
+// Mask hides a literal env or header value, which may be a credential
+// whatever its name (GH_PAT, DATABASE_URL, ...). References are left
+// visible since they only name where the value comes from.
+func Mask(value string) string {
+	if value == "" || HasReference(value) {
+		return value
+	}
+	return MaskedValue
+}
@@ -0,0 +1,115 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type mapLookup map[string]string
+
+func (m mapLookup) Get(name string) (string, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+func TestExpand(t *testing.T) {
+	t.Setenv("SPOT_TEST_HOME", "/home/spot")
+	lookup := mapLookup{"github": "ghp_123"}
+
+	got, err := Expand("Bearer ${secret:github} from ${env:SPOT_TEST_HOME}", lookup)
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if got != "Bearer ghp_123 from /home/spot" {
+		t.Errorf("Unexpected expansion: %s", got)
+	}
+
+	if got, _ := Expand("plain ${OTHER} value", nil); got != "plain ${OTHER} value" {
+		t.Errorf("Expected other placeholders to be left alone, got %s", got)
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		lookup Lookup
+	}{
+		{"missing env", "${env:SPOT_TEST_SURELY_UNSET}", nil},
+		{"missing secret", "${secret:nope}", mapLookup{}},
+		{"locked store", "${secret:github}", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Expand(tt.value, tt.lookup)
+			if !errors.Is(err, ErrUnresolved) {
+				t.Errorf("Expected ErrUnresolved, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"ghp_123", MaskedValue},
+		{"Bearer abc", MaskedValue},
+		{"postgres://app:hunter2@db/app", MaskedValue},
+		{"${secret:api}", "${secret:api}"},
+		{"Bearer ${env:GH_PAT}", "Bearer ${env:GH_PAT}"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Mask(tt.value); got != tt.want {
+			t.Errorf("Mask(%s): expected %s, got %s", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc.json")
+
+	store, err := Open(path, "correct horse")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := store.Set("github", "ghp_123"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.Set("slack", "xoxb-456"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading store: %v", err)
+	}
+	if strings.Contains(string(raw), "ghp_123") {
+		t.Error("Expected secret values to be encrypted on disk")
+	}
+
+	reopened, err := Open(path, "correct horse")
+	if err != nil {
+		t.Fatalf("reopening store: %v", err)
+	}
+	if v, ok := reopened.Get("github"); !ok || v != "ghp_123" {
+		t.Errorf("Expected ghp_123, got %q", v)
+	}
+
+	if err := reopened.Delete("slack"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if names := reopened.Names(); len(names) != 1 || names[0] != "github" {
+		t.Errorf("Expected only github, got %v", names)
+	}
+
+	if _, err := Open(path, "wrong horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Expected ErrWrongPassphrase, got %v", err)
+	}
+}
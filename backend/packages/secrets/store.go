@@ -0,0 +1,197 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	storeVersion = 1
+
+	// scrypt parameters recommended for interactive logins
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	keyLength  = 32
+	saltLength = 16
+)
+
+// ErrWrongPassphrase is returned when the store cannot be decrypted
+var ErrWrongPassphrase = errors.New("wrong passphrase for secret store")
+
+// encryptedFile is the on-disk layout of the store
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Store keeps named secrets in a file encrypted with a key derived from a
+// passphrase (scrypt + AES-256-GCM)
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	key     []byte
+	salt    []byte
+	secrets map[string]string
+}
+
+// Open decrypts the store at path, creating an empty one if it does not exist
+func Open(path, passphrase string) (*Store, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+
+	s := &Store{path: path, secrets: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.salt = make([]byte, saltLength)
+		if _, err := rand.Read(s.salt); err != nil {
+			return nil, fmt.Errorf("generating salt: %w", err)
+		}
+		if s.key, err = deriveKey(passphrase, s.salt); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading secret store: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing secret store: %w", err)
+	}
+	if file.Version != storeVersion {
+		return nil, fmt.Errorf("unsupported secret store version %d", file.Version)
+	}
+
+	s.salt = file.Salt
+	if s.key, err = deriveKey(passphrase, s.salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	if err := json.Unmarshal(plain, &s.secrets); err != nil {
+		return nil, fmt.Errorf("parsing secrets: %w", err)
+	}
+	return s, nil
+}
+
+// Get returns the value of a secret
+func (s *Store) Get(name string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.secrets[name]
+	return value, ok
+}
+
+// Names returns the names of all stored secrets, sorted
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set stores a secret and writes the store to disk
+func (s *Store) Set(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("secret name cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.secrets[name] = value
+	return s.save()
+}
+
+// Delete removes a secret and writes the store to disk
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.secrets[name]; !ok {
+		return fmt.Errorf("secret %s does not exist", name)
+	}
+	delete(s.secrets, name)
+	return s.save()
+}
+
+// save encrypts and writes the store; callers hold s.mu
+func (s *Store) save() error {
+	plain, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generating nonce: %w", err)
+	}
+
+	data, err := json.MarshalIndent(encryptedFile{
+		Version: storeVersion,
+		Salt:    s.salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plain, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("creating secret store directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("writing secret store: %w", err)
+	}
+	return nil
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
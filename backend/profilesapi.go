@@ -0,0 +1,208 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+
+	"smart-spotlight-ai/backend/llm"
+	"smart-spotlight-ai/backend/llm/mcphost"
+	"smart-spotlight-ai/backend/packages/llm/models"
+	"smart-spotlight-ai/backend/packages/llm/providers"
+	"smart-spotlight-ai/backend/packages/secrets"
+	"smart-spotlight-ai/backend/settings"
+)
+
+// profileConfig resolves a profile into a provider configuration. Key
+// references are expanded here so resolved keys never reach settings.json.
+// A key that cannot be resolved is reported and left empty.
+func (a *App) profileConfig(profile settings.ProviderProfile) (providers.Config, error) {
+	config := providers.Config{
+		Name:     profile.Name,
+		Provider: profile.Provider,
+		BaseURL:  profile.BaseURL,
+		Model:    profile.Model,
+		Params: models.GenerationParams{
+			Temperature: profile.Temperature,
+			MaxTokens:   profile.MaxTokens,
+		},
+	}
+	if profile.Ollama != nil {
+		config.Ollama = *profile.Ollama
+	}
+	if profile.Anthropic != nil {
+		config.Anthropic = *profile.Anthropic
+	}
+	if profile.OpenAI != nil {
+		config.OpenAI = *profile.OpenAI
+	}
+	if profile.Azure != nil {
+		config.Azure = *profile.Azure
+	}
+	if profile.Bedrock != nil {
+		config.Bedrock = *profile.Bedrock
+	}
+	apiKey, err := secrets.Expand(profile.APIKey, a.secretLookup())
+	if err != nil {
+		return config, fmt.Errorf("profile %s: API key: %w", profile.Name, err)
+	}
+	config.APIKey = apiKey
+	return config, nil
+}
+
+// profileChain resolves the named profile and its fallbacks. A profile whose
+// key cannot be resolved stays in the chain, using it fails with an auth
+// error that hands over to the next one.
+func (a *App) profileChain(s *settings.Settings, name string) []providers.Config {
+	var chain []providers.Config
+	for _, profile := range s.ProfileChain(name) {
+		config, err := a.profileConfig(profile)
+		if err != nil {
+			log.Printf("Error resolving provider profile: %v", err)
+		}
+		chain = append(chain, config)
+	}
+	return chain
+}
+
+// defaultChain resolves the default profile and its fallbacks
+func (a *App) defaultChain(s *settings.Settings) []providers.Config {
+	profile, ok := s.ActiveProfile()
+	if !ok {
+		return nil
+	}
+	return a.profileChain(s, profile.Name)
+}
+
+// mcpProvider converts a resolved chain into the MCP provider configuration
+func mcpProvider(chain []providers.Config) mcphost.LLMProvider {
+	provider := mcphost.LLMProvider{
+		Name:         chain[0].Name,
+		ProviderName: chain[0].Provider,
+		BaseURL:      chain[0].BaseURL,
+		APIKey:       chain[0].APIKey,
+		ModelName:    chain[0].Model,
+		Params:       chain[0].Params,
+		Ollama:       chain[0].Ollama,
+		Anthropic:    chain[0].Anthropic,
+		OpenAI:       chain[0].OpenAI,
+		Azure:        chain[0].Azure,
+		Bedrock:      chain[0].Bedrock,
+		Metadata:     make(map[string]string),
+	}
+	for _, fallback := range chain[1:] {
+		provider.Fallbacks = append(provider.Fallbacks, mcpProvider([]providers.Config{fallback}))
+	}
+	return provider
+}
+
+// routeQuery splits a leading @profile off query and resolves that profile's
+// chain; a nil chain means the query uses the default profile
+func (a *App) routeQuery(query string) ([]providers.Config, string) {
+	current := settings.GetCurrentSettings()
+	name, rest, ok := current.RouteQuery(query)
+	if !ok {
+		return nil, query
+	}
+	return a.profileChain(current, name), rest
+}
+
+// applyProviderSettings rebuilds both search modes from the default profile
+func (a *App) applyProviderSettings() error {
+	current := settings.GetCurrentSettings()
+	a.capabilities.SetUserOverrides(current.CapabilityOverrides)
+	chain := a.defaultChain(current)
+	a.llmService = llm.NewService(chain...)
+
+	if a.mcpService == nil {
+		return a.initializeMCPService()
+	}
+	if len(chain) == 0 {
+		return fmt.Errorf("no provider profile configured")
+	}
+	a.mcpService.SetSystemPrompt(current.SystemPrompt, current.SystemPromptTemplate)
+	return a.mcpService.SetProvider(mcpProvider(chain))
+}
+
+// modifyProfiles saves a change to the profiles and applies it to both search modes
+func (a *App) modifyProfiles(modify func(*settings.Settings) error) error {
+	if _, err := settings.ModifySettings(modify); err != nil {
+		return err
+	}
+	if err := a.applyProviderSettings(); err != nil {
+		return fmt.Errorf("profile saved but could not be applied: %w", err)
+	}
+	// The default may now point at an endpoint whose models are not cached yet
+	go a.refreshAvailableModels()
+	return nil
+}
+
+// maskProfile hides a literal API key; references stay visible
+func maskProfile(profile settings.ProviderProfile) settings.ProviderProfile {
+	profile.APIKey = secrets.Mask(profile.APIKey)
+	return profile
+}
+
+// GetProviderProfiles returns every provider profile with keys masked
+func (a *App) GetProviderProfiles() []settings.ProviderProfile {
+	current := settings.GetCurrentSettings()
+	profiles := make([]settings.ProviderProfile, len(current.Profiles))
+	for i, profile := range current.Profiles {
+		profiles[i] = maskProfile(profile)
+	}
+	return profiles
+}
+
+// GetProviderProfile returns a single provider profile with its key masked
+func (a *App) GetProviderProfile(name string) (settings.ProviderProfile, error) {
+	profile, ok := settings.GetCurrentSettings().Profile(name)
+	if !ok {
+		return settings.ProviderProfile{}, fmt.Errorf("profile %s not found", name)
+	}
+	return maskProfile(profile), nil
+}
+
+// GetDefaultProviderProfile returns the name of the profile both search modes use
+func (a *App) GetDefaultProviderProfile() string {
+	return settings.GetCurrentSettings().DefaultProfile
+}
+
+// GetProviderTypes returns the provider types a profile can use
+func (a *App) GetProviderTypes() []string {
+	return settings.ProviderTypes
+}
+
+// CreateProviderProfile adds a provider profile
+func (a *App) CreateProviderProfile(profile settings.ProviderProfile) error {
+	return a.modifyProfiles(func(s *settings.Settings) error {
+		return s.AddProfile(profile)
+	})
+}
+
+// UpdateProviderProfile replaces a provider profile, which may be renamed.
+// A key sent back still masked keeps the stored key.
+func (a *App) UpdateProviderProfile(name string, profile settings.ProviderProfile) error {
+	return a.modifyProfiles(func(s *settings.Settings) error {
+		existing, ok := s.Profile(name)
+		if !ok {
+			return fmt.Errorf("profile %s not found", name)
+		}
+		if profile.APIKey == secrets.MaskedValue {
+			profile.APIKey = existing.APIKey
+		}
+		return s.UpdateProfile(name, profile)
+	})
+}
+
+// DeleteProviderProfile removes a provider profile other than the default
+func (a *App) DeleteProviderProfile(name string) error {
+	return a.modifyProfiles(func(s *settings.Settings) error {
+		return s.DeleteProfile(name)
+	})
+}
+
+// SetDefaultProviderProfile switches both search modes to another profile
+func (a *App) SetDefaultProviderProfile(name string) error {
+	return a.modifyProfiles(func(s *settings.Settings) error {
+		return s.SetDefaultProfile(name)
+	})
+}
@@ -0,0 +1,191 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"smart-spotlight-ai/backend/packages/secrets"
+	"smart-spotlight-ai/backend/settings"
+)
+
+// secretsFileName is the encrypted store behind ${secret:name} references
+const secretsFileName = "secrets.enc.json"
+
+// openSecretStore unlocks the secret store with SPOT_AI_SECRETS_PASSPHRASE, if set
+func (a *App) openSecretStore() {
+	passphrase := settings.GetEnvWithDefault("SPOT_AI_SECRETS_PASSPHRASE", "")
+	if passphrase == "" {
+		return
+	}
+	if err := a.unlockSecrets(passphrase); err != nil {
+		log.Printf("Error unlocking secret store: %v", err)
+	}
+}
+
+func (a *App) unlockSecrets(passphrase string) error {
+	configDir, err := settings.GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	store, err := secrets.Open(filepath.Join(configDir, secretsFileName), passphrase)
+	if err != nil {
+		return err
+	}
+	a.secrets = store
+	// OAuth tokens are kept in memory until the store can hold them
+	if a.mcpAuth != nil {
+		if err := a.mcpAuth.SetVault(store); err != nil {
+			log.Printf("Error moving OAuth credentials to the secret store: %v", err)
+		}
+	}
+	return nil
+}
+
+// secretLookup returns the unlocked store, or nil while it is locked
+func (a *App) secretLookup() secrets.Lookup {
+	if a.secrets == nil {
+		return nil
+	}
+	return a.secrets
+}
+
+// UnlockSecrets opens the secret store (creating it on first use) and
+// relaunches MCP servers and the provider so their ${secret:...} references resolve
+func (a *App) UnlockSecrets(passphrase string) error {
+	if err := a.unlockSecrets(passphrase); err != nil {
+		return err
+	}
+
+	if a.mcpService != nil {
+		a.mcpService.SetSecrets(a.secrets)
+		if err := a.mcpService.InitializeClients(); err != nil {
+			return fmt.Errorf("failed to relaunch MCP servers: %w", err)
+		}
+	}
+	// Profiles may keep their API key in the store
+	if err := a.applyProviderSettings(); err != nil {
+		return fmt.Errorf("failed to apply provider profile: %w", err)
+	}
+	if a.gateway != nil {
+		a.gateway.SetSecrets(a.secrets)
+		if err := a.ReloadMCPGateway(); err != nil {
+			return fmt.Errorf("failed to relaunch gateway servers: %w", err)
+		}
+	}
+	return nil
+}
+
+// IsSecretStoreUnlocked reports whether secrets can be read and written
+func (a *App) IsSecretStoreUnlocked() bool {
+	return a.secrets != nil
+}
+
+// ListSecrets returns the names of stored secrets; values never leave the backend
+func (a *App) ListSecrets() ([]string, error) {
+	if a.secrets == nil {
+		return nil, fmt.Errorf("secret store is locked")
+	}
+	return a.secrets.Names(), nil
+}
+
+// SetSecret stores a secret that configs can reference as ${secret:name}
+func (a *App) SetSecret(name, value string) error {
+	if a.secrets == nil {
+		return fmt.Errorf("secret store is locked")
+	}
+	return a.secrets.Set(name, value)
+}
+
+// DeleteSecret removes a stored secret
+func (a *App) DeleteSecret(name string) error {
+	if a.secrets == nil {
+		return fmt.Errorf("secret store is locked")
+	}
+	return a.secrets.Delete(name)
+}
+
+// maskEnv hides the literal values of env variables
+func maskEnv(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(env))
+	for key, value := range env {
+		masked[key] = secrets.Mask(value)
+	}
+	return masked
+}
+
+// maskHeaders hides the literal values of "Name: value" headers
+func maskHeaders(headers []string) []string {
+	if headers == nil {
+		return nil
+	}
+	masked := make([]string, len(headers))
+	for i, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			masked[i] = header
+			continue
+		}
+		masked[i] = name + ": " + secrets.Mask(strings.TrimSpace(value))
+	}
+	return masked
+}
+
+// maskServerConfig returns a copy of server with its env and header values masked
+func maskServerConfig(server settings.ServerConfigWrapper) settings.ServerConfigWrapper {
+	switch cfg := server.Config.(type) {
+	case settings.STDIOServerConfig:
+		cfg.Env = maskEnv(cfg.Env)
+		server.Config = cfg
+	case settings.SSEServerConfig:
+		cfg.Headers = maskHeaders(cfg.Headers)
+		server.Config = cfg
+	}
+	return server
+}
+
+// unmaskEnv restores values the frontend sent back still masked. A masked
+// value with no stored value behind it, e.g. after renaming the variable,
+// is rejected rather than saved as the mask.
+func unmaskEnv(existing, incoming map[string]string) (map[string]string, error) {
+	for key, value := range incoming {
+		if value != secrets.MaskedValue {
+			continue
+		}
+		stored, ok := existing[key]
+		if !ok {
+			return nil, fmt.Errorf("env variable %s has no stored value to keep, enter its value again", key)
+		}
+		incoming[key] = stored
+	}
+	return incoming, nil
+}
+
+// unmaskHeaders restores header values the frontend sent back still masked,
+// rejecting masked headers no stored header matches by name
+func unmaskHeaders(existing, incoming []string) ([]string, error) {
+	previous := make(map[string]string, len(existing))
+	for _, header := range existing {
+		if name, _, ok := strings.Cut(header, ":"); ok {
+			previous[strings.ToLower(strings.TrimSpace(name))] = header
+		}
+	}
+
+	for i, header := range incoming {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(value) != secrets.MaskedValue {
+			continue
+		}
+		stored, found := previous[strings.ToLower(strings.TrimSpace(name))]
+		if !found {
+			return nil, fmt.Errorf("header %s has no stored value to keep, enter its value again", strings.TrimSpace(name))
+		}
+		incoming[i] = stored
+	}
+	return incoming, nil
+}
@@ -0,0 +1,455 @@
+package settings
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// catalogDirName holds user-provided registry files next to the other config files
+const catalogDirName = "mcp-catalog"
+
+// CatalogSourceBundled marks entries that ship with the app
+const CatalogSourceBundled = "bundled"
+
+//go:embed mcp_catalog.json
+var bundledCatalog []byte
+
+var catalogPlaceholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)\s*\}\}`)
+
+// CatalogInput is a value the user fills in when installing a catalog entry.
+// It is referenced as {{name}} in the entry's args and env.
+type CatalogInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+	Secret      bool   `json:"secret,omitempty"` // kept in the secret store when it is unlocked
+	Default     string `json:"default,omitempty"`
+}
+
+// CatalogEntry describes a known server and how to launch it
+type CatalogEntry struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Homepage    string            `json:"homepage,omitempty"`
+	Runtime     string            `json:"runtime"` // what must be installed, e.g. node, python or docker
+	Command     string            `json:"command"`
+	Args        []string          `json:"args,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	Inputs      []CatalogInput    `json:"inputs,omitempty"`
+	Source      string            `json:"source"` // CatalogSourceBundled or the registry file
+}
+
+// CatalogOrigin records which catalog entry a server was installed from, the
+// non-secret inputs it was installed with and which secret inputs were set
+type CatalogOrigin struct {
+	ID      string            `json:"id"`
+	Inputs  map[string]string `json:"inputs,omitempty"`
+	Secrets []string          `json:"secrets,omitempty"`
+}
+
+// CatalogDrift lists how an installed server differs from its catalog entry
+type CatalogDrift struct {
+	Server      string   `json:"server"`
+	EntryID     string   `json:"entryId"`
+	Differences []string `json:"differences"`
+}
+
+type catalogFile struct {
+	Servers []CatalogEntry `json:"servers"`
+}
+
+// CatalogDir returns where user-provided registry files are read from
+func (s *MCPServerSettingsService) CatalogDir() string {
+	return filepath.Join(s.configDir, catalogDirName)
+}
+
+// Catalog returns the bundled entries merged with those of every *.json file
+// in CatalogDir, sorted by name. User entries replace bundled ones with the
+// same id; a file that cannot be read is logged and skipped.
+func (s *MCPServerSettingsService) Catalog() []CatalogEntry {
+	byID := map[string]CatalogEntry{}
+	add := func(data []byte, source string) error {
+		var file catalogFile
+		if err := json.Unmarshal(stripJSONC(data), &file); err != nil {
+			return err
+		}
+		for _, entry := range file.Servers {
+			if err := entry.validate(); err != nil {
+				slog.Warn("Skipping invalid catalog entry", "source", source, "error", err)
+				continue
+			}
+			entry.Source = source
+			byID[entry.ID] = entry
+		}
+		return nil
+	}
+
+	if err := add(bundledCatalog, CatalogSourceBundled); err != nil {
+		slog.Error("Failed to read bundled MCP catalog", "error", err)
+	}
+	paths, _ := filepath.Glob(filepath.Join(s.CatalogDir(), "*.json"))
+	sort.Strings(paths)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = add(data, path)
+		}
+		if err != nil {
+			slog.Warn("Skipping unreadable MCP catalog file", "path", path, "error", err)
+		}
+	}
+
+	entries := make([]CatalogEntry, 0, len(byID))
+	for _, entry := range byID {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// CatalogEntry returns the catalog entry with the given id
+func (s *MCPServerSettingsService) CatalogEntry(id string) (CatalogEntry, bool) {
+	for _, entry := range s.Catalog() {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return CatalogEntry{}, false
+}
+
+// validate checks that an entry can be installed and only refers to its own inputs
+func (e CatalogEntry) validate() error {
+	if e.ID == "" || e.Command == "" {
+		return fmt.Errorf("entry %q needs an id and a command", e.Name)
+	}
+	declared := map[string]bool{}
+	for _, input := range e.Inputs {
+		declared[input.Name] = true
+	}
+	for _, value := range e.templates() {
+		for _, match := range catalogPlaceholderRe.FindAllStringSubmatch(value, -1) {
+			if !declared[match[1]] {
+				return fmt.Errorf("entry %s uses undeclared input %q", e.ID, match[1])
+			}
+		}
+	}
+	return nil
+}
+
+// templates returns every string of the entry that may hold placeholders
+func (e CatalogEntry) templates() []string {
+	values := append([]string{}, e.Args...)
+	for _, value := range e.Env {
+		values = append(values, value)
+	}
+	return values
+}
+
+// RuntimeAvailable reports whether the entry's command can be found on
+// pathEnv; an empty pathEnv searches our own PATH
+func (e CatalogEntry) RuntimeAvailable(pathEnv string) bool {
+	return findExecutable(e.Command, pathEnv)
+}
+
+// render fills in the entry's placeholders. Inputs without a value fall back
+// to their default; a required input without either is an error.
+func (e CatalogEntry) render(values map[string]string) (STDIOServerConfig, error) {
+	resolved := map[string]string{}
+	for _, input := range e.Inputs {
+		value := strings.TrimSpace(values[input.Name])
+		if value == "" {
+			value = input.Default
+		}
+		if value == "" && input.Required {
+			return STDIOServerConfig{}, fmt.Errorf("%s is required: %s", input.Name, input.Description)
+		}
+		resolved[input.Name] = value
+	}
+	for name := range values {
+		if _, ok := resolved[name]; !ok {
+			return STDIOServerConfig{}, fmt.Errorf("%s has no input named %s", e.Name, name)
+		}
+	}
+
+	fill := func(value string) string {
+		return catalogPlaceholderRe.ReplaceAllStringFunc(value, func(match string) string {
+			return resolved[catalogPlaceholderRe.FindStringSubmatch(match)[1]]
+		})
+	}
+
+	config := STDIOServerConfig{Command: e.Command, Args: []string{}}
+	for _, arg := range e.Args {
+		// an optional input left empty drops an argument made of just it
+		if arg = fill(arg); arg != "" {
+			config.Args = append(config.Args, arg)
+		}
+	}
+	if len(e.Env) > 0 {
+		config.Env = make(map[string]string, len(e.Env))
+		for key, value := range e.Env {
+			if value = fill(value); value != "" {
+				config.Env[key] = value
+			}
+		}
+	}
+	return config, nil
+}
+
+// InstallFromCatalog adds the catalog entry id as server name. storeSecret
+// is called for every secret input and returns what goes into the config in
+// its place, typically a ${secret:...} reference. Secrets are only stored
+// once the inputs are valid and the name is free; deleteSecret removes them
+// again when the install fails after that.
+func (s *MCPServerSettingsService) InstallFromCatalog(
+	name, id string,
+	values map[string]string,
+	pathEnv string,
+	storeSecret func(key, value string) (string, error),
+	deleteSecret func(key string),
+) error {
+	entry, ok := s.CatalogEntry(id)
+	if !ok {
+		return fmt.Errorf("catalog has no entry %s", id)
+	}
+	if name == "" {
+		name = entry.ID
+	}
+	if _, exists := s.GetServer(name); exists {
+		return fmt.Errorf("server with name %s already exists", name)
+	}
+	if !entry.RuntimeAvailable(pathEnv) {
+		return fmt.Errorf("%s needs %s: %s was not found on PATH", entry.Name, entry.Runtime, entry.Command)
+	}
+	for key := range values {
+		if !entry.hasInput(key) {
+			return fmt.Errorf("%s has no input named %s", entry.Name, key)
+		}
+	}
+	if _, err := entry.render(values); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.configLoaded {
+		if err := s.loadServerConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to load server config: %w", err)
+		}
+	}
+	if _, exists := s.serverConfig.MCPServers[name]; exists {
+		return fmt.Errorf("server with name %s already exists", name)
+	}
+
+	var stored []string
+	installed := false
+	defer func() {
+		if !installed {
+			for _, key := range stored {
+				deleteSecret(key)
+			}
+		}
+	}()
+
+	filled := map[string]string{}
+	origin := &CatalogOrigin{ID: entry.ID, Inputs: map[string]string{}}
+	for key, value := range values {
+		if entry.input(key).Secret && value != "" {
+			ref, err := storeSecret(name+"/"+key, value)
+			if err != nil {
+				return fmt.Errorf("failed to store %s: %w", key, err)
+			}
+			stored = append(stored, name+"/"+key)
+			filled[key] = ref
+			origin.Secrets = append(origin.Secrets, key)
+			continue
+		}
+		filled[key] = value
+		origin.Inputs[key] = value
+	}
+
+	config, err := entry.render(filled)
+	if err != nil {
+		return err
+	}
+	sort.Strings(origin.Secrets)
+	config.Catalog = origin
+
+	s.serverConfig.MCPServers[name] = ServerConfigWrapper{Config: config, Enabled: true}
+	if err := s.saveServerConfig(); err != nil {
+		delete(s.serverConfig.MCPServers, name)
+		return err
+	}
+	installed = true
+	return nil
+}
+
+func (e CatalogEntry) hasInput(name string) bool {
+	return e.input(name).Name != ""
+}
+
+func (e CatalogEntry) input(name string) CatalogInput {
+	for _, input := range e.Inputs {
+		if input.Name == name {
+			return input
+		}
+	}
+	return CatalogInput{}
+}
+
+// CatalogDrift compares every server installed from the catalog with its
+// current entry. Secret values are not compared, only whether they are set.
+func (s *MCPServerSettingsService) CatalogDrift() []CatalogDrift {
+	entries := map[string]CatalogEntry{}
+	for _, entry := range s.Catalog() {
+		entries[entry.ID] = entry
+	}
+
+	servers := s.GetAllServers()
+	drifts := []CatalogDrift{}
+	for _, name := range sortedNames(servers) {
+		config, ok := servers[name].Config.(STDIOServerConfig)
+		if !ok || config.Catalog == nil {
+			continue
+		}
+		drift := CatalogDrift{Server: name, EntryID: config.Catalog.ID}
+		if entry, ok := entries[config.Catalog.ID]; ok {
+			drift.Differences = entry.differences(config)
+		} else {
+			drift.Differences = []string{fmt.Sprintf("catalog entry %s no longer exists", config.Catalog.ID)}
+		}
+		if len(drift.Differences) > 0 {
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts
+}
+
+// catalogSecretMarker stands in for secret values, which are not kept, when
+// an installed server is compared with its catalog entry
+const catalogSecretMarker = "\x00secret\x00"
+
+// matchesTemplate reports whether value equals expected, where every secret
+// marker in expected matches any non-empty text
+func matchesTemplate(value, expected string) bool {
+	if !strings.Contains(expected, catalogSecretMarker) {
+		return value == expected
+	}
+	parts := strings.Split(expected, catalogSecretMarker)
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, "(?s:.+)") + "$").MatchString(value)
+}
+
+// showTemplate renders expected for a message, with secrets shown as <secret>
+func showTemplate(expected string) string {
+	return strings.ReplaceAll(expected, catalogSecretMarker, "<secret>")
+}
+
+// differences describes how config deviates from the entry rendered with
+// the inputs config was installed with
+func (e CatalogEntry) differences(config STDIOServerConfig) []string {
+	set := map[string]bool{}
+	for _, name := range config.Catalog.Secrets {
+		set[name] = true
+	}
+	values := map[string]string{}
+	for _, input := range e.Inputs {
+		if input.Secret {
+			// only check that a secret is set, unless it was left out
+			if input.Required || set[input.Name] {
+				values[input.Name] = catalogSecretMarker
+			}
+		} else if value, ok := config.Catalog.Inputs[input.Name]; ok {
+			values[input.Name] = value
+		}
+	}
+	want, err := e.render(values)
+	if err != nil {
+		return []string{fmt.Sprintf("catalog entry now needs different inputs: %v", err)}
+	}
+
+	var diffs []string
+	if config.Command != want.Command {
+		diffs = append(diffs, fmt.Sprintf("command is %q, catalog has %q", config.Command, want.Command))
+	}
+	argsMatch := len(config.Args) == len(want.Args)
+	for i := 0; argsMatch && i < len(want.Args); i++ {
+		argsMatch = matchesTemplate(config.Args[i], want.Args[i])
+	}
+	if !argsMatch {
+		shown := make([]string, len(want.Args))
+		for i, arg := range want.Args {
+			shown[i] = showTemplate(arg)
+		}
+		diffs = append(diffs, fmt.Sprintf("args are %q, catalog has %q", config.Args, shown))
+	}
+
+	keys := map[string]bool{}
+	for key := range config.Env {
+		keys[key] = true
+	}
+	for key := range want.Env {
+		keys[key] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		have, installed := config.Env[key]
+		expected, listed := want.Env[key]
+		switch {
+		case !listed:
+			diffs = append(diffs, fmt.Sprintf("env %s is not in the catalog entry", key))
+		case !installed:
+			diffs = append(diffs, fmt.Sprintf("env %s from the catalog entry is missing", key))
+		case !matchesTemplate(have, expected):
+			diffs = append(diffs, fmt.Sprintf("env %s is %q, catalog has %q", key, have, showTemplate(expected)))
+		}
+	}
+	return diffs
+}
+
+// findExecutable reports whether name is an executable file in one of the
+// directories of pathEnv, or of our own PATH when pathEnv is empty
+func findExecutable(name, pathEnv string) bool {
+	if pathEnv == "" {
+		pathEnv = os.Getenv("PATH")
+	}
+	candidates := []string{name}
+	if runtime.GOOS == "windows" {
+		candidates = append(candidates, name+".exe", name+".cmd", name+".bat")
+	}
+
+	dirs := filepath.SplitList(pathEnv)
+	if strings.ContainsRune(name, filepath.Separator) {
+		dirs = []string{""}
+	}
+	for _, dir := range dirs {
+		for _, candidate := range candidates {
+			info, err := os.Stat(filepath.Join(dir, candidate))
+			if err == nil && !info.IsDir() && (runtime.GOOS == "windows" || info.Mode()&0111 != 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// userCatalog relies on sh being on PATH so the runtime check passes
+const userCatalog = `{
+	"servers": [
+		{
+			"id": "notes",
+			"name": "Notes",
+			"runtime": "shell",
+			"command": "sh",
+			"args": ["-c", "notes-server", "--dir", "{{dir}}", "{{verbose}}"],
+			"env": {"NOTES_TOKEN": "{{token}}", "NOTES_MODE": "{{mode}}"},
+			"inputs": [
+				{"name": "dir", "description": "Notes folder", "required": true},
+				{"name": "verbose", "description": "Extra flag"},
+				{"name": "mode", "description": "Mode", "default": "read"},
+				{"name": "token", "description": "API token", "required": true, "secret": true}
+			]
+		},
+		{
+			"id": "vault",
+			"name": "Vault",
+			"runtime": "shell",
+			"command": "sh",
+			"args": ["--key", "{{key}}"],
+			"env": {"VAULT_EXTRA": "{{extra}}"},
+			"inputs": [
+				{"name": "key", "description": "Access key", "required": true, "secret": true},
+				{"name": "extra", "description": "Second key", "secret": true}
+			]
+		},
+		{"id": "broken", "name": "Broken", "command": "sh", "args": ["{{nope}}"]}
+	]
+}`
+
+func newCatalogService(t *testing.T) *MCPServerSettingsService {
+	dir := t.TempDir()
+	svc, err := NewMCPServerSettingsService(dir)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := os.MkdirAll(svc.CatalogDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(svc.CatalogDir(), "team.json"), []byte(userCatalog), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+func TestCatalogMergesBundledAndUserEntries(t *testing.T) {
+	svc := newCatalogService(t)
+
+	entries := map[string]CatalogEntry{}
+	for _, entry := range svc.Catalog() {
+		entries[entry.ID] = entry
+	}
+	if entries["github"].Source != CatalogSourceBundled {
+		t.Errorf("Expected the bundled github entry, got %+v", entries["github"])
+	}
+	if !strings.HasSuffix(entries["notes"].Source, "team.json") {
+		t.Errorf("Expected the notes entry from team.json, got %+v", entries["notes"])
+	}
+	if _, ok := entries["broken"]; ok {
+		t.Errorf("Expected an entry with undeclared inputs to be skipped")
+	}
+}
+
+func TestInstallFromCatalog(t *testing.T) {
+	svc := newCatalogService(t)
+	stored := map[string]string{}
+	storeSecret := func(key, value string) (string, error) {
+		stored[key] = value
+		return "${secret:" + key + "}", nil
+	}
+	deleteSecret := func(key string) { delete(stored, key) }
+
+	err := svc.InstallFromCatalog("my-notes", "notes", map[string]string{"token": "t0k"}, "", storeSecret, deleteSecret)
+	if err == nil || !strings.Contains(err.Error(), "dir is required") {
+		t.Errorf("Expected a missing required input to be reported, got %v", err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("Expected no secret to be stored for invalid inputs, got %v", stored)
+	}
+	err = svc.InstallFromCatalog("my-notes", "notes", map[string]string{"dir": "/n", "colour": "red"}, "", storeSecret, deleteSecret)
+	if err == nil || !strings.Contains(err.Error(), "no input named colour") {
+		t.Errorf("Expected an unknown input to be rejected, got %v", err)
+	}
+	err = svc.InstallFromCatalog("my-notes", "notes", map[string]string{"dir": "/n"}, "/nonexistent", storeSecret, deleteSecret)
+	if err == nil || !strings.Contains(err.Error(), "needs shell") {
+		t.Errorf("Expected a missing runtime to be reported, got %v", err)
+	}
+
+	err = svc.InstallFromCatalog("my-notes", "notes", map[string]string{"dir": "/n", "token": "t0k"}, "", storeSecret, deleteSecret)
+	if err != nil {
+		t.Fatalf("InstallFromCatalog failed: %v", err)
+	}
+	if stored["my-notes/token"] != "t0k" {
+		t.Errorf("Expected the token in the secret store, got %v", stored)
+	}
+
+	server, ok := svc.GetServer("my-notes")
+	if !ok {
+		t.Fatal("Expected the server to be added")
+	}
+	config := server.Config.(STDIOServerConfig)
+	if want := []string{"-c", "notes-server", "--dir", "/n"}; !reflect.DeepEqual(config.Args, want) {
+		t.Errorf("Expected args %v, got %v", want, config.Args)
+	}
+	if want := map[string]string{"NOTES_TOKEN": "${secret:my-notes/token}", "NOTES_MODE": "read"}; !reflect.DeepEqual(config.Env, want) {
+		t.Errorf("Expected env %v, got %v", want, config.Env)
+	}
+	if config.Catalog == nil || config.Catalog.ID != "notes" || config.Catalog.Inputs["token"] != "" {
+		t.Errorf("Expected the catalog origin without the secret, got %+v", config.Catalog)
+	}
+
+	if drift := svc.CatalogDrift(); len(drift) != 0 {
+		t.Errorf("Expected a fresh install to match its entry, got %+v", drift)
+	}
+
+	err = svc.InstallFromCatalog("my-notes", "notes", map[string]string{"dir": "/m", "token": "other"}, "", storeSecret, deleteSecret)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected a taken name to be refused, got %v", err)
+	}
+	if stored["my-notes/token"] != "t0k" {
+		t.Errorf("Expected the installed server's secret to be kept, got %v", stored)
+	}
+}
+
+func TestInstallFromCatalogRemovesSecretsWhenSaveFails(t *testing.T) {
+	svc := newCatalogService(t)
+	stored := map[string]string{}
+	storeSecret := func(key, value string) (string, error) {
+		stored[key] = value
+		return "${secret:" + key + "}", nil
+	}
+	deleteSecret := func(key string) { delete(stored, key) }
+
+	// a directory in place of the config file makes the save fail
+	if err := os.Remove(svc.serversFile); err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(svc.serversFile, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	err := svc.InstallFromCatalog("my-notes", "notes", map[string]string{"dir": "/n", "token": "t0k"}, "", storeSecret, deleteSecret)
+	if err == nil {
+		t.Fatal("Expected the install to fail")
+	}
+	if len(stored) != 0 {
+		t.Errorf("Expected the stored secret to be removed, got %v", stored)
+	}
+	if _, ok := svc.GetServer("my-notes"); ok {
+		t.Errorf("Expected the server not to be added")
+	}
+}
+
+func TestCatalogDrift(t *testing.T) {
+	svc := newCatalogService(t)
+	storeSecret := func(key, value string) (string, error) { return value, nil }
+	deleteSecret := func(key string) {}
+	if err := svc.InstallFromCatalog("", "notes", map[string]string{"dir": "/n", "token": "t"}, "", storeSecret, deleteSecret); err != nil {
+		t.Fatalf("InstallFromCatalog failed: %v", err)
+	}
+
+	server, _ := svc.GetServer("notes")
+	config := server.Config.(STDIOServerConfig)
+	config.Args = append(config.Args, "--fast")
+	config.Env = map[string]string{"NOTES_TOKEN": "rotated", "NOTES_MODE": "write"}
+	if err := svc.UpdateServer("notes", ServerConfigWrapper{Config: config, Enabled: true}); err != nil {
+		t.Fatalf("UpdateServer failed: %v", err)
+	}
+
+	drift := svc.CatalogDrift()
+	if len(drift) != 1 || drift[0].Server != "notes" {
+		t.Fatalf("Expected drift for notes, got %+v", drift)
+	}
+	got := strings.Join(drift[0].Differences, "\n")
+	if !strings.Contains(got, "args are") || !strings.Contains(got, `env NOTES_MODE is "write"`) {
+		t.Errorf("Expected args and mode differences, got:\n%s", got)
+	}
+	if strings.Contains(got, "NOTES_TOKEN") {
+		t.Errorf("Expected a changed secret not to count as drift, got:\n%s", got)
+	}
+}
+
+func TestCatalogDriftOfSecrets(t *testing.T) {
+	svc := newCatalogService(t)
+	storeSecret := func(key, value string) (string, error) { return "${secret:" + key + "}", nil }
+	deleteSecret := func(key string) {}
+	if err := svc.InstallFromCatalog("", "vault", map[string]string{"key": "k"}, "", storeSecret, deleteSecret); err != nil {
+		t.Fatalf("InstallFromCatalog failed: %v", err)
+	}
+	if err := svc.InstallFromCatalog("both", "vault", map[string]string{"key": "k", "extra": "e"}, "", storeSecret, deleteSecret); err != nil {
+		t.Fatalf("InstallFromCatalog failed: %v", err)
+	}
+	// a secret in args and an optional secret left out are not drift
+	if drift := svc.CatalogDrift(); len(drift) != 0 {
+		t.Fatalf("Expected fresh installs to match their entry, got %+v", drift)
+	}
+
+	server, _ := svc.GetServer("vault")
+	config := server.Config.(STDIOServerConfig)
+	config.Args = []string{"--key", "rotated"}
+	if err := svc.UpdateServer("vault", ServerConfigWrapper{Config: config, Enabled: true}); err != nil {
+		t.Fatalf("UpdateServer failed: %v", err)
+	}
+	if drift := svc.CatalogDrift(); len(drift) != 0 {
+		t.Errorf("Expected a changed secret argument not to count as drift, got %+v", drift)
+	}
+
+	config.Args = []string{"--token", "rotated"}
+	if err := svc.UpdateServer("vault", ServerConfigWrapper{Config: config, Enabled: true}); err != nil {
+		t.Fatalf("UpdateServer failed: %v", err)
+	}
+	drift := svc.CatalogDrift()
+	if len(drift) != 1 || !strings.Contains(strings.Join(drift[0].Differences, "\n"), `catalog has ["--key" "<secret>"]`) {
+		t.Errorf("Expected the changed flag to be reported, got %+v", drift)
+	}
+}
@@ -0,0 +1,588 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"smart-spotlight-ai/backend/packages/secrets"
+)
+
+// Clients whose MCP configs can be imported and exported
+const (
+	ClientClaudeDesktop = "claude-desktop"
+	ClientCursor        = "cursor"
+	ClientVSCode        = "vscode"
+)
+
+// Conflict states reported by PreviewImport
+const (
+	ImportNew       = "new"
+	ImportIdentical = "identical"
+	ImportConflict  = "conflict"
+)
+
+// Strategies for servers whose name already exists
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+)
+
+// ImportSource is a client config file found on disk
+type ImportSource struct {
+	Client string `json:"client"`
+	Path   string `json:"path"`
+}
+
+// ImportCandidate is one server from an import source
+type ImportCandidate struct {
+	Name     string              `json:"name"`
+	Server   ServerConfigWrapper `json:"server"`
+	Status   string              `json:"status"`
+	Warnings []string            `json:"warnings,omitempty"`
+}
+
+// ImportPreview lists what an import would change
+type ImportPreview struct {
+	Source  ImportSource      `json:"source"`
+	Servers []ImportCandidate `json:"servers"`
+}
+
+// ImportResult summarizes a completed import
+type ImportResult struct {
+	Added    []string          `json:"added"`
+	Replaced []string          `json:"replaced"`
+	Skipped  []string          `json:"skipped"`
+	Renamed  map[string]string `json:"renamed"` // imported name -> name it was saved under
+}
+
+// clientServer is the union of the server shapes used by other clients
+type clientServer struct {
+	Type     string            `json:"type,omitempty"`
+	Command  string            `json:"command,omitempty"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Disabled bool              `json:"disabled,omitempty"`
+}
+
+// ImportSourcePaths returns the standard Linux locations of each client's config
+func ImportSourcePaths() []ImportSource {
+	home, _ := os.UserHomeDir()
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(home, ".config")
+	}
+
+	return []ImportSource{
+		{Client: ClientClaudeDesktop, Path: filepath.Join(configDir, "Claude", "claude_desktop_config.json")},
+		{Client: ClientCursor, Path: filepath.Join(home, ".cursor", "mcp.json")},
+		{Client: ClientVSCode, Path: filepath.Join(configDir, "Code", "User", "mcp.json")},
+		{Client: ClientVSCode, Path: filepath.Join(configDir, "Code", "User", "settings.json")},
+	}
+}
+
+// DetectImportSources returns the client config files that exist and contain servers
+func (s *MCPServerSettingsService) DetectImportSources() []ImportSource {
+	var found []ImportSource
+	for _, source := range ImportSourcePaths() {
+		data, err := os.ReadFile(source.Path)
+		if err != nil {
+			continue
+		}
+		servers, _, err := ParseClientConfig(source.Client, data)
+		if err == nil && len(servers) > 0 {
+			found = append(found, source)
+		}
+	}
+	return found
+}
+
+// ParseClientConfig converts another client's config file into our server
+// configs. Warnings describe anything that could not be carried over exactly.
+func ParseClientConfig(client string, data []byte) (map[string]ServerConfigWrapper, map[string][]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(stripJSONC(data), &raw); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s config: %w", client, err)
+	}
+
+	var serversJSON json.RawMessage
+	switch client {
+	case ClientClaudeDesktop, ClientCursor:
+		serversJSON = raw["mcpServers"]
+	case ClientVSCode:
+		// .vscode/mcp.json has top-level "servers"; settings.json nests them under "mcp"
+		serversJSON = raw["servers"]
+		if serversJSON == nil && raw["mcp"] != nil {
+			var mcpSection struct {
+				Servers json.RawMessage `json:"servers"`
+			}
+			if err := json.Unmarshal(raw["mcp"], &mcpSection); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse mcp section: %w", err)
+			}
+			serversJSON = mcpSection.Servers
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported client: %s", client)
+	}
+
+	servers := make(map[string]ServerConfigWrapper)
+	warnings := make(map[string][]string)
+	if serversJSON == nil {
+		return servers, warnings, nil
+	}
+
+	var entries map[string]clientServer
+	if err := json.Unmarshal(serversJSON, &entries); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse servers: %w", err)
+	}
+
+	for name, entry := range entries {
+		server, notes := entry.toServerConfig()
+		servers[name] = server
+		if len(notes) > 0 {
+			warnings[name] = notes
+		}
+	}
+	return servers, warnings, nil
+}
+
+// vscodeInputRe matches VS Code's prompted ${input:id} variables
+var vscodeInputRe = regexp.MustCompile(`\$\{input:([^}]+)\}`)
+
+func (c clientServer) toServerConfig() (ServerConfigWrapper, []string) {
+	var notes []string
+	convert := func(value string) string {
+		if vscodeInputRe.MatchString(value) {
+			notes = append(notes, "prompted inputs were mapped to ${secret:...} references; store them as secrets")
+			return vscodeInputRe.ReplaceAllString(value, "$${secret:$1}")
+		}
+		return value
+	}
+
+	wrapper := ServerConfigWrapper{Enabled: !c.Disabled}
+
+	if c.URL != "" {
+		if c.Type == "http" || c.Type == "streamable-http" {
+			notes = append(notes, "streamable HTTP server imported as SSE")
+		}
+
+		names := make([]string, 0, len(c.Headers))
+		for key := range c.Headers {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+
+		headers := make([]string, 0, len(names))
+		for _, key := range names {
+			headers = append(headers, key+": "+convert(c.Headers[key]))
+		}
+		if len(headers) == 0 {
+			headers = nil
+		}
+
+		wrapper.Config = SSEServerConfig{Url: convert(c.URL), Headers: headers}
+		return wrapper, dedupe(notes)
+	}
+
+	var env map[string]string
+	if len(c.Env) > 0 {
+		env = make(map[string]string, len(c.Env))
+		for key, value := range c.Env {
+			env[key] = convert(value)
+		}
+	}
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = convert(arg)
+	}
+
+	wrapper.Config = STDIOServerConfig{Command: convert(c.Command), Args: args, Env: env}
+	return wrapper, dedupe(notes)
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// PreviewImport reads a client config and reports how each server compares
+// with what is already configured
+func (s *MCPServerSettingsService) PreviewImport(client, path string) (*ImportPreview, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	servers, warnings, err := ParseClientConfig(client, data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	preview := &ImportPreview{Source: ImportSource{Client: client, Path: path}}
+	for _, name := range sortedNames(servers) {
+		candidate := ImportCandidate{
+			Name:     name,
+			Server:   servers[name],
+			Status:   ImportNew,
+			Warnings: warnings[name],
+		}
+		if existing, ok := s.serverConfig.MCPServers[name]; ok {
+			candidate.Status = ImportConflict
+			if reflect.DeepEqual(existing.Config, servers[name].Config) {
+				candidate.Status = ImportIdentical
+			}
+		}
+		preview.Servers = append(preview.Servers, candidate)
+	}
+	return preview, nil
+}
+
+// Import merges the servers of a client config. Servers whose name already
+// exists are skipped, overwritten or saved under a new name depending on
+// strategy; identical servers are always skipped.
+func (s *MCPServerSettingsService) Import(client, path, strategy string) (*ImportResult, error) {
+	switch strategy {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+	default:
+		return nil, fmt.Errorf("unknown conflict strategy: %s", strategy)
+	}
+
+	preview, err := s.PreviewImport(client, path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.configLoaded {
+		if err := s.loadServerConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to load server config: %w", err)
+		}
+	}
+
+	result := &ImportResult{Renamed: make(map[string]string)}
+	for _, candidate := range preview.Servers {
+		switch {
+		case candidate.Status == ImportIdentical:
+			result.Skipped = append(result.Skipped, candidate.Name)
+		case candidate.Status == ImportNew:
+			s.serverConfig.MCPServers[candidate.Name] = candidate.Server
+			result.Added = append(result.Added, candidate.Name)
+		case strategy == ConflictOverwrite:
+			s.serverConfig.MCPServers[candidate.Name] = candidate.Server
+			result.Replaced = append(result.Replaced, candidate.Name)
+		case strategy == ConflictRename:
+			name := s.freeName(candidate.Name)
+			s.serverConfig.MCPServers[name] = candidate.Server
+			result.Renamed[candidate.Name] = name
+		default:
+			result.Skipped = append(result.Skipped, candidate.Name)
+		}
+	}
+
+	if err := s.saveServerConfig(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// freeName returns name with the first numeric suffix not yet in use; callers hold s.mutex
+func (s *MCPServerSettingsService) freeName(name string) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if _, exists := s.serverConfig.MCPServers[candidate]; !exists {
+			return candidate
+		}
+	}
+}
+
+// ExportClientConfig renders our servers in a client's config format,
+// keeping the other keys of existing. VS Code servers go under "mcp.servers"
+// when existing is a settings.json and under "servers" otherwise. Disabled
+// servers are left out for clients whose format cannot mark them disabled.
+// existing with comments is refused, rewriting it would drop them.
+//
+// Other clients cannot resolve ${env:...} and ${secret:...} references.
+// resolve expands them into the written values; when it is nil, servers
+// using references are listed in the error and nothing is exported.
+func (s *MCPServerSettingsService) ExportClientConfig(client string, existing []byte, resolve func(string) (string, error)) ([]byte, error) {
+	s.mutex.RLock()
+	entries := make(map[string]clientServer, len(s.serverConfig.MCPServers))
+	for name, server := range s.serverConfig.MCPServers {
+		if !server.Enabled && client != ClientCursor {
+			continue
+		}
+		entries[name] = fromServerConfig(client, server)
+	}
+	s.mutex.RUnlock()
+
+	var referencing []string
+	for _, name := range sortedEntryNames(entries) {
+		entry := entries[name]
+		if !entry.hasReferences() {
+			continue
+		}
+		if resolve == nil {
+			referencing = append(referencing, name)
+			continue
+		}
+		if err := entry.resolveReferences(resolve); err != nil {
+			return nil, fmt.Errorf("server %s: %w", name, err)
+		}
+		entries[name] = entry
+	}
+	if len(referencing) > 0 {
+		return nil, fmt.Errorf("%s cannot resolve ${env:...} or ${secret:...} references, used by %s; export with references resolved",
+			client, strings.Join(referencing, ", "))
+	}
+
+	doc := make(map[string]interface{})
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if !bytes.Equal(removeComments(existing), existing) {
+			return nil, fmt.Errorf("existing %s config has comments, which exporting would remove; remove them or export to another file", client)
+		}
+		if err := json.Unmarshal(stripJSONC(existing), &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse existing %s config: %w", client, err)
+		}
+	}
+
+	switch client {
+	case ClientClaudeDesktop, ClientCursor:
+		doc["mcpServers"] = entries
+	case ClientVSCode:
+		if !isVSCodeSettings(doc) {
+			doc["servers"] = entries
+			break
+		}
+		section, _ := doc["mcp"].(map[string]interface{})
+		if section == nil {
+			section = make(map[string]interface{})
+		}
+		section["servers"] = entries
+		doc["mcp"] = section
+	default:
+		return nil, fmt.Errorf("unsupported client: %s", client)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Export writes our servers to a client config file, keeping unrelated keys.
+// resolve is passed on to ExportClientConfig.
+func (s *MCPServerSettingsService) Export(client, path string, resolve func(string) (string, error)) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	data, err := s.ExportClientConfig(client, existing, resolve)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// isVSCodeSettings reports whether doc is a VS Code settings.json rather than
+// an mcp.json, whose only top-level keys are "servers" and "inputs"
+func isVSCodeSettings(doc map[string]interface{}) bool {
+	if _, ok := doc["servers"]; ok {
+		return false
+	}
+	for key := range doc {
+		if key != "inputs" {
+			return true
+		}
+	}
+	return false
+}
+
+func fromServerConfig(client string, server ServerConfigWrapper) clientServer {
+	entry := clientServer{Disabled: !server.Enabled && client == ClientCursor}
+
+	switch cfg := server.Config.(type) {
+	case STDIOServerConfig:
+		entry.Command, entry.Args, entry.Env = cfg.Command, cfg.Args, cfg.Env
+		if client == ClientVSCode {
+			entry.Type = transportStdio
+		}
+	case SSEServerConfig:
+		entry.URL = cfg.Url
+		if len(cfg.Headers) > 0 {
+			entry.Headers = make(map[string]string, len(cfg.Headers))
+			for _, header := range cfg.Headers {
+				if key, value, ok := strings.Cut(header, ":"); ok {
+					entry.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+				}
+			}
+		}
+		if client == ClientVSCode {
+			entry.Type = transportSSE
+		}
+	}
+	return entry
+}
+
+// values returns pointers to every value of the entry that may hold a reference
+func (c *clientServer) values() []*string {
+	values := []*string{&c.Command, &c.URL}
+	for i := range c.Args {
+		values = append(values, &c.Args[i])
+	}
+	return values
+}
+
+func (c clientServer) hasReferences() bool {
+	for _, value := range c.values() {
+		if secrets.HasReference(*value) {
+			return true
+		}
+	}
+	for _, m := range []map[string]string{c.Env, c.Headers} {
+		for _, value := range m {
+			if secrets.HasReference(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveReferences expands the references of the entry, copying its args
+// and maps so the stored config is left alone
+func (c *clientServer) resolveReferences(resolve func(string) (string, error)) error {
+	c.Args = append([]string(nil), c.Args...)
+	for _, value := range c.values() {
+		resolved, err := resolve(*value)
+		if err != nil {
+			return err
+		}
+		*value = resolved
+	}
+	for _, m := range []*map[string]string{&c.Env, &c.Headers} {
+		if *m == nil {
+			continue
+		}
+		resolved := make(map[string]string, len(*m))
+		for key, value := range *m {
+			v, err := resolve(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			resolved[key] = v
+		}
+		*m = resolved
+	}
+	return nil
+}
+
+func sortedEntryNames(entries map[string]clientServer) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedNames(servers map[string]ServerConfigWrapper) []string {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// stripJSONC removes comments and trailing commas so VS Code style files parse as JSON
+func stripJSONC(data []byte) []byte {
+	return removeTrailingCommas(removeComments(data))
+}
+
+// scanJSON calls visit for every byte outside string literals; visit returns
+// how many bytes it consumed and what to write in their place
+func scanJSON(data []byte, visit func(i int) (int, []byte)) []byte {
+	var out bytes.Buffer
+	inString, escaped := false, false
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+			out.WriteByte(c)
+			continue
+		}
+
+		n, replacement := visit(i)
+		out.Write(replacement)
+		i += n - 1
+	}
+	return out.Bytes()
+}
+
+func removeComments(data []byte) []byte {
+	return scanJSON(data, func(i int) (int, []byte) {
+		switch {
+		case bytes.HasPrefix(data[i:], []byte("//")):
+			end := bytes.IndexByte(data[i:], '\n')
+			if end < 0 {
+				return len(data) - i, nil
+			}
+			return end, nil
+		case bytes.HasPrefix(data[i:], []byte("/*")):
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return len(data) - i, nil
+			}
+			return end + 4, []byte(" ")
+		}
+		return 1, data[i : i+1]
+	})
+}
+
+func removeTrailingCommas(data []byte) []byte {
+	return scanJSON(data, func(i int) (int, []byte) {
+		if data[i] == ',' {
+			rest := bytes.TrimLeft(data[i+1:], " \t\r\n")
+			if len(rest) > 0 && (rest[0] == '}' || rest[0] == ']') {
+				return 1, nil
+			}
+		}
+		return 1, data[i : i+1]
+	})
+}
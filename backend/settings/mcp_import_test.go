@@ -0,0 +1,272 @@
+package settings
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const claudeDesktopConfig = `{
+	"globalShortcut": "Ctrl+Space",
+	"mcpServers": {
+		"filesystem": {
+			"command": "npx",
+			"args": ["-y", "@modelcontextprotocol/server-filesystem", "/home/me"]
+		},
+		"github": {
+			"command": "docker",
+			"args": ["run", "-i", "ghcr.io/github/github-mcp-server"],
+			"env": {"GITHUB_TOKEN": "ghp_123"}
+		}
+	}
+}`
+
+const cursorConfig = `{
+	"mcpServers": {
+		"linear": {
+			"url": "https://mcp.linear.app/sse",
+			"headers": {"X-Team": "core", "Authorization": "Bearer abc"}
+		},
+		"old": {"command": "old-server", "disabled": true}
+	}
+}`
+
+const vscodeSettings = `{
+	// editor settings we must not trip over
+	"editor.fontSize": 14,
+	"mcp": {
+		"servers": {
+			"memory": {
+				"type": "stdio",
+				"command": "npx",
+				"args": ["-y", "@modelcontextprotocol/server-memory",], /* trailing comma */
+				"env": {"API_KEY": "${input:memory-key}"},
+			},
+			"remote": {"type": "http", "url": "https://example.com/mcp"},
+		},
+	},
+}`
+
+func TestParseClientConfigs(t *testing.T) {
+	servers, _, err := ParseClientConfig(ClientClaudeDesktop, []byte(claudeDesktopConfig))
+	if err != nil {
+		t.Fatalf("Failed to parse Claude Desktop config: %v", err)
+	}
+	github := servers["github"].Config.(STDIOServerConfig)
+	if github.Command != "docker" || github.Env["GITHUB_TOKEN"] != "ghp_123" {
+		t.Errorf("Unexpected github server: %+v", github)
+	}
+
+	servers, _, err = ParseClientConfig(ClientCursor, []byte(cursorConfig))
+	if err != nil {
+		t.Fatalf("Failed to parse Cursor config: %v", err)
+	}
+	linear := servers["linear"].Config.(SSEServerConfig)
+	expectedHeaders := []string{"Authorization: Bearer abc", "X-Team: core"}
+	if linear.Url != "https://mcp.linear.app/sse" || !reflect.DeepEqual(linear.Headers, expectedHeaders) {
+		t.Errorf("Unexpected linear server: %+v", linear)
+	}
+	if servers["old"].Enabled {
+		t.Errorf("Expected disabled Cursor server to import as disabled")
+	}
+
+	servers, warnings, err := ParseClientConfig(ClientVSCode, []byte(vscodeSettings))
+	if err != nil {
+		t.Fatalf("Failed to parse VS Code settings: %v", err)
+	}
+	memory := servers["memory"].Config.(STDIOServerConfig)
+	if memory.Env["API_KEY"] != "${secret:memory-key}" {
+		t.Errorf("Expected input to become a secret reference, got %s", memory.Env["API_KEY"])
+	}
+	if len(warnings["memory"]) != 1 || len(warnings["remote"]) != 1 {
+		t.Errorf("Expected one warning for memory and remote, got %v", warnings)
+	}
+}
+
+func TestImportPreviewAndMerge(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewMCPServerSettingsService(dir)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	// one identical server, one conflicting server
+	if err := service.AddSTDIOServer("filesystem", "npx",
+		[]string{"-y", "@modelcontextprotocol/server-filesystem", "/home/me"}, nil); err != nil {
+		t.Fatalf("Failed to add server: %v", err)
+	}
+	if err := service.AddSTDIOServer("github", "github-mcp", nil, nil); err != nil {
+		t.Fatalf("Failed to add server: %v", err)
+	}
+
+	source := filepath.Join(dir, "claude_desktop_config.json")
+	if err := os.WriteFile(source, []byte(claudeDesktopConfig), 0600); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	preview, err := service.PreviewImport(ClientClaudeDesktop, source)
+	if err != nil {
+		t.Fatalf("PreviewImport: %v", err)
+	}
+	statuses := map[string]string{}
+	for _, candidate := range preview.Servers {
+		statuses[candidate.Name] = candidate.Status
+	}
+	expected := map[string]string{"filesystem": ImportIdentical, "github": ImportConflict}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected statuses %v, got %v", expected, statuses)
+	}
+
+	result, err := service.Import(ClientClaudeDesktop, source, ConflictRename)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if result.Renamed["github"] != "github-2" || len(result.Skipped) != 1 {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+	if server, ok := service.GetServer("github"); !ok || server.Config.(STDIOServerConfig).Command != "github-mcp" {
+		t.Errorf("Expected the existing github server to be kept")
+	}
+	if _, ok := service.GetServer("github-2"); !ok {
+		t.Errorf("Expected the imported server under github-2")
+	}
+
+	if _, err := service.Import(ClientClaudeDesktop, source, "merge"); err == nil {
+		t.Errorf("Expected an error for an unknown strategy")
+	}
+}
+
+func TestExportKeepsOtherSettings(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewMCPServerSettingsService(dir)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.AddSSEServer("linear", "https://mcp.linear.app/sse", []string{"X-Team: core"}); err != nil {
+		t.Fatalf("Failed to add server: %v", err)
+	}
+
+	target := filepath.Join(dir, "claude_desktop_config.json")
+	if err := os.WriteFile(target, []byte(`{"globalShortcut": "Ctrl+Space"}`), 0600); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+	if err := service.Export(ClientClaudeDesktop, target, nil); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Export is not valid JSON: %v", err)
+	}
+	if doc["globalShortcut"] != "Ctrl+Space" {
+		t.Errorf("Expected other settings to be kept, got %v", doc)
+	}
+
+	// the export imports back to the same server
+	servers, _, err := ParseClientConfig(ClientClaudeDesktop, data)
+	if err != nil {
+		t.Fatalf("Failed to parse export: %v", err)
+	}
+	original, _ := service.GetServer("linear")
+	if !reflect.DeepEqual(servers["linear"].Config, original.Config) {
+		t.Errorf("Expected round trip to keep %+v, got %+v", original.Config, servers["linear"].Config)
+	}
+}
+
+func TestExportRefusesReferences(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewMCPServerSettingsService(dir)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.AddSTDIOServer("github", "github-mcp", []string{"--org", "${env:GH_ORG}"},
+		map[string]string{"GITHUB_TOKEN": "${secret:github}"}); err != nil {
+		t.Fatalf("Failed to add server: %v", err)
+	}
+	if err := service.AddSSEServer("linear", "https://mcp.linear.app/sse", []string{"X-Team: core"}); err != nil {
+		t.Fatalf("Failed to add server: %v", err)
+	}
+
+	target := filepath.Join(dir, "cursor.json")
+	err = service.Export(ClientCursor, target, nil)
+	if err == nil || !strings.Contains(err.Error(), "github") || strings.Contains(err.Error(), "linear") {
+		t.Errorf("Expected the export to be refused naming github only, got %v", err)
+	}
+	if _, statErr := os.Stat(target); !os.IsNotExist(statErr) {
+		t.Errorf("Expected nothing to be written when the export is refused")
+	}
+
+	resolve := func(value string) (string, error) {
+		return strings.NewReplacer("${env:GH_ORG}", "acme", "${secret:github}", "ghp_123").Replace(value), nil
+	}
+	if err := service.Export(ClientCursor, target, resolve); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	data, _ := os.ReadFile(target)
+	servers, _, err := ParseClientConfig(ClientCursor, data)
+	if err != nil {
+		t.Fatalf("Failed to parse export: %v", err)
+	}
+	exported := servers["github"].Config.(STDIOServerConfig)
+	if exported.Args[1] != "acme" || exported.Env["GITHUB_TOKEN"] != "ghp_123" {
+		t.Errorf("Expected resolved values in the export, got %+v", exported)
+	}
+	if stored, _ := service.GetServer("github"); stored.Config.(STDIOServerConfig).Env["GITHUB_TOKEN"] != "${secret:github}" {
+		t.Errorf("Expected the stored config to keep its references")
+	}
+}
+
+func TestExportFollowsTargetShape(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewMCPServerSettingsService(dir)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.AddSSEServer("linear", "https://mcp.linear.app/sse", nil); err != nil {
+		t.Fatalf("Failed to add server: %v", err)
+	}
+	if err := service.AddSTDIOServer("paused", "paused-mcp", nil, nil); err != nil {
+		t.Fatalf("Failed to add server: %v", err)
+	}
+	if err := service.SetServerEnabled("paused", false); err != nil {
+		t.Fatalf("Failed to disable server: %v", err)
+	}
+
+	// a settings.json gets mcp.servers next to the other settings
+	data, err := service.ExportClientConfig(ClientVSCode, []byte(`{"editor.fontSize": 14}`), nil)
+	if err != nil {
+		t.Fatalf("ExportClientConfig: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil || doc["editor.fontSize"] != float64(14) {
+		t.Fatalf("Expected editor.fontSize to be kept, got %s", data)
+	}
+	servers, _, err := ParseClientConfig(ClientVSCode, data)
+	if err != nil || len(servers) != 1 || !strings.Contains(string(data), `"mcp": {`) {
+		t.Errorf("Expected linear under mcp.servers without the disabled server, got %s", data)
+	}
+
+	// an mcp.json keeps top-level servers
+	data, err = service.ExportClientConfig(ClientVSCode, []byte(`{"inputs": []}`), nil)
+	if err != nil || !strings.Contains(string(data), `"servers": {`) || strings.Contains(string(data), `"mcp"`) {
+		t.Errorf("Expected top-level servers, got %s, %v", data, err)
+	}
+
+	// Cursor can mark the disabled server instead of dropping it
+	data, _ = service.ExportClientConfig(ClientCursor, nil, nil)
+	if servers, _, _ := ParseClientConfig(ClientCursor, data); len(servers) != 2 || servers["paused"].Enabled {
+		t.Errorf("Expected the disabled server to stay disabled, got %s", data)
+	}
+
+	_, err = service.ExportClientConfig(ClientVSCode, []byte("{\n  // font\n  \"editor.fontSize\": 14\n}"), nil)
+	if err == nil || !strings.Contains(err.Error(), "comments") {
+		t.Errorf("Expected a config with comments to be refused, got %v", err)
+	}
+}
@@ -3,6 +3,9 @@ package settings
 
 import (
 	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
 )
 
 const (
@@ -18,6 +21,7 @@ type MCPServerConfig struct {
 // ServerConfig is an interface that all server types must implement
 type ServerConfig interface {
 	GetType() string
+	GetToolFilter() ToolFilter
 }
 
 // ServerConfigWrapper wraps different types of server configurations
@@ -84,11 +88,79 @@ func (w ServerConfigWrapper) MarshalJSON() ([]byte, error) {
 	return json.Marshal(result)
 }
 
+// ToolFilter limits which of a server's tools are offered to the model.
+// Patterns are globs such as "read_*". When IncludeTools is set only matching
+// tools are kept; ExcludeTools always wins over IncludeTools.
+type ToolFilter struct {
+	IncludeTools []string `json:"includeTools,omitempty"`
+	ExcludeTools []string `json:"excludeTools,omitempty"`
+}
+
+// Validate checks that every pattern is a well-formed glob
+func (f ToolFilter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.IncludeTools...), f.ExcludeTools...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// GetToolFilter returns the tool filter of the server
+func (f ToolFilter) GetToolFilter() ToolFilter {
+	return f
+}
+
+// ExecOptions controls how a stdio server process is launched: its working
+// directory, whether our environment is passed on, resource limits and an
+// optional bubblewrap sandbox. The zero value keeps the previous behavior.
+type ExecOptions struct {
+	WorkingDir string          `json:"workingDir,omitempty"`
+	ScrubEnv   bool            `json:"scrubEnv,omitempty"`
+	Limits     *ResourceLimits `json:"limits,omitempty"`
+	Sandbox    *SandboxOptions `json:"sandbox,omitempty"`
+}
+
+// ResourceLimits caps what a server process may use; zero means unlimited
+type ResourceLimits struct {
+	CPUSeconds int `json:"cpuSeconds,omitempty"`
+	MemoryMB   int `json:"memoryMB,omitempty"`
+	OpenFiles  int `json:"openFiles,omitempty"`
+}
+
+// SandboxOptions lists the host paths visible inside the sandbox
+type SandboxOptions struct {
+	ReadOnlyPaths []string `json:"readOnlyPaths,omitempty"`
+	WritablePaths []string `json:"writablePaths,omitempty"`
+	AllowNetwork  bool     `json:"allowNetwork,omitempty"`
+}
+
+// Validate checks that limits are not negative and paths are absolute
+func (o ExecOptions) Validate() error {
+	if o.WorkingDir != "" && !filepath.IsAbs(o.WorkingDir) {
+		return fmt.Errorf("working directory %q must be an absolute path", o.WorkingDir)
+	}
+	if o.Limits != nil && (o.Limits.CPUSeconds < 0 || o.Limits.MemoryMB < 0 || o.Limits.OpenFiles < 0) {
+		return fmt.Errorf("resource limits must not be negative")
+	}
+	if o.Sandbox != nil {
+		for _, p := range append(append([]string{}, o.Sandbox.ReadOnlyPaths...), o.Sandbox.WritablePaths...) {
+			if !filepath.IsAbs(p) {
+				return fmt.Errorf("sandbox path %q must be an absolute path", p)
+			}
+		}
+	}
+	return nil
+}
+
 // STDIOServerConfig represents configuration for a command-line based MCP server
 type STDIOServerConfig struct {
 	Command string            `json:"command"`
 	Args    []string          `json:"args"`
 	Env     map[string]string `json:"env,omitempty"`
+	Catalog *CatalogOrigin    `json:"catalog,omitempty"` // set for servers installed from the catalog
+	ToolFilter
+	ExecOptions
 }
 
 // GetType returns the type of this server config
@@ -100,6 +172,7 @@ func (s STDIOServerConfig) GetType() string {
 type SSEServerConfig struct {
 	Url     string   `json:"url"`
 	Headers []string `json:"headers,omitempty"`
+	ToolFilter
 }
 
 // GetType returns the type of this server config
@@ -330,6 +330,15 @@ func (s *MCPServerSettingsService) UpdateServer(name string, config ServerConfig
 		return fmt.Errorf("server with name %s does not exist", name)
 	}
 
+	if err := config.Config.GetToolFilter().Validate(); err != nil {
+		return err
+	}
+	if stdioConfig, ok := config.Config.(STDIOServerConfig); ok {
+		if err := stdioConfig.ExecOptions.Validate(); err != nil {
+			return err
+		}
+	}
+
 	// Update the server
 	s.serverConfig.MCPServers[name] = config
 
@@ -461,6 +470,72 @@ func (s *MCPServerSettingsService) SetServerEnabled(name string, enabled bool) e
 	return s.saveServerConfig()
 }
 
+// SetServerToolFilter replaces the include/exclude tool patterns of a server
+func (s *MCPServerSettingsService) SetServerToolFilter(name string, filter ToolFilter) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.configLoaded {
+		if err := s.loadServerConfig(); err != nil {
+			return fmt.Errorf("failed to load server config: %w", err)
+		}
+	}
+
+	serverConfig, exists := s.serverConfig.MCPServers[name]
+	if !exists {
+		return fmt.Errorf("server with name %s does not exist", name)
+	}
+
+	switch cfg := serverConfig.Config.(type) {
+	case STDIOServerConfig:
+		cfg.ToolFilter = filter
+		serverConfig.Config = cfg
+	case SSEServerConfig:
+		cfg.ToolFilter = filter
+		serverConfig.Config = cfg
+	default:
+		return fmt.Errorf("server %s has unsupported type %s", name, serverConfig.Config.GetType())
+	}
+	s.serverConfig.MCPServers[name] = serverConfig
+
+	return s.saveServerConfig()
+}
+
+// SetServerExecOptions replaces how a stdio server process is launched
+func (s *MCPServerSettingsService) SetServerExecOptions(name string, options ExecOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.configLoaded {
+		if err := s.loadServerConfig(); err != nil {
+			return fmt.Errorf("failed to load server config: %w", err)
+		}
+	}
+
+	serverConfig, exists := s.serverConfig.MCPServers[name]
+	if !exists {
+		return fmt.Errorf("server with name %s does not exist", name)
+	}
+
+	stdioConfig, ok := serverConfig.Config.(STDIOServerConfig)
+	if !ok {
+		return fmt.Errorf("server %s is not a stdio server", name)
+	}
+	stdioConfig.ExecOptions = options
+	serverConfig.Config = stdioConfig
+	s.serverConfig.MCPServers[name] = serverConfig
+
+	return s.saveServerConfig()
+}
+
 // GetEnabledServers returns servers that are both in the active list and marked as enabled
 func (s *MCPServerSettingsService) GetEnabledServers() map[string]ServerConfigWrapper {
 	s.mutex.RLock()
@@ -260,6 +260,76 @@ func TestMCPServerSettingsServiceE2E(t *testing.T) {
 		}
 	})
 
+	// Test tool filters
+	t.Run("Tool Filters", func(t *testing.T) {
+		before, _ := service.GetServer("api_server")
+		filter := ToolFilter{
+			IncludeTools: []string{"read_*", "list_*"},
+			ExcludeTools: []string{"delete_*"},
+		}
+		if err := service.SetServerToolFilter("api_server", filter); err != nil {
+			t.Fatalf("Failed to set tool filter: %v", err)
+		}
+
+		err := service.SetServerToolFilter("api_server", ToolFilter{ExcludeTools: []string{"[unclosed"}})
+		if err == nil {
+			t.Errorf("Expected an error for a malformed pattern")
+		}
+
+		// reload from disk to check the filter round-trips
+		reloaded, err := createTestMCPServerSettingsService(tempDir)
+		if err != nil {
+			t.Fatalf("Failed to reload service: %v", err)
+		}
+		server, exists := reloaded.GetServer("api_server")
+		if !exists {
+			t.Fatalf("api_server not found after reload")
+		}
+		if got := server.Config.GetToolFilter(); !reflect.DeepEqual(got, filter) {
+			t.Errorf("Expected filter %+v, got %+v", filter, got)
+		}
+		if got, want := server.Config.(SSEServerConfig).Url, before.Config.(SSEServerConfig).Url; got != want {
+			t.Errorf("Expected url %s to be preserved, got %s", want, got)
+		}
+	})
+
+	// Test execution options of stdio servers
+	t.Run("Exec Options", func(t *testing.T) {
+		if err := service.AddSTDIOServer("sandboxed_server", "/usr/bin/node", []string{"index.js"}, nil); err != nil {
+			t.Fatalf("Failed to add STDIO server: %v", err)
+		}
+		defer service.DeleteServer("sandboxed_server")
+
+		options := ExecOptions{
+			WorkingDir: "/srv/app",
+			ScrubEnv:   true,
+			Limits:     &ResourceLimits{CPUSeconds: 30, MemoryMB: 512},
+			Sandbox:    &SandboxOptions{ReadOnlyPaths: []string{"/srv/data"}, WritablePaths: []string{"/srv/app"}},
+		}
+		if err := service.SetServerExecOptions("sandboxed_server", options); err != nil {
+			t.Fatalf("Failed to set exec options: %v", err)
+		}
+
+		if err := service.SetServerExecOptions("sandboxed_server", ExecOptions{WorkingDir: "relative"}); err == nil {
+			t.Errorf("Expected an error for a relative working directory")
+		}
+		if err := service.SetServerExecOptions("api_server", options); err == nil {
+			t.Errorf("Expected an error for an SSE server")
+		}
+
+		reloaded, err := createTestMCPServerSettingsService(tempDir)
+		if err != nil {
+			t.Fatalf("Failed to reload service: %v", err)
+		}
+		server, exists := reloaded.GetServer("sandboxed_server")
+		if !exists {
+			t.Fatalf("sandboxed_server not found after reload")
+		}
+		if got := server.Config.(STDIOServerConfig).ExecOptions; !reflect.DeepEqual(got, options) {
+			t.Errorf("Expected exec options %+v, got %+v", options, got)
+		}
+	})
+
 	// Test file paths
 	t.Run("File Paths", func(t *testing.T) {
 		expectedServersPath := filepath.Join(tempDir, mcpServersFileName)
@@ -1,33 +1,75 @@
 package settings
 
-import "os"
+import (
+	"os"
+
+	"smart-spotlight-ai/backend/packages/llm/capabilities"
+)
 
 // Settings represents application settings
 type Settings struct {
-	BaseURL         string   `json:"baseUrl"`
-	APIKey          string   `json:"apiKey"`
-	Model           string   `json:"model"`
-	AvailableModels []string `json:"availableModels"`
+	Profiles        []ProviderProfile `json:"profiles"`
+	DefaultProfile  string            `json:"defaultProfile"`
+	AvailableModels []string          `json:"availableModels"`
+
+	// CapabilityOverrides correct what the built-in table and model
+	// discovery know about a model
+	CapabilityOverrides []capabilities.Override `json:"capabilityOverrides,omitempty"`
+
+	// SystemPrompt is the persona the MCP search puts into its system
+	// prompt; SystemPromptTemplate replaces the default template
+	SystemPrompt         string `json:"systemPrompt,omitempty"`
+	SystemPromptTemplate string `json:"systemPromptTemplate,omitempty"`
+	// SystemPromptMigrated records that the SPOT_AI_SYSTEM_PROMPT* variables
+	// were copied in, so a prompt cleared later is not copied again
+	SystemPromptMigrated bool `json:"systemPromptMigrated,omitempty"`
+
+	// Single provider configuration of older settings files, moved into a
+	// profile by MigrateProfiles
+	Provider string `json:"provider,omitempty"`
+	BaseURL  string `json:"baseUrl,omitempty"`
+	APIKey   string `json:"apiKey,omitempty"`
+	Model    string `json:"model,omitempty"`
 }
 
 // DefaultSettings returns default application settings
 func DefaultSettings() *Settings {
-	return &Settings{
-		BaseURL:         os.Getenv("SPOT_AI_API_ENDPOINT"),
-		APIKey:          os.Getenv("SPOT_AI_API_KEY"),
-		Model:           os.Getenv("SPOT_AI_MODEL"),
+	s := &Settings{
 		AvailableModels: DefaultModels(),
 	}
+	s.MigrateProfiles()
+	s.MigrateSystemPrompt()
+	return s
+}
+
+// MigrateSystemPrompt copies the persona and template of the
+// SPOT_AI_SYSTEM_PROMPT and SPOT_AI_SYSTEM_PROMPT_TEMPLATE environment
+// variables, which are not read otherwise, into empty settings once. It
+// reports whether anything changed.
+func (s *Settings) MigrateSystemPrompt() bool {
+	if s.SystemPromptMigrated {
+		return false
+	}
+	s.SystemPromptMigrated = true
+	if s.SystemPrompt == "" {
+		s.SystemPrompt = os.Getenv("SPOT_AI_SYSTEM_PROMPT")
+	}
+	if s.SystemPromptTemplate == "" {
+		s.SystemPromptTemplate = os.Getenv("SPOT_AI_SYSTEM_PROMPT_TEMPLATE")
+	}
+	return true
 }
 
-// DefaultModels returns the list of available models
+// DefaultModels returns the models offered before model discovery has
+// reached the provider
 func DefaultModels() []string {
 	return []string{
-		"google/gemini-2.5-pro-exp-03-25",
-		"gpt-4",
-		"gpt-3.5-turbo",
-		"anthropic/claude-3-opus",
-		"anthropic/claude-3-sonnet",
-		"anthropic/claude-2",
+		"gpt-4o",
+		"gpt-4o-mini",
+		"claude-sonnet-4-5",
+		"claude-haiku-4-5",
+		"gemini-2.5-pro",
+		"gemini-2.5-flash",
+		"llama3.2",
 	}
 }
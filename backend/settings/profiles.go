@@ -0,0 +1,365 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"smart-spotlight-ai/backend/packages/llm/providers/anthropic"
+	"smart-spotlight-ai/backend/packages/llm/providers/azure"
+	"smart-spotlight-ai/backend/packages/llm/providers/bedrock"
+	"smart-spotlight-ai/backend/packages/llm/providers/ollama"
+	"smart-spotlight-ai/backend/packages/llm/providers/openai"
+)
+
+// DefaultProfileName names the profile created from the pre-profile settings
+const DefaultProfileName = "default"
+
+// Provider types a profile can use
+var ProviderTypes = []string{"openai", "anthropic", "ollama", "google", "azure", "bedrock"}
+
+// ProviderProfile is a named LLM provider configuration
+type ProviderProfile struct {
+	Name        string   `json:"name"`
+	Provider    string   `json:"provider"`
+	BaseURL     string   `json:"baseUrl"`
+	APIKey      string   `json:"apiKey"` // the key itself or a ${env:...} / ${secret:...} reference
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature,omitempty"` // nil keeps the provider default
+	MaxTokens   int      `json:"maxTokens,omitempty"`   // 0 keeps the provider default
+	Fallbacks   []string `json:"fallbacks,omitempty"`   // profiles tried in order when this one fails
+
+	Ollama    *ollama.Options    `json:"ollama,omitempty"`    // num_ctx and keep_alive of ollama profiles
+	Anthropic *anthropic.Options `json:"anthropic,omitempty"` // prompt caching and thinking of anthropic profiles
+	OpenAI    *openai.Options    `json:"openai,omitempty"`    // API and reasoning effort of openai profiles
+	Azure     *azure.Options     `json:"azure,omitempty"`     // api-version and OpenAI options of azure profiles
+	Bedrock   *bedrock.Options   `json:"bedrock,omitempty"`   // region and access key of bedrock profiles
+}
+
+// Validate checks that the profile can be used to build a provider
+func (p ProviderProfile) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	known := false
+	for _, provider := range ProviderTypes {
+		known = known || p.Provider == provider
+	}
+	if !known {
+		return fmt.Errorf("profile %s: unsupported provider %q, expected one of %s",
+			p.Name, p.Provider, strings.Join(ProviderTypes, ", "))
+	}
+	if p.Model == "" {
+		return fmt.Errorf("profile %s: model is required", p.Name)
+	}
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return fmt.Errorf("profile %s: temperature must be between 0 and 2", p.Name)
+	}
+	if p.MaxTokens < 0 {
+		return fmt.Errorf("profile %s: max tokens cannot be negative", p.Name)
+	}
+	if p.Ollama != nil {
+		if err := p.Ollama.Validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+	if p.Anthropic != nil {
+		if err := p.Anthropic.Validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+	if p.OpenAI != nil {
+		if err := p.OpenAI.Validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+	if p.Provider == "azure" && p.BaseURL == "" {
+		return fmt.Errorf("profile %s: azure needs the resource endpoint as base URL", p.Name)
+	}
+	if p.Azure != nil {
+		if err := p.Azure.Validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+	if p.Bedrock != nil {
+		if err := p.Bedrock.Validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+	for _, fallback := range p.Fallbacks {
+		if fallback == p.Name {
+			return fmt.Errorf("profile %s cannot fall back to itself", p.Name)
+		}
+	}
+	if strings.ContainsAny(p.Name, " \t\n") {
+		return fmt.Errorf("profile %s: name cannot contain spaces, it is typed after @", p.Name)
+	}
+	return nil
+}
+
+// checkFallbacks reports a fallback that names no profile
+func (s *Settings) checkFallbacks(profile ProviderProfile) error {
+	for _, fallback := range profile.Fallbacks {
+		if _, ok := s.Profile(fallback); !ok {
+			return fmt.Errorf("profile %s: fallback %s not found", profile.Name, fallback)
+		}
+	}
+	return nil
+}
+
+// ValidateProfiles checks every profile, that their names are unique and
+// that the default exists
+func (s *Settings) ValidateProfiles() error {
+	seen := make(map[string]bool, len(s.Profiles))
+	for _, profile := range s.Profiles {
+		if err := profile.Validate(); err != nil {
+			return err
+		}
+		if seen[profile.Name] {
+			return fmt.Errorf("profile %s exists more than once", profile.Name)
+		}
+		seen[profile.Name] = true
+		if err := s.checkFallbacks(profile); err != nil {
+			return err
+		}
+	}
+	if len(s.Profiles) > 0 && !seen[s.DefaultProfile] {
+		return fmt.Errorf("default profile %q not found", s.DefaultProfile)
+	}
+	return nil
+}
+
+// Profile returns the profile with the given name
+func (s *Settings) Profile(name string) (ProviderProfile, bool) {
+	for _, profile := range s.Profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return ProviderProfile{}, false
+}
+
+// ActiveProfile returns the default profile, or the first one if the default is missing
+func (s *Settings) ActiveProfile() (ProviderProfile, bool) {
+	if profile, ok := s.Profile(s.DefaultProfile); ok {
+		return profile, true
+	}
+	if len(s.Profiles) > 0 {
+		return s.Profiles[0], true
+	}
+	return ProviderProfile{}, false
+}
+
+// AddProfile adds a new profile; the first one becomes the default
+func (s *Settings) AddProfile(profile ProviderProfile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	if _, exists := s.Profile(profile.Name); exists {
+		return fmt.Errorf("profile %s already exists", profile.Name)
+	}
+	if err := s.checkFallbacks(profile); err != nil {
+		return err
+	}
+	s.Profiles = append(s.Profiles, profile)
+	if len(s.Profiles) == 1 {
+		s.DefaultProfile = profile.Name
+	}
+	return nil
+}
+
+// UpdateProfile replaces the named profile. Renaming the default keeps it the default.
+func (s *Settings) UpdateProfile(name string, profile ProviderProfile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	if profile.Name != name {
+		if _, exists := s.Profile(profile.Name); exists {
+			return fmt.Errorf("profile %s already exists", profile.Name)
+		}
+	}
+	if err := s.checkFallbacks(profile); err != nil {
+		return err
+	}
+	for i := range s.Profiles {
+		if s.Profiles[i].Name == name {
+			s.Profiles[i] = profile
+			if s.DefaultProfile == name {
+				s.DefaultProfile = profile.Name
+			}
+			s.renameFallback(name, profile.Name)
+			return nil
+		}
+	}
+	return fmt.Errorf("profile %s not found", name)
+}
+
+// renameFallback updates the fallback lists naming a renamed or, with an
+// empty new name, deleted profile
+func (s *Settings) renameFallback(oldName, newName string) {
+	if oldName == newName {
+		return
+	}
+	for i := range s.Profiles {
+		var fallbacks []string
+		for _, fallback := range s.Profiles[i].Fallbacks {
+			if fallback == oldName {
+				fallback = newName
+			}
+			if fallback != "" {
+				fallbacks = append(fallbacks, fallback)
+			}
+		}
+		s.Profiles[i].Fallbacks = fallbacks
+	}
+}
+
+// DeleteProfile removes the named profile. The default can only be removed
+// once another profile has been made the default.
+func (s *Settings) DeleteProfile(name string) error {
+	if name == s.DefaultProfile {
+		return fmt.Errorf("profile %s is the default, choose another default first", name)
+	}
+	for i := range s.Profiles {
+		if s.Profiles[i].Name == name {
+			s.Profiles = append(s.Profiles[:i], s.Profiles[i+1:]...)
+			s.renameFallback(name, "")
+			return nil
+		}
+	}
+	return fmt.Errorf("profile %s not found", name)
+}
+
+// ProfileChain returns the named profile followed by its fallbacks. Missing
+// and repeated profiles are skipped, fallbacks of fallbacks are not followed.
+func (s *Settings) ProfileChain(name string) []ProviderProfile {
+	primary, ok := s.Profile(name)
+	if !ok {
+		return nil
+	}
+	chain := []ProviderProfile{primary}
+	seen := map[string]bool{name: true}
+	for _, fallback := range primary.Fallbacks {
+		if profile, ok := s.Profile(fallback); ok && !seen[fallback] {
+			chain = append(chain, profile)
+			seen[fallback] = true
+		}
+	}
+	return chain
+}
+
+// RouteQuery splits a leading @profile off query. It reports false, leaving
+// the query alone, when the query names no profile.
+func (s *Settings) RouteQuery(query string) (profile string, rest string, ok bool) {
+	trimmed := strings.TrimSpace(query)
+	if !strings.HasPrefix(trimmed, "@") {
+		return "", query, false
+	}
+	name := trimmed[1:]
+	if i := strings.IndexFunc(name, unicode.IsSpace); i >= 0 {
+		name, rest = name[:i], name[i:]
+	}
+	if _, exists := s.Profile(name); !exists {
+		return "", query, false
+	}
+	return name, strings.TrimSpace(rest), true
+}
+
+// SetDefaultProfile makes the named profile the one both search modes use
+func (s *Settings) SetDefaultProfile(name string) error {
+	if _, ok := s.Profile(name); !ok {
+		return fmt.Errorf("profile %s not found", name)
+	}
+	s.DefaultProfile = name
+	return nil
+}
+
+// MigrateProfiles moves the single provider configuration of older
+// settings.json files, or else the SPOT_AI_* environment variables, into a
+// default profile. It reports whether anything changed.
+func (s *Settings) MigrateProfiles() bool {
+	if len(s.Profiles) > 0 {
+		return false
+	}
+
+	profile := ProviderProfile{
+		Name:     DefaultProfileName,
+		Provider: s.Provider,
+		BaseURL:  s.BaseURL,
+		APIKey:   s.APIKey,
+		Model:    s.Model,
+	}
+	if profile.Provider == "" {
+		profile.Provider = GetEnvWithDefault("SPOT_AI_PROVIDER", "openai")
+	}
+	if profile.BaseURL == "" {
+		profile.BaseURL = os.Getenv("SPOT_AI_API_ENDPOINT")
+	}
+	// Reference the variable so the key itself is not copied into the file
+	if _, ok := os.LookupEnv("SPOT_AI_API_KEY"); ok && profile.APIKey == "" {
+		profile.APIKey = "${env:SPOT_AI_API_KEY}"
+	}
+	if profile.Model == "" {
+		profile.Model = GetEnvWithDefault("SPOT_AI_MODEL", "gpt-4o")
+	}
+
+	s.Profiles = []ProviderProfile{profile}
+	s.DefaultProfile = profile.Name
+	s.Provider, s.BaseURL, s.APIKey, s.Model = "", "", "", ""
+	return true
+}
+
+// FillLegacyFields copies the default profile into the single provider
+// fields, which the settings form that predates profiles still edits
+func (s *Settings) FillLegacyFields() {
+	if profile, ok := s.ActiveProfile(); ok {
+		s.Provider, s.BaseURL, s.APIKey, s.Model = profile.Provider, profile.BaseURL, profile.APIKey, profile.Model
+	}
+}
+
+// ApplyLegacyFields moves the single provider fields, as filled in by
+// FillLegacyFields and edited by that form, onto the default profile and
+// clears them. Settings without any of the fields are left unchanged.
+func (s *Settings) ApplyLegacyFields() {
+	if s.Provider == "" && s.BaseURL == "" && s.APIKey == "" && s.Model == "" {
+		return
+	}
+	if s.MigrateProfiles() {
+		return
+	}
+
+	active := 0
+	for i, profile := range s.Profiles {
+		if profile.Name == s.DefaultProfile {
+			active = i
+		}
+	}
+	profile := &s.Profiles[active]
+	if s.Provider != "" {
+		profile.Provider = s.Provider
+	}
+	profile.BaseURL, profile.APIKey, profile.Model = s.BaseURL, s.APIKey, s.Model
+	s.Provider, s.BaseURL, s.APIKey, s.Model = "", "", "", ""
+}
+
+// settingsMu serializes ModifySettings so concurrent edits are not lost
+var settingsMu sync.Mutex
+
+// ModifySettings applies modify to a copy of the current settings and saves
+// the result. The current settings are left untouched if modify or the save fails.
+func ModifySettings(modify func(*Settings) error) (*Settings, error) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	updated := *GetCurrentSettings()
+	updated.Profiles = append([]ProviderProfile(nil), updated.Profiles...)
+	if err := modify(&updated); err != nil {
+		return nil, err
+	}
+	if err := UpdateSettings(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
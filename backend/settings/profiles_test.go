@@ -0,0 +1,215 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+
+	"smart-spotlight-ai/backend/packages/llm/providers/ollama"
+	"smart-spotlight-ai/backend/packages/llm/providers/openai"
+)
+
+func TestMigrateProfilesFromSettingsFile(t *testing.T) {
+	t.Setenv("SPOT_AI_PROVIDER", "anthropic")
+	s := &Settings{BaseURL: "https://llm.example.com/v1", APIKey: "sk-file", Model: "gpt-4"}
+
+	if !s.MigrateProfiles() {
+		t.Fatal("Expected the legacy fields to be migrated")
+	}
+	profile, ok := s.ActiveProfile()
+	if !ok || profile.Name != DefaultProfileName {
+		t.Fatalf("Expected the default profile, got %+v", s.Profiles)
+	}
+	if profile.Provider != "anthropic" || profile.BaseURL != "https://llm.example.com/v1" ||
+		profile.APIKey != "sk-file" || profile.Model != "gpt-4" {
+		t.Errorf("Expected the file values with the env provider, got %+v", profile)
+	}
+	if s.APIKey != "" || s.BaseURL != "" || s.Model != "" {
+		t.Errorf("Expected the legacy fields to be cleared, got %+v", s)
+	}
+	if s.MigrateProfiles() {
+		t.Errorf("Expected a second migration to change nothing")
+	}
+}
+
+func TestMigrateProfilesFromEnv(t *testing.T) {
+	t.Setenv("SPOT_AI_PROVIDER", "openai")
+	t.Setenv("SPOT_AI_API_KEY", "sk-env")
+	t.Setenv("SPOT_AI_MODEL", "gpt-4o-mini")
+
+	s := DefaultSettings()
+	profile, _ := s.ActiveProfile()
+	if profile.APIKey != "${env:SPOT_AI_API_KEY}" {
+		t.Errorf("Expected the key to reference the env variable, got %q", profile.APIKey)
+	}
+	if profile.Model != "gpt-4o-mini" {
+		t.Errorf("Expected the env model, got %q", profile.Model)
+	}
+}
+
+func TestLegacyFieldsEditDefaultProfile(t *testing.T) {
+	s := &Settings{
+		Profiles: []ProviderProfile{
+			{Name: "local", Provider: "ollama", Model: "llama3.2"},
+			{Name: "work", Provider: "openai", APIKey: "sk-work", Model: "gpt-4o"},
+		},
+		DefaultProfile: "work",
+	}
+	s.FillLegacyFields()
+	if s.Provider != "openai" || s.APIKey != "sk-work" || s.Model != "gpt-4o" {
+		t.Fatalf("Expected the default profile in the legacy fields, got %+v", s)
+	}
+
+	s.BaseURL, s.Model = "https://llm.example.com/v1", "gpt-4.1"
+	s.ApplyLegacyFields()
+	work, _ := s.Profile("work")
+	if work.BaseURL != "https://llm.example.com/v1" || work.Model != "gpt-4.1" || work.APIKey != "sk-work" {
+		t.Errorf("Expected the edits on the default profile, got %+v", work)
+	}
+	if local, _ := s.Profile("local"); local.Model != "llama3.2" {
+		t.Errorf("Expected other profiles to be left alone, got %+v", local)
+	}
+	if s.Provider != "" || s.BaseURL != "" || s.APIKey != "" || s.Model != "" {
+		t.Errorf("Expected the legacy fields to be cleared, got %+v", s)
+	}
+}
+
+func TestProfileCRUD(t *testing.T) {
+	s := &Settings{}
+	temperature := 0.2
+	local := ProviderProfile{Name: "local", Provider: "ollama", Model: "llama3.2", Temperature: &temperature}
+	if err := s.AddProfile(local); err != nil {
+		t.Fatalf("AddProfile failed: %v", err)
+	}
+	if s.DefaultProfile != "local" {
+		t.Errorf("Expected the first profile to become the default, got %q", s.DefaultProfile)
+	}
+	if err := s.AddProfile(local); err == nil {
+		t.Errorf("Expected a duplicate name to be rejected")
+	}
+
+	tooHot := 3.0
+	if err := s.AddProfile(ProviderProfile{Name: "hot", Provider: "openai", Model: "gpt-4o", Temperature: &tooHot}); err == nil ||
+		!strings.Contains(err.Error(), "temperature") {
+		t.Errorf("Expected an out of range temperature to be rejected, got %v", err)
+	}
+	if err := s.AddProfile(ProviderProfile{Name: "odd", Provider: "mistral", Model: "m"}); err == nil {
+		t.Errorf("Expected an unknown provider to be rejected")
+	}
+	slow := ProviderProfile{Name: "slow", Provider: "ollama", Model: "llama3.2", Ollama: &ollama.Options{KeepAlive: "a while"}}
+	if err := s.AddProfile(slow); err == nil || !strings.Contains(err.Error(), "keep_alive") {
+		t.Errorf("Expected an invalid keep_alive to be rejected, got %v", err)
+	}
+	effort := ProviderProfile{Name: "effort", Provider: "openai", Model: "o3", OpenAI: &openai.Options{ReasoningEffort: "extreme"}}
+	if err := s.AddProfile(effort); err == nil || !strings.Contains(err.Error(), "reasoning effort") {
+		t.Errorf("Expected an unknown reasoning effort to be rejected, got %v", err)
+	}
+	if err := s.AddProfile(ProviderProfile{Name: "contoso", Provider: "azure", Model: "prod-gpt4o"}); err == nil ||
+		!strings.Contains(err.Error(), "endpoint") {
+		t.Errorf("Expected an azure profile without endpoint to be rejected, got %v", err)
+	}
+
+	cloud := ProviderProfile{Name: "cloud", Provider: "openai", Model: "gpt-4o", APIKey: "${secret:openai}"}
+	if err := s.AddProfile(cloud); err != nil {
+		t.Fatalf("AddProfile failed: %v", err)
+	}
+
+	local.Name = "workstation"
+	if err := s.UpdateProfile("local", local); err != nil {
+		t.Fatalf("UpdateProfile failed: %v", err)
+	}
+	if s.DefaultProfile != "workstation" {
+		t.Errorf("Expected renaming the default to keep it the default, got %q", s.DefaultProfile)
+	}
+	if err := s.UpdateProfile("workstation", cloud); err == nil {
+		t.Errorf("Expected renaming onto an existing profile to be rejected")
+	}
+
+	if err := s.DeleteProfile("workstation"); err == nil {
+		t.Errorf("Expected deleting the default to be rejected")
+	}
+	if err := s.SetDefaultProfile("cloud"); err != nil {
+		t.Fatalf("SetDefaultProfile failed: %v", err)
+	}
+	if err := s.DeleteProfile("workstation"); err != nil {
+		t.Fatalf("DeleteProfile failed: %v", err)
+	}
+	if err := s.ValidateProfiles(); err != nil {
+		t.Errorf("Expected the remaining profiles to be valid, got %v", err)
+	}
+	if len(s.Profiles) != 1 || s.Profiles[0].Name != "cloud" {
+		t.Errorf("Expected only the cloud profile, got %+v", s.Profiles)
+	}
+}
+
+func TestRouteQueryAndChain(t *testing.T) {
+	s := &Settings{}
+	for _, profile := range []ProviderProfile{
+		{Name: "local", Provider: "ollama", Model: "llama3.2"},
+		{Name: "claude", Provider: "anthropic", Model: "claude-sonnet-4-5"},
+	} {
+		if err := s.AddProfile(profile); err != nil {
+			t.Fatalf("AddProfile failed: %v", err)
+		}
+	}
+	if err := s.AddProfile(ProviderProfile{Name: "hard", Provider: "openai", Model: "gpt-4o", Fallbacks: []string{"gone"}}); err == nil {
+		t.Errorf("Expected an unknown fallback to be rejected")
+	}
+	claude, _ := s.Profile("claude")
+	claude.Fallbacks = []string{"local", "local"}
+	if err := s.UpdateProfile("claude", claude); err != nil {
+		t.Fatalf("UpdateProfile failed: %v", err)
+	}
+
+	name, rest, ok := s.RouteQuery("  @claude\tprove it  ")
+	if !ok || name != "claude" || rest != "prove it" {
+		t.Errorf("Expected claude and the rest of the query, got %q %q %v", name, rest, ok)
+	}
+	if _, rest, ok := s.RouteQuery("@someone said hi"); ok || rest != "@someone said hi" {
+		t.Errorf("Expected a query naming no profile to be left alone, got %q %v", rest, ok)
+	}
+
+	chain := s.ProfileChain("claude")
+	if len(chain) != 2 || chain[0].Name != "claude" || chain[1].Name != "local" {
+		t.Errorf("Expected claude then local once, got %+v", chain)
+	}
+
+	local, _ := s.Profile("local")
+	local.Name = "ollama"
+	if err := s.UpdateProfile("local", local); err != nil {
+		t.Fatalf("UpdateProfile failed: %v", err)
+	}
+	if claude, _ := s.Profile("claude"); len(claude.Fallbacks) != 2 || claude.Fallbacks[0] != "ollama" {
+		t.Errorf("Expected the fallback to follow the rename, got %v", claude.Fallbacks)
+	}
+	if err := s.SetDefaultProfile("claude"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteProfile("ollama"); err != nil {
+		t.Fatalf("DeleteProfile failed: %v", err)
+	}
+	if claude, _ := s.Profile("claude"); len(claude.Fallbacks) != 0 {
+		t.Errorf("Expected the deleted fallback to be dropped, got %v", claude.Fallbacks)
+	}
+}
+
+func TestMigrateSystemPromptFromEnv(t *testing.T) {
+	t.Setenv("SPOT_AI_SYSTEM_PROMPT", "You are terse.")
+	t.Setenv("SPOT_AI_SYSTEM_PROMPT_TEMPLATE", "{{.Persona}} Today is {{.Date}}.")
+
+	s := &Settings{SystemPromptTemplate: "{{.Persona}}"}
+	if !s.MigrateSystemPrompt() {
+		t.Fatal("Expected the env prompt to be migrated")
+	}
+	if s.SystemPrompt != "You are terse." {
+		t.Errorf("Expected the env persona, got %q", s.SystemPrompt)
+	}
+	if s.SystemPromptTemplate != "{{.Persona}}" {
+		t.Errorf("Expected the stored template to be kept, got %q", s.SystemPromptTemplate)
+	}
+
+	// A persona cleared after the migration stays cleared
+	s.SystemPrompt = ""
+	if s.MigrateSystemPrompt() || s.SystemPrompt != "" {
+		t.Errorf("Expected a second migration to change nothing, got %q", s.SystemPrompt)
+	}
+}
@@ -33,6 +33,19 @@ func InitSettings() {
 		if saveErr := SaveSettings(AppSettings); saveErr != nil {
 			log.Printf("Error saving initial default settings: %v", saveErr)
 		}
+		return
+	}
+
+	if AppSettings.MigrateProfiles() {
+		slog.Info("Moved provider settings into a profile", "profile", AppSettings.DefaultProfile)
+		if saveErr := SaveSettings(AppSettings); saveErr != nil {
+			log.Printf("Error saving migrated settings: %v", saveErr)
+		}
+	}
+	if AppSettings.MigrateSystemPrompt() {
+		if saveErr := SaveSettings(AppSettings); saveErr != nil {
+			log.Printf("Error saving migrated settings: %v", saveErr)
+		}
 	}
 }
 
@@ -4,6 +4,8 @@ package backend
 import (
 	"fmt"
 	"log/slog"
+	"smart-spotlight-ai/backend/llm/mcphost"
+	"smart-spotlight-ai/backend/packages/secrets"
 	"smart-spotlight-ai/backend/settings"
 )
 
@@ -43,16 +45,25 @@ func (a *App) GetMCPServers() []MCPServerInfo {
 		case "stdio":
 			if stdioConfig, ok := server.Config.(settings.STDIOServerConfig); ok {
 				configMap = map[string]interface{}{
-					"command": stdioConfig.Command,
-					"args":    stdioConfig.Args,
-					"env":     stdioConfig.Env,
+					"command":      stdioConfig.Command,
+					"args":         stdioConfig.Args,
+					"env":          maskEnv(stdioConfig.Env),
+					"includeTools": stdioConfig.IncludeTools,
+					"excludeTools": stdioConfig.ExcludeTools,
+					"workingDir":   stdioConfig.WorkingDir,
+					"scrubEnv":     stdioConfig.ScrubEnv,
+					"limits":       stdioConfig.Limits,
+					"sandbox":      stdioConfig.Sandbox,
+					"catalog":      stdioConfig.Catalog,
 				}
 			}
 		case "sse":
 			if sseConfig, ok := server.Config.(settings.SSEServerConfig); ok {
 				configMap = map[string]interface{}{
-					"url":     sseConfig.Url,
-					"headers": sseConfig.Headers,
+					"url":          sseConfig.Url,
+					"headers":      maskHeaders(sseConfig.Headers),
+					"includeTools": sseConfig.IncludeTools,
+					"excludeTools": sseConfig.ExcludeTools,
 				}
 			}
 		default:
@@ -71,13 +82,52 @@ func (a *App) GetMCPServers() []MCPServerInfo {
 	return result
 }
 
+// GetMCPServerStatuses reports whether each configured server started and,
+// when it did not, why (e.g. "npx not found; looked in ...")
+func (a *App) GetMCPServerStatuses() []mcphost.ServerStatus {
+	if a.mcpService == nil {
+		return []mcphost.ServerStatus{}
+	}
+	return a.mcpService.ServerStatuses()
+}
+
+// TailMCPServerLogs returns the last lines a server logged, from stderr and
+// logging notifications, including those only left in its rotated log
+// files; lines <= 0 returns everything
+func (a *App) TailMCPServerLogs(name string, lines int) []mcphost.ServerLogEntry {
+	if a.mcpService == nil {
+		return []mcphost.ServerLogEntry{}
+	}
+	return a.mcpService.TailServerLogs(name, lines)
+}
+
+// SearchMCPServerLogs returns the log records of a server containing query
+func (a *App) SearchMCPServerLogs(name, query string) []mcphost.ServerLogEntry {
+	if a.mcpService == nil {
+		return []mcphost.ServerLogEntry{}
+	}
+	return a.mcpService.SearchServerLogs(name, query)
+}
+
+// ClearMCPServerLogs drops a server's log records and deletes its log files
+func (a *App) ClearMCPServerLogs(name string) error {
+	if a.mcpService == nil {
+		return fmt.Errorf("MCP service is not initialized")
+	}
+	return a.mcpService.ClearServerLogs(name)
+}
+
 // AddMCPSTDIOServer adds a new STDIO-based MCP server
 func (a *App) AddMCPSTDIOServer(name, command string, args []string, env map[string]string) error {
 	if a.mcpServerSettingsService == nil {
 		return fmt.Errorf("MCP server settings service not initialized")
 	}
 
-	return a.mcpServerSettingsService.AddSTDIOServer(name, command, args, env)
+	if err := a.mcpServerSettingsService.AddSTDIOServer(name, command, args, env); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
 }
 
 // AddMCPSSEServer adds a new SSE-based MCP server
@@ -86,7 +136,11 @@ func (a *App) AddMCPSSEServer(name, url string, headers []string) error {
 		return fmt.Errorf("MCP server settings service not initialized")
 	}
 
-	return a.mcpServerSettingsService.AddSSEServer(name, url, headers)
+	if err := a.mcpServerSettingsService.AddSSEServer(name, url, headers); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
 }
 
 // DeleteMCPServer removes an MCP server
@@ -95,7 +149,11 @@ func (a *App) DeleteMCPServer(name string) error {
 		return fmt.Errorf("MCP server settings service not initialized")
 	}
 
-	return a.mcpServerSettingsService.DeleteServer(name)
+	if err := a.mcpServerSettingsService.DeleteServer(name); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
 }
 
 // EnableMCPServer enables an MCP server
@@ -104,7 +162,11 @@ func (a *App) EnableMCPServer(name string) error {
 		return fmt.Errorf("MCP server settings service not initialized")
 	}
 
-	return a.mcpServerSettingsService.EnableServer(name)
+	if err := a.mcpServerSettingsService.EnableServer(name); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
 }
 
 // DisableMCPServer disables an MCP server
@@ -113,7 +175,11 @@ func (a *App) DisableMCPServer(name string) error {
 		return fmt.Errorf("MCP server settings service not initialized")
 	}
 
-	return a.mcpServerSettingsService.DisableServer(name)
+	if err := a.mcpServerSettingsService.DisableServer(name); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
 }
 
 // SetMCPServerEnabled sets the enabled state of an MCP server
@@ -122,7 +188,11 @@ func (a *App) SetMCPServerEnabled(name string, enabled bool) error {
 		return fmt.Errorf("MCP server settings service not initialized")
 	}
 
-	return a.mcpServerSettingsService.SetServerEnabled(name, enabled)
+	if err := a.mcpServerSettingsService.SetServerEnabled(name, enabled); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
 }
 
 // UpdateMCPSTDIOServer updates an existing STDIO server configuration
@@ -131,17 +201,39 @@ func (a *App) UpdateMCPSTDIOServer(name, command string, args []string, env map[
 		return fmt.Errorf("MCP server settings service not initialized")
 	}
 
+	// Values shown masked come back masked; keep what was stored
+	var execOptions settings.ExecOptions
+	var catalog *settings.CatalogOrigin
+	if existing, ok := a.mcpServerSettingsService.GetServer(name); ok {
+		if stdioConfig, ok := existing.Config.(settings.STDIOServerConfig); ok {
+			unmasked, err := unmaskEnv(stdioConfig.Env, env)
+			if err != nil {
+				return err
+			}
+			env = unmasked
+			execOptions = stdioConfig.ExecOptions
+			catalog = stdioConfig.Catalog
+		}
+	}
+
 	// Create the server configuration wrapper
 	serverConfig := settings.ServerConfigWrapper{
 		Config: settings.STDIOServerConfig{
-			Command: command,
-			Args:    args,
-			Env:     env,
+			Command:     command,
+			Args:        args,
+			Env:         env,
+			Catalog:     catalog,
+			ToolFilter:  a.existingToolFilter(name),
+			ExecOptions: execOptions,
 		},
 		Enabled: true, // Default to enabled, can be changed separately
 	}
 
-	return a.mcpServerSettingsService.UpdateServer(name, serverConfig)
+	if err := a.mcpServerSettingsService.UpdateServer(name, serverConfig); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
 }
 
 // UpdateMCPSSEServer updates an existing SSE server configuration
@@ -150,16 +242,71 @@ func (a *App) UpdateMCPSSEServer(name, url string, headers []string) error {
 		return fmt.Errorf("MCP server settings service not initialized")
 	}
 
+	// Values shown masked come back masked; keep what was stored
+	if existing, ok := a.mcpServerSettingsService.GetServer(name); ok {
+		if sseConfig, ok := existing.Config.(settings.SSEServerConfig); ok {
+			unmasked, err := unmaskHeaders(sseConfig.Headers, headers)
+			if err != nil {
+				return err
+			}
+			headers = unmasked
+		}
+	}
+
 	// Create the server configuration wrapper
 	serverConfig := settings.ServerConfigWrapper{
 		Config: settings.SSEServerConfig{
-			Url:     url,
-			Headers: headers,
+			Url:        url,
+			Headers:    headers,
+			ToolFilter: a.existingToolFilter(name),
 		},
 		Enabled: true, // Default to enabled, can be changed separately
 	}
 
-	return a.mcpServerSettingsService.UpdateServer(name, serverConfig)
+	if err := a.mcpServerSettingsService.UpdateServer(name, serverConfig); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
+}
+
+// SetMCPServerToolFilter sets the glob patterns that limit which of a server's
+// tools the model sees. Empty lists remove the restriction.
+func (a *App) SetMCPServerToolFilter(name string, includeTools, excludeTools []string) error {
+	if a.mcpServerSettingsService == nil {
+		return fmt.Errorf("MCP server settings service not initialized")
+	}
+
+	if err := a.mcpServerSettingsService.SetServerToolFilter(name, settings.ToolFilter{
+		IncludeTools: includeTools,
+		ExcludeTools: excludeTools,
+	}); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
+}
+
+// SetMCPServerExecOptions sets the working directory, environment handling,
+// resource limits and sandbox of a stdio server
+func (a *App) SetMCPServerExecOptions(name string, options settings.ExecOptions) error {
+	if a.mcpServerSettingsService == nil {
+		return fmt.Errorf("MCP server settings service not initialized")
+	}
+
+	if err := a.mcpServerSettingsService.SetServerExecOptions(name, options); err != nil {
+		return err
+	}
+	a.mcpServersChanged()
+	return nil
+}
+
+// existingToolFilter keeps a server's tool filter across edits of its other fields
+func (a *App) existingToolFilter(name string) settings.ToolFilter {
+	if server, ok := a.mcpServerSettingsService.GetServer(name); ok {
+		return server.Config.GetToolFilter()
+	}
+	return settings.ToolFilter{}
 }
 
 // GetMCPConfigPath returns the path to the MCP configuration file
@@ -176,4 +323,60 @@ func (a *App) GetActiveMCPConfigPath() string {
 		return ""
 	}
 	return a.mcpServerSettingsService.GetActiveServersFilePath()
-}
\ No newline at end of file
+}
+
+// DetectMCPImportSources lists config files of other MCP clients found on this machine
+func (a *App) DetectMCPImportSources() []settings.ImportSource {
+	if a.mcpServerSettingsService == nil {
+		return []settings.ImportSource{}
+	}
+	return a.mcpServerSettingsService.DetectImportSources()
+}
+
+// PreviewMCPImport shows which servers an import would add and which
+// conflict. Env and header values are masked like in GetMCPServers.
+func (a *App) PreviewMCPImport(client, path string) (*settings.ImportPreview, error) {
+	if a.mcpServerSettingsService == nil {
+		return nil, fmt.Errorf("MCP server settings service not initialized")
+	}
+	preview, err := a.mcpServerSettingsService.PreviewImport(client, path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range preview.Servers {
+		preview.Servers[i].Server = maskServerConfig(preview.Servers[i].Server)
+	}
+	return preview, nil
+}
+
+// ImportMCPServers merges another client's servers; strategy decides what
+// happens to name conflicts ("skip", "overwrite" or "rename")
+func (a *App) ImportMCPServers(client, path, strategy string) (*settings.ImportResult, error) {
+	if a.mcpServerSettingsService == nil {
+		return nil, fmt.Errorf("MCP server settings service not initialized")
+	}
+	result, err := a.mcpServerSettingsService.Import(client, path, strategy)
+	if err != nil {
+		return nil, err
+	}
+	a.mcpServersChanged()
+	return result, nil
+}
+
+// ExportMCPServers writes our servers to another client's config file. Other
+// clients cannot resolve ${env:...} and ${secret:...} references: unless
+// resolveReferences is set, servers using them fail the export and are
+// listed in the error; with it, their values are written out in plaintext.
+func (a *App) ExportMCPServers(client, path string, resolveReferences bool) error {
+	if a.mcpServerSettingsService == nil {
+		return fmt.Errorf("MCP server settings service not initialized")
+	}
+	var resolve func(string) (string, error)
+	if resolveReferences {
+		lookup := a.secretLookup()
+		resolve = func(value string) (string, error) {
+			return secrets.Expand(value, lookup)
+		}
+	}
+	return a.mcpServerSettingsService.Export(client, path, resolve)
+}
@@ -5,7 +5,8 @@ import {
   WindowSetSize,
   LogInfo,
   EventsOn,
-   EventsOff
+   EventsOff,
+  BrowserOpenURL
 } from "../../../wailsjs/runtime/runtime";
 import ConfirmationDialog from "./ConfirmationDialog";
 import SearchInput      from "./SearchInput";
@@ -79,8 +80,8 @@ export default function SearchContainer() {
           break;
 
         case "authorization_required":
-
-          // Show modal / toast here
+          // ev.Data = {server, url}; the backend waits for the redirect
+          BrowserOpenURL(ev.Data.url);
           break;
       }
     }
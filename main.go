@@ -48,6 +48,16 @@ func main() {
 
 	// Check for development mode
 	isDev := strings.ToLower(os.Getenv("SMARTSPOTLIGHT_DEV")) == "true"
+
+	// MCP clients launch us with --mcp-gateway to reach the running app's
+	// gateway over stdio; stdout then belongs to the protocol
+	if len(os.Args) > 1 && os.Args[1] == "--mcp-gateway" {
+		if err := backend.RunMCPGatewayStdio(getVersion(isDev)); err != nil {
+			slog.Error("MCP gateway bridge failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
 	if isDev {
 		handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 			Level: slog.LevelDebug, // Set log level to Debug